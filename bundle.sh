@@ -4,6 +4,8 @@ shopt -s globstar
 
 # Setup bin directory
 mkdir -p bin/assets
+# Entries of the file index, see below
+index=""
 
 # Convert PNG assets
 for file in assets/*.png
@@ -20,6 +22,7 @@ do
 
   echo "go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal"
   go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal
+  index+="\t\"${file/assets\//}\": $variablePascal,\n"
 done
 
 # Convert CSV assets
@@ -37,6 +40,26 @@ do
 
   echo "go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal"
   go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal
+  index+="\t\"${file/assets\//}\": $variablePascal,\n"
+done
+
+# Convert tiled map (.tmx) & tileset (.tsx) assets
+for file in assets/*.tmx assets/*.tsx
+do
+  if [[ ! -f "$file" ]]
+  then
+      continue
+  fi
+  extension="${file##*.}"
+  noExtension="${file/.$extension/_${extension^^}}"
+  output="bin/$noExtension.go"
+  variable="${noExtension/assets\//}"
+  # Convert to PascalCase
+  variablePascal=$(echo "$variable" | sed -r 's/(^|_)(.)/\U\2/g')
+
+  echo "go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal"
+  go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal
+  index+="\t\"${file/assets\//}\": $variablePascal,\n"
 done
 
 # Convert .ogg audio assets
@@ -54,4 +77,28 @@ do
 
   echo "go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal"
   go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal
+  index+="\t\"${file/assets\//}\": $variablePascal,\n"
 done
+
+# Convert .json assets, e.g. the asset manifest
+for file in assets/*.json
+do
+  if [[ ! -f "$file" ]]
+  then
+      continue
+  fi
+  noExtension="${file/.json/_JSON}"
+  output="bin/$noExtension.go"
+  variable="${noExtension/assets\//}"
+  # Convert to PascalCase
+  variablePascal=$(echo "$variable" | sed -r 's/(^|_)(.)/\U\2/g')
+
+  echo "go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal"
+  go run github.com/hajimehoshi/file2byteslice/cmd/file2byteslice@latest -input $file -output $output -package assets -var $variablePascal
+  index+="\t\"${file/assets\//}\": $variablePascal,\n"
+done
+
+# Index of all bundled files by path relative to assets/. Used to resolve files referenced by the asset manifest
+echo "Writing bin/assets/Files.go"
+printf "package assets\n\nvar Files = map[string][]byte{\n$index}\n" > bin/assets/Files.go
+gofmt -w bin/assets/Files.go
@@ -0,0 +1,269 @@
+package engine
+
+import (
+	"log"
+
+	"github.com/jakecoffman/cp"
+)
+
+const (
+	// Gap between bounding boxes that still counts as melee contact
+	aiMeleeReach = 4.0
+	// Ranged npcs stop within this fraction of their fire range
+	aiRangedReach = 0.9
+	// Fleeing npcs steer towards a point this far ahead
+	aiFleeLookAhead = 100.0
+	// Seconds npcs flee before returning to fight
+	aiFleeDuration = 5.0
+	// Ranged npcs back off while reloading, if closer than this fraction of their fire range
+	aiKiteReach = 0.5
+	// Seconds between lighting the fuse & exploding, at 1 attack per second
+	aiFuseTime = 1.0
+)
+
+// Conditions & actions of the built in behaviours. Combine them to build new ones
+
+// True while fleeing. Npcs flee once health drops below the flee threshold &
+// return to fight after aiFleeDuration, so waves cannot stall on npcs that ran off
+func AiShouldFlee(n *NpcAggro) bool {
+	if n.fleeing && n.fleeTimer.Done() {
+		n.fleeing = false
+		n.fled = true
+	}
+	if !n.fleeing && !n.fled && n.fleeHealth > 0 && n.Health() < n.fleeHealth*n.MaxHealth() {
+		n.fleeing = true
+		n.fleeTimer.Set(aiFleeDuration)
+	}
+	return n.fleeing
+}
+
+// Run away from the nearest enemy in sight, or from home if there is none
+func AiFlee(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	n.setTarget(nil)
+	pos := n.shape.Body().Position()
+	threat := n.perceive()
+	if threat == nil {
+		threat = n.home
+	}
+	away := pos.Sub(threat.Shape().Body().Position())
+	if away.LengthSq() == 0 {
+		away = cp.ForAngle(float64(n.Id()))
+	}
+	away = away.Normalize()
+	n.moveTowards(n.shape.Body(), pos.Add(away.Mult(aiFleeLookAhead)), nil, ctx.Dt)
+	return AiRunning
+}
+
+// Target the nearest enemy in sight, falls back to home. Fails only without home
+func AiAcquireTarget(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	target := n.perceive()
+	if target == nil {
+		target = n.home
+	}
+	n.setTarget(target)
+	if target == nil {
+		return AiFailure
+	}
+	return AiSuccess
+}
+
+// Target the nearest enemy in sight. Fails if there is none
+func AiPerceiveEnemy(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	n.setTarget(n.perceive())
+	if n.target == nil {
+		return AiFailure
+	}
+	return AiSuccess
+}
+
+// Swing at the target while in contact. Fails otherwise
+func AiMeleeAttack(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	body := n.shape.Body()
+	if !n.inMeleeReach() {
+		n.attacking = false
+		return AiFailure
+	}
+	// Stop all movement
+	body.SetVelocity(0, 0)
+	n.face(n.target.Shape().Body().Position().Sub(body.Position()))
+
+	// Once within range of target, we start attacking
+	if !n.attacking {
+		n.attacking = true
+		// Loop idle animation (in between swings, if animation allows)
+		if err := n.asset.AnimationController().Loop("idle"); err != nil {
+			log.Println("Could not play idle animation", err.Error())
+		}
+		// Play first attack animation
+		if err := n.asset.AnimationController().Play("attack"); err != nil {
+			log.Println("Could not play attack animation", err.Error())
+		}
+		// Set timer to apply swing damage
+		n.swingTimer.Set(1 / n.AtkSpeed())
+		n.sfxTimeout.Set(npcSwingSfxDelay)
+		return AiRunning
+	}
+
+	// Check if we need to play SFX
+	if n.sfxTimeout.Done() {
+		if err := n.playAtkSE(); err != nil {
+			log.Printf("Could not play npc atk sound effect: %s\n", err.Error())
+		}
+		// Disable timeout until next swing
+		n.sfxTimeout.Stop()
+	}
+	// Wait for next swing timer
+	if !n.swingTimer.Done() {
+		return AiRunning
+	}
+
+	// Apply damage
+	u, ok := n.shape.Space().StaticBody.UserData.(SpaceUserData)
+	if !ok {
+		log.Println("ERROR: Could not apply damage: No acces to damage model via space user data possible")
+		return AiRunning
+	}
+	if _, err := u.applyDamage(n, n.target); err != nil {
+		log.Println("Error during npc swing damage calc", err.Error())
+		return AiRunning
+	}
+
+	// Queue up next swing, animation, sfx
+	if err := n.asset.AnimationController().Play("attack"); err != nil {
+		log.Printf("Could not play attack animation: %s\n", err.Error())
+	}
+	n.sfxTimeout.Set(npcSwingSfxDelay)
+	n.swingTimer.Set(1 / n.AtkSpeed())
+	return AiRunning
+}
+
+// Shoot at the target while in range & sight. Fails otherwise or without gun
+func AiRangedAttack(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	if n.gun == nil {
+		return AiFailure
+	}
+	pos := n.shape.Body().Position()
+	info := n.target.Shape().PointQuery(pos)
+	if info.Distance > n.gun.FireRange()*aiRangedReach || !LineOfSight(n.shape.Space(), pos, info.Point) {
+		return AiFailure
+	}
+	n.attacking = false
+	n.shape.Body().SetVelocity(0, 0)
+	n.face(info.Point.Sub(pos))
+	if n.gun.IsReloading() {
+		return AiRunning
+	}
+	n.gun.SetAim(info.Point)
+	// Behaviours run during the physics step. Projectiles cannot be added to the locked space
+	gun := n.gun
+	n.shape.Space().AddPostStepCallback(func(*cp.Space, interface{}, interface{}) {
+		if err := gun.Shoot(); err != nil {
+			log.Println("Could not shoot", err.Error())
+		}
+	}, gun, nil)
+	return AiRunning
+}
+
+// Back off from the target while reloading & too close. Fails otherwise or without gun
+func AiKeepDistance(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	if n.gun == nil || !n.gun.IsReloading() {
+		return AiFailure
+	}
+	pos := n.shape.Body().Position()
+	info := n.target.Shape().PointQuery(pos)
+	away := pos.Sub(info.Point)
+	if info.Distance >= n.gun.FireRange()*aiKiteReach || away.LengthSq() == 0 {
+		return AiFailure
+	}
+	n.attacking = false
+	n.moveTowards(n.shape.Body(), pos.Add(away.Normalize().Mult(aiFleeLookAhead)), nil, ctx.Dt)
+	return AiRunning
+}
+
+// Light the fuse once in contact with the target & explode when it burnt down.
+// Keeps running once lit, even if the target moved away. Fails if not in contact
+func AiDetonate(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	// Dead bombers do not go off
+	if n.Health() <= 0 {
+		return AiFailure
+	}
+	if !n.attacking {
+		if !n.inMeleeReach() {
+			return AiFailure
+		}
+		n.attacking = true
+		if err := n.asset.AnimationController().Loop("attack"); err != nil {
+			log.Println("Could not play attack animation", err.Error())
+		}
+		n.swingTimer.Set(aiFuseTime / n.AtkSpeed())
+	}
+	n.shape.Body().SetVelocity(0, 0)
+	if !n.swingTimer.Done() {
+		return AiRunning
+	}
+	if err := n.explode(); err != nil {
+		log.Println("Could not explode", err.Error())
+	}
+	return AiSuccess
+}
+
+// Restore health of all hurt npcs within the ability radius, incl. itself.
+// Fails while on cooldown or if nobody is hurt. Shares its cooldown with swings
+func AiHeal(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	if !n.swingTimer.Done() {
+		return AiFailure
+	}
+	hurt := n.hurtAllies()
+	if len(hurt) == 0 {
+		return AiFailure
+	}
+	for _, ally := range hurt {
+		ally.SetHealth(ally.Health() + n.Power())
+	}
+	n.attacking = false
+	n.shape.Body().SetVelocity(0, 0)
+	if err := n.asset.AnimationController().Play("attack"); err != nil {
+		log.Println("Could not play attack animation", err.Error())
+	}
+	n.swingTimer.Set(1 / n.AtkSpeed())
+	return AiSuccess
+}
+
+// Move towards the target. Fails if it cannot be reached
+func AiChase(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	next, ok := n.nextPosition()
+	if !ok {
+		return AiFailure
+	}
+	n.moveTowards(n.shape.Body(), next, n.target.Shape(), ctx.Dt)
+	return AiRunning
+}
+
+// Walk along waypoints. Fails without waypoints
+func AiPatrol(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	if len(n.wayPoints) == 0 {
+		return AiFailure
+	}
+	n.setTarget(nil)
+	n.simpleWaypointAlgorithm(n.shape.Body(), ctx.Dt)
+	return AiRunning
+}
+
+func AiIdle(ctx *AiContext) AiStatus {
+	n := ctx.Npc
+	n.shape.Body().SetVelocityVector(cp.Vector{})
+	if err := n.asset.AnimationController().Loop("idle"); err != nil {
+		log.Println("Error looping", err.Error())
+	}
+	return AiRunning
+}
@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"github.com/jakecoffman/cp"
+)
+
+var (
+	// Npcs attack players & buildings (castle, towers)
+	aiEnemyFilter = cp.NewShapeFilter(cp.NO_GROUP, cp.ALL_CATEGORIES, PlayerCategory|TowerCategory)
+	// Blocks line of sight
+	aiSightFilter = cp.NewShapeFilter(cp.NO_GROUP, cp.ALL_CATEGORIES, OuterWallsCategory)
+)
+
+// True if no wall blocks the line between src & dst
+func LineOfSight(space *cp.Space, src, dst cp.Vector) bool {
+	return space.SegmentQueryFirst(src, dst, 0, aiSightFilter).Shape == nil
+}
+
+// Nearest enemy within radius that is in line of sight. Nil if there is none.
+// Players are preferred over buildings. Players inside buildings cannot be seen
+func PerceiveEnemy(space *cp.Space, pos cp.Vector, radius float64) DefenderEntity {
+	var res DefenderEntity
+	resPlayer := false
+	resDist := radius
+	space.BBQuery(cp.NewBBForCircle(pos, radius), aiEnemyFilter, func(shape *cp.Shape, _ interface{}) {
+		enemy, ok := shape.Body().UserData.(DefenderEntity)
+		if !ok {
+			return
+		}
+		if inside, ok := enemy.(interface{ Inside() bool }); ok && inside.Inside() {
+			return
+		}
+		info := shape.PointQuery(pos)
+		dist := max(info.Distance, 0)
+		if dist > radius {
+			return
+		}
+		player := shape.Filter.Categories&PlayerCategory != 0
+		// Lower priority or further away than current choice
+		if res != nil && ((resPlayer && !player) || (resPlayer == player && dist >= resDist)) {
+			return
+		}
+		if !LineOfSight(space, pos, info.Point) {
+			return
+		}
+		res, resPlayer, resDist = enemy, player, dist
+	}, nil)
+	return res
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Result of ticking a behaviour tree node
+type AiStatus int
+
+const (
+	AiSuccess AiStatus = iota
+	AiFailure
+	// Action takes more than one tick. Evaluated again next tick
+	AiRunning
+)
+
+// State passed down the tree during a single tick
+type AiContext struct {
+	Npc *NpcAggro
+	Dt  float64
+}
+
+// Node of a behaviour tree. Trees are evaluated from the root every tick & hold no state,
+// so a single tree can be shared by all npcs. Per npc state lives on the npc
+type AiNode interface {
+	Tick(ctx *AiContext) AiStatus
+}
+
+// Runs children in order until one does not fail
+type AiSelector []AiNode
+
+func (s AiSelector) Tick(ctx *AiContext) AiStatus {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != AiFailure {
+			return status
+		}
+	}
+	return AiFailure
+}
+
+// Runs children in order until one does not succeed
+type AiSequence []AiNode
+
+func (s AiSequence) Tick(ctx *AiContext) AiStatus {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != AiSuccess {
+			return status
+		}
+	}
+	return AiSuccess
+}
+
+// Succeeds if the condition holds
+type AiCondition func(n *NpcAggro) bool
+
+func (c AiCondition) Tick(ctx *AiContext) AiStatus {
+	if c(ctx.Npc) {
+		return AiSuccess
+	}
+	return AiFailure
+}
+
+type AiAction func(ctx *AiContext) AiStatus
+
+func (a AiAction) Tick(ctx *AiContext) AiStatus { return a(ctx) }
+
+// Behaviours npc types can refer to by name
+var aiBehaviours = map[string]AiNode{}
+
+const defaultAiBehaviour = "melee"
+
+func RegisterAiBehaviour(name string, root AiNode) {
+	aiBehaviours[name] = root
+}
+
+func AiBehaviour(name string) (AiNode, error) {
+	root, ok := aiBehaviours[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown ai behaviour %s", name)
+	}
+	return root, nil
+}
+
+// Names of all registered behaviours
+func AiBehaviours() []string {
+	res := []string{}
+	for name := range aiBehaviours {
+		res = append(res, name)
+	}
+	slices.Sort(res)
+	return res
+}
+
+func init() {
+	flee := AiSequence{AiCondition(AiShouldFlee), AiAction(AiFlee)}
+	idle := AiAction(AiIdle)
+	// Attack the closest enemy in sight, otherwise the home target
+	RegisterAiBehaviour("melee", AiSelector{
+		flee,
+		AiSequence{AiAction(AiAcquireTarget), AiSelector{AiAction(AiMeleeAttack), AiAction(AiChase)}},
+		idle,
+	})
+	// Shoot from range. Fights in melee if there is no gun or line of sight
+	RegisterAiBehaviour("ranged", AiSelector{
+		flee,
+		AiSequence{AiAction(AiAcquireTarget), AiSelector{AiAction(AiRangedAttack), AiAction(AiMeleeAttack), AiAction(AiChase)}},
+		idle,
+	})
+	// Shoot from range & back off between shots
+	RegisterAiBehaviour("archer", AiSelector{
+		flee,
+		AiSequence{AiAction(AiAcquireTarget), AiSelector{AiAction(AiKeepDistance), AiAction(AiRangedAttack), AiAction(AiMeleeAttack), AiAction(AiChase)}},
+		idle,
+	})
+	// Run into the closest enemy & explode. Never flees
+	RegisterAiBehaviour("bomber", AiSelector{
+		AiAction(AiDetonate),
+		AiSequence{AiAction(AiAcquireTarget), AiAction(AiChase)},
+		idle,
+	})
+	// Heal hurt npcs nearby, fight in melee in between
+	RegisterAiBehaviour("healer", AiSelector{
+		flee,
+		AiAction(AiHeal),
+		AiSequence{AiAction(AiAcquireTarget), AiSelector{AiAction(AiMeleeAttack), AiAction(AiChase)}},
+		idle,
+	})
+	// Walk along waypoints & only attack enemies in sight
+	RegisterAiBehaviour("patrol", AiSelector{
+		flee,
+		AiSequence{AiAction(AiPerceiveEnemy), AiSelector{AiAction(AiMeleeAttack), AiAction(AiChase)}},
+		AiAction(AiPatrol),
+		idle,
+	})
+}
@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+func TestAiComposites(t *testing.T) {
+	ticks := 0
+	count := func(status AiStatus) AiNode {
+		return AiAction(func(*AiContext) AiStatus {
+			ticks++
+			return status
+		})
+	}
+	ctx := &AiContext{}
+	if status := (AiSelector{count(AiFailure), count(AiRunning), count(AiSuccess)}).Tick(ctx); status != AiRunning || ticks != 2 {
+		t.Errorf("Expected selector to stop at first running child, got %d after %d ticks", status, ticks)
+	}
+	ticks = 0
+	if status := (AiSequence{count(AiSuccess), count(AiFailure), count(AiSuccess)}).Tick(ctx); status != AiFailure || ticks != 2 {
+		t.Errorf("Expected sequence to stop at first failing child, got %d after %d ticks", status, ticks)
+	}
+	if _, err := AiBehaviour("unknown"); err == nil {
+		t.Error("Expected unknown behaviour to fail")
+	}
+}
+
+// Tree standing in for a player or building
+func newAiTestEnemy(t *testing.T, w *GameWorld, pos cp.Vector, filter cp.ShapeFilter) *TreeEntity {
+	enemy := newSteeringTestTree(t, w, pos)
+	enemy.Shape().SetFilter(filter)
+	enemy.Shape().SetCollisionType(CastleCollision)
+	return enemy
+}
+
+func newAiTestNpc(t *testing.T, w *GameWorld, home DefenderEntity, pos cp.Vector, opts NpcOpts) *NpcAggro {
+	asset, err := w.AssetManager.CharacterAsset("npc-orc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.BaseHealth = 100
+	opts.StartingPos = pos
+	npc, err := NewNpcAggro(home, asset, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddEntity(npc); err != nil {
+		t.Fatal(err)
+	}
+	return npc
+}
+
+func TestNpcSwitchesTargets(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	home := newAiTestEnemy(t, w, cp.Vector{X: 900, Y: 500}, TowerCollisionFilter())
+	player := newAiTestEnemy(t, w, cp.Vector{X: 300, Y: 500}, PlayerCollisionFilter())
+	npc := newAiTestNpc(t, w, home, cp.Vector{X: 200, Y: 500}, NpcOpts{AggroRadius: 150})
+	w.Update()
+	if npc.Target() != player {
+		t.Errorf("Expected npc to target player in sight, got %v", npc.Target())
+	}
+
+	// Out of range
+	player.Shape().Body().SetPosition(cp.Vector{X: 200, Y: 900})
+	w.Update()
+	if npc.Target() != home {
+		t.Errorf("Expected npc to fall back to home, got %v", npc.Target())
+	}
+
+	// Behind a wall
+	player.Shape().Body().SetPosition(cp.Vector{X: 300, Y: 500})
+	RegisterWallSegmentToSpace(w.Space(), WallSegment{cp.Vector{X: 260, Y: 400}, cp.Vector{X: 260, Y: 600}})
+	w.Update()
+	if npc.Target() != home {
+		t.Errorf("Expected npc not to see player behind wall, got %v", npc.Target())
+	}
+}
+
+func TestNpcFleesAtLowHealth(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	home := newAiTestEnemy(t, w, cp.Vector{X: 600, Y: 500}, TowerCollisionFilter())
+	npc := newAiTestNpc(t, w, home, cp.Vector{X: 400, Y: 500}, NpcOpts{FleeHealth: 0.5})
+	npc.SetHealth(40)
+	start := npc.Body().Position().Distance(home.Shape().Body().Position())
+	for range 60 {
+		w.Update()
+	}
+	if !npc.Fleeing() || npc.Body().Position().Distance(home.Shape().Body().Position()) <= start {
+		t.Errorf("Expected npc to flee from home, got %v", npc.Body().Position())
+	}
+	// Fleeing is persisted
+	restored := newAiTestNpc(t, w, home, cp.Vector{}, NpcOpts{})
+	if err := restored.Restore(npc.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Fleeing() {
+		t.Error("Expected restored npc to keep fleeing")
+	}
+
+	// Returns to fight once the flee timer is done & does not flee again
+	for range int(aiFleeDuration * 60) {
+		w.Update()
+	}
+	fled := npc.Body().Position().Distance(home.Shape().Body().Position())
+	for range 60 {
+		w.Update()
+	}
+	if npc.Fleeing() || npc.Body().Position().Distance(home.Shape().Body().Position()) >= fled {
+		t.Errorf("Expected npc to return after fleeing, got %v", npc.Body().Position())
+	}
+}
+
+func TestPatrolLoopsWaypoints(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	home := newAiTestEnemy(t, w, cp.Vector{X: 900, Y: 900}, TowerCollisionFilter())
+	waypoints := []cp.Vector{{X: 200, Y: 200}, {X: 300, Y: 200}, {X: 300, Y: 300}}
+	npc := newAiTestNpc(t, w, home, waypoints[0], NpcOpts{Behaviour: "patrol", Waypoints: waypoints, LoopWaypoints: true})
+	visited := []int{npc.currentWpIndex}
+	for range 900 {
+		w.Update()
+		if idx := npc.currentWpIndex; idx != visited[len(visited)-1] {
+			visited = append(visited, idx)
+		}
+	}
+	if len(visited) < 4 || visited[3] != 0 {
+		t.Errorf("Expected npc to loop through waypoints, visited %v", visited)
+	}
+	if npc.Target() != nil {
+		t.Errorf("Expected patrolling npc to ignore home, got %v", npc.Target())
+	}
+}
+
+func TestRangedNpcShootsFromRange(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	home := newAiTestEnemy(t, w, cp.Vector{X: 600, Y: 500}, TowerCollisionFilter())
+	npc := newAiTestNpc(t, w, home, cp.Vector{X: 200, Y: 500}, NpcOpts{Behaviour: "ranged"})
+	proj, err := w.AssetManager.ProjectileAsset("bone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gun, err := NewAimedGun(w, npc, proj, BasicGunOpts{FireRange: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	npc.SetGun(gun)
+	for range 600 {
+		w.Update()
+	}
+	if home.Health() >= 100 {
+		t.Error("Expected ranged npc to damage home")
+	}
+	if gap := home.Shape().PointQuery(npc.Body().Position()).Distance; gap < 100 {
+		t.Errorf("Expected ranged npc to keep its distance, got %f", gap)
+	}
+}
+
+func TestArcherKeepsDistance(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	home := newAiTestEnemy(t, w, cp.Vector{X: 500, Y: 500}, TowerCollisionFilter())
+	npc := newAiTestNpc(t, w, home, cp.Vector{X: 440, Y: 500}, NpcOpts{Behaviour: "archer"})
+	proj, err := w.AssetManager.ProjectileAsset("arrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gun, err := NewAimedGun(w, npc, proj, BasicGunOpts{FireRange: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	npc.SetGun(gun)
+	for range 300 {
+		w.Update()
+	}
+	if home.Health() >= 100 {
+		t.Error("Expected archer to damage home")
+	}
+	if gap := home.Shape().PointQuery(npc.Body().Position()).Distance; gap < 80 {
+		t.Errorf("Expected archer to back off, got %f", gap)
+	}
+}
+
+func TestBomberExplodesOnContact(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	home := newAiTestEnemy(t, w, cp.Vector{X: 500, Y: 500}, TowerCollisionFilter())
+	nearby := newAiTestEnemy(t, w, cp.Vector{X: 500, Y: 540}, TowerCollisionFilter())
+	far := newAiTestEnemy(t, w, cp.Vector{X: 800, Y: 500}, TowerCollisionFilter())
+	npc := newAiTestNpc(t, w, home, cp.Vector{X: 400, Y: 500}, NpcOpts{Behaviour: "bomber", BasePower: 30, AbilityRadius: 60})
+	killed := 0
+	Subscribe(w.Events(), func(e EntityKilled) {
+		if e.Entity == GameEntity(npc) {
+			killed++
+		}
+	})
+	for range 300 {
+		w.Update()
+	}
+	if w.Space().ContainsBody(npc.Body()) {
+		t.Fatal("Expected bomber to die in the explosion")
+	}
+	// Dies via the regular death pipeline
+	if killed != 1 {
+		t.Errorf("Expected bomber kill to be published once, got %d", killed)
+	}
+	if home.Health() >= 100 || nearby.Health() >= 100 {
+		t.Errorf("Expected explosion to damage enemies within radius, got %f & %f", home.Health(), nearby.Health())
+	}
+	if far.Health() < 100 {
+		t.Error("Expected explosion to spare enemies out of radius")
+	}
+}
+
+func TestHealerRestoresNearbyNpcs(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	home := newAiTestEnemy(t, w, cp.Vector{X: 900, Y: 900}, TowerCollisionFilter())
+	newAiTestNpc(t, w, home, cp.Vector{X: 200, Y: 200}, NpcOpts{Behaviour: "healer", BasePower: 10, AbilityRadius: 100})
+	// Idle without waypoints
+	near := newAiTestNpc(t, w, home, cp.Vector{X: 260, Y: 200}, NpcOpts{Behaviour: "patrol"})
+	far := newAiTestNpc(t, w, home, cp.Vector{X: 200, Y: 500}, NpcOpts{Behaviour: "patrol"})
+	near.SetHealth(50)
+	far.SetHealth(50)
+	w.Update()
+	if near.Health() != 60 {
+		t.Errorf("Expected healer to restore health of nearby npc, got %f", near.Health())
+	}
+	if far.Health() != 50 {
+		t.Errorf("Expected healer to ignore npcs out of radius, got %f", far.Health())
+	}
+	// Cooldown
+	w.Update()
+	if near.Health() != 60 {
+		t.Errorf("Expected healer to wait for cooldown, got %f", near.Health())
+	}
+}
+
+func TestShieldBlocksFrontalProjectiles(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		shooter cp.Vector
+		blocked bool
+	}{
+		{"front", cp.Vector{X: 700, Y: 500}, true},
+		{"behind", cp.Vector{X: 300, Y: 500}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w, _ := newDamageTestWorld(t)
+			home := newAiTestEnemy(t, w, cp.Vector{X: 900, Y: 900}, TowerCollisionFilter())
+			// Idle without waypoints & enemies in sight
+			npc := newAiTestNpc(t, w, home, cp.Vector{X: 500, Y: 500}, NpcOpts{Behaviour: "patrol", ShieldAngle: 45})
+			npc.face(cp.Vector{X: 1})
+			shooter := newAiTestEnemy(t, w, tc.shooter, cp.NewShapeFilter(cp.NO_GROUP, PlayerCategory, 0))
+			proj, err := w.AssetManager.ProjectileAsset("arrow")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gun, err := NewAimedGun(w, shooter, proj, BasicGunOpts{FireRange: 300, Damage: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			gun.SetAim(npc.Body().Position())
+			if err := gun.Shoot(); err != nil {
+				t.Fatal(err)
+			}
+			for range 120 {
+				w.Update()
+			}
+			if blocked := npc.Health() == 100; blocked != tc.blocked {
+				t.Errorf("Expected blocked = %v, got health %f", tc.blocked, npc.Health())
+			}
+		})
+	}
+}
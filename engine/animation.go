@@ -24,10 +24,11 @@ type AnimationAsset interface {
 type BaseAnimationManager struct {
 	asset AnimationAsset
 
-	playingAnimation      *GameAssetAnimation
+	// Animations are resolved by key on every draw to pick up hot reloaded animation tables
+	playingAnimation      string
 	playingAnimationTimer Timer
 
-	loopAnimation         *GameAssetAnimation
+	loopAnimation         string
 	loopingAnimationTimer Timer
 }
 
@@ -42,66 +43,70 @@ func NewAnimationManager(asset AnimationAsset) (*BaseAnimationManager, error) {
 	if am.loopingAnimationTimer, err = NewAnimationTimer(asset); err != nil {
 		return nil, err
 	}
-	if am.loopAnimation, err = asset.Animation("idle"); err != nil {
+	if _, err = asset.Animation("idle"); err != nil {
 		return nil, fmt.Errorf("Cannot init animation manager without idle animation: %e", err.Error())
 	}
+	am.loopAnimation = "idle"
 	am.loopingAnimationTimer.Start()
 
 	return am, nil
 }
 
 func (a *BaseAnimationManager) Play(animationKey string) error {
-	var err error
-	a.playingAnimation, err = a.asset.Animation(animationKey)
-	if err != nil {
+	if _, err := a.asset.Animation(animationKey); err != nil {
 		return err
 	}
+	a.playingAnimation = animationKey
 	a.playingAnimationTimer.Stop()
 	a.playingAnimationTimer.Start()
 	return nil
 }
 
 func (a *BaseAnimationManager) Loop(animationKey string) error {
-	var err error
-	a.loopAnimation, err = a.asset.Animation(animationKey)
-	if err != nil {
+	if _, err := a.asset.Animation(animationKey); err != nil {
 		return err
 	}
+	a.loopAnimation = animationKey
 	return nil
 }
 
 func (a *BaseAnimationManager) StopPlaying() error {
-	a.playingAnimation = nil
+	a.playingAnimation = ""
 	a.playingAnimationTimer.Stop()
 	return nil
 }
 
 func (a *BaseAnimationManager) Draw(t RenderingTarget, shape *cp.Shape, o Orientation) error {
+	loopAnimation, err := a.asset.Animation(a.loopAnimation)
+	if err != nil {
+		return fmt.Errorf("Cannot draw: %s", err.Error())
+	}
 	// Check if there is an animation currently playing, if not, play loop
-	if !a.playingAnimationTimer.Active() || a.playingAnimation == nil {
+	if !a.playingAnimationTimer.Active() || a.playingAnimation == "" {
 		currentAnimationTile := 0
-		if a.loopAnimation == nil {
-			return fmt.Errorf("Cannot draw: Neither play, nor loop animation defined")
-		}
-		if a.loopAnimation.FrameCount > 1 && a.loopAnimation.Speed > 0 {
-			currentAnimationTile = int(a.loopingAnimationTimer.Elapsed()/a.loopAnimation.Speed) % a.loopAnimation.FrameCount
+		if loopAnimation.FrameCount > 1 && loopAnimation.Speed > 0 {
+			currentAnimationTile = int(a.loopingAnimationTimer.Elapsed()/loopAnimation.Speed) % loopAnimation.FrameCount
 		}
-		return a.asset.DrawAnimationTile(t, shape, a.loopAnimation, currentAnimationTile, o)
+		return a.asset.DrawAnimationTile(t, shape, loopAnimation, currentAnimationTile, o)
+	}
+	playingAnimation, err := a.asset.Animation(a.playingAnimation)
+	if err != nil {
+		return fmt.Errorf("Cannot draw: %s", err.Error())
 	}
 
 	// Calculate how many frames the animation has to play
-	totalAnimationFrames := a.playingAnimation.Speed * float64(a.playingAnimation.FrameCount)
+	totalAnimationFrames := playingAnimation.Speed * float64(playingAnimation.FrameCount)
 	diff := a.playingAnimationTimer.Elapsed()
 
 	// Not finished playing
 	if diff < float64(totalAnimationFrames) {
 		// Calculate current animation tile
-		currentAnimationTile := int(diff / float64(a.playingAnimation.Speed))
-		return a.asset.DrawAnimationTile(t, shape, a.playingAnimation, currentAnimationTile, o)
+		currentAnimationTile := int(diff / float64(playingAnimation.Speed))
+		return a.asset.DrawAnimationTile(t, shape, playingAnimation, currentAnimationTile, o)
 	}
 
 	// Finished playing, back to loop
 	a.playingAnimationTimer.Stop()
 	a.loopingAnimationTimer.Start()
-	return a.asset.DrawAnimationTile(t, shape, a.loopAnimation, 0, o)
+	return a.asset.DrawAnimationTile(t, shape, loopAnimation, 0, o)
 }
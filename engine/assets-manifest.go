@@ -0,0 +1,246 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+// Reads asset files by path relative to the assets directory
+type AssetFileReader func(path string) ([]byte, error)
+
+const AssetManifestPath = "manifest.json"
+
+// Reads files that have been bundled into the binary via bundle.sh
+func NewBundledAssetReader(files map[string][]byte) AssetFileReader {
+	return func(filePath string) ([]byte, error) {
+		data, ok := files[path.Clean(filePath)]
+		if !ok {
+			return nil, fmt.Errorf("Unknown bundled asset %s", filePath)
+		}
+		return data, nil
+	}
+}
+
+// Reads files from disk. Allows adding assets without rebuilding
+func NewDirAssetReader(dir string) AssetFileReader {
+	return func(filePath string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dir, filepath.FromSlash(filePath)))
+	}
+}
+
+// Declares all assets available to the asset manager
+type AssetManifest struct {
+	Tilesets    []TilesetManifest    `json:"tilesets"`
+	Characters  []CharacterManifest  `json:"characters"`
+	Projectiles []ProjectileManifest `json:"projectiles"`
+	Sounds      []SoundManifest      `json:"sounds"`
+}
+
+type TilesetManifest struct {
+	Name       string `json:"name"`
+	Image      string `json:"image"`
+	TileWidth  int    `json:"tileWidth"`
+	TileHeight int    `json:"tileHeight"`
+}
+
+type CharacterManifest struct {
+	Name       string  `json:"name"`
+	Image      string  `json:"image"`
+	TileWidth  int     `json:"tileWidth"`
+	TileHeight int     `json:"tileHeight"`
+	OffsetX    float64 `json:"offsetX"`
+	OffsetY    float64 `json:"offsetY"`
+	Scale      float64 `json:"scale"`
+	// Multiplied onto the sprite sheet, e.g. "#ff8080". Allows variants of the same image
+	Tint       string                       `json:"tint"`
+	Animations map[string]AnimationManifest `json:"animations"`
+}
+
+type AnimationManifest struct {
+	StartTile  int     `json:"startTile"`
+	FrameCount int     `json:"frameCount"`
+	Speed      float64 `json:"speed"`
+}
+
+type ProjectileManifest struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	// Scale image to a square of this size. Takes precedence over scale
+	Size           int     `json:"size"`
+	Scale          float64 `json:"scale"`
+	AnimationSpeed float64 `json:"animationSpeed"`
+}
+
+type SoundManifest struct {
+	Name string `json:"name"`
+	// Ogg vorbis file
+	File string `json:"file"`
+}
+
+// Validation error pointing to the offending manifest entry
+type AssetManifestError struct {
+	Section string
+	Entry   string
+	Field   string
+	Reason  string
+}
+
+func (e *AssetManifestError) Error() string {
+	return fmt.Sprintf("Invalid asset manifest: %s '%s', field '%s': %s", e.Section, e.Entry, e.Field, e.Reason)
+}
+
+func manifestError(section, entry, field, reason string) error {
+	return &AssetManifestError{Section: section, Entry: entry, Field: field, Reason: reason}
+}
+
+// Parses & validates a manifest. Unknown fields are rejected to catch typos
+func ParseAssetManifest(data []byte) (*AssetManifest, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	m := &AssetManifest{}
+	if err := decoder.Decode(m); err != nil {
+		return nil, fmt.Errorf("Could not parse asset manifest: %s", err.Error())
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *AssetManifest) Validate() error {
+	names := map[string]bool{}
+	checkName := func(section string, idx int, name string) error {
+		if name == "" {
+			return manifestError(section, fmt.Sprintf("#%d", idx), "name", "required")
+		}
+		if names[section+name] {
+			return manifestError(section, name, "name", "duplicate")
+		}
+		names[section+name] = true
+		return nil
+	}
+	for idx, t := range m.Tilesets {
+		if err := checkName("tileset", idx, t.Name); err != nil {
+			return err
+		}
+		if t.Image == "" {
+			return manifestError("tileset", t.Name, "image", "required")
+		}
+		if t.TileWidth <= 0 {
+			return manifestError("tileset", t.Name, "tileWidth", "must be positive")
+		}
+		if t.TileHeight <= 0 {
+			return manifestError("tileset", t.Name, "tileHeight", "must be positive")
+		}
+	}
+	for idx, c := range m.Characters {
+		if err := checkName("character", idx, c.Name); err != nil {
+			return err
+		}
+		if c.Image == "" {
+			return manifestError("character", c.Name, "image", "required")
+		}
+		if c.TileWidth <= 0 {
+			return manifestError("character", c.Name, "tileWidth", "must be positive")
+		}
+		if c.TileHeight <= 0 {
+			return manifestError("character", c.Name, "tileHeight", "must be positive")
+		}
+		if c.Scale < 0 {
+			return manifestError("character", c.Name, "scale", "must not be negative")
+		}
+		if c.Tint != "" {
+			if _, err := parseHexColor(c.Tint); err != nil {
+				return manifestError("character", c.Name, "tint", err.Error())
+			}
+		}
+		if len(c.Animations) == 0 {
+			return manifestError("character", c.Name, "animations", "at least one animation required")
+		}
+		// Sorted to report errors deterministically
+		keys := []string{}
+		for key := range c.Animations {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			anim := c.Animations[key]
+			if anim.StartTile < 0 {
+				return manifestError("character", c.Name, "animations."+key+".startTile", "must not be negative")
+			}
+			if anim.FrameCount <= 0 {
+				return manifestError("character", c.Name, "animations."+key+".frameCount", "must be positive")
+			}
+			if anim.Speed < 0 {
+				return manifestError("character", c.Name, "animations."+key+".speed", "must not be negative")
+			}
+		}
+	}
+	for idx, p := range m.Projectiles {
+		if err := checkName("projectile", idx, p.Name); err != nil {
+			return err
+		}
+		if p.Image == "" {
+			return manifestError("projectile", p.Name, "image", "required")
+		}
+		if p.Size < 0 {
+			return manifestError("projectile", p.Name, "size", "must not be negative")
+		}
+		if p.Scale < 0 {
+			return manifestError("projectile", p.Name, "scale", "must not be negative")
+		}
+	}
+	for idx, s := range m.Sounds {
+		if err := checkName("sound", idx, s.Name); err != nil {
+			return err
+		}
+		if s.File == "" {
+			return manifestError("sound", s.Name, "file", "required")
+		}
+	}
+	return nil
+}
+
+// Subset of the manifest with all entries that load the given file
+func (m *AssetManifest) referencing(file string) *AssetManifest {
+	res := &AssetManifest{}
+	for _, t := range m.Tilesets {
+		if t.Image == file {
+			res.Tilesets = append(res.Tilesets, t)
+		}
+	}
+	for _, c := range m.Characters {
+		if c.Image == file {
+			res.Characters = append(res.Characters, c)
+		}
+	}
+	for _, p := range m.Projectiles {
+		if p.Image == file {
+			res.Projectiles = append(res.Projectiles, p)
+		}
+	}
+	for _, s := range m.Sounds {
+		if s.File == file {
+			res.Sounds = append(res.Sounds, s)
+		}
+	}
+	return res
+}
+
+// Parses colors in #rrggbb notation
+func parseHexColor(s string) (color.RGBA, error) {
+	var r, g, b uint8
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("must be a #rrggbb color")
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("must be a #rrggbb color")
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 0xff}, nil
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lucb31/game-engine-go/bin/assets"
+)
+
+type staticAnimationTime float64
+
+func (t staticAnimationTime) AnimationTime() float64 { return float64(t) }
+
+func TestBundledAssetManifest(t *testing.T) {
+	am, err := NewAssetManagerFromReader(staticAnimationTime(1), NewBundledAssetReader(assets.Files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := am.CharacterAsset("npc-orc"); err != nil {
+		t.Error(err)
+	}
+	if _, err := am.ProjectileAsset("arrow"); err != nil {
+		t.Error(err)
+	}
+	if _, err := am.Sound("shoot"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAssetManifestValidation(t *testing.T) {
+	cases := []struct {
+		manifest     string
+		entry, field string
+	}{
+		{`{"tilesets": [{"name": "plains", "image": "plains.png", "tileWidth": 0, "tileHeight": 16}]}`, "plains", "tileWidth"},
+		{`{"characters": [{"name": "orc", "image": "orc.png", "tileWidth": 16, "tileHeight": 16, "animations": {"walk": {"frameCount": 0}}}]}`, "orc", "animations.walk.frameCount"},
+		{`{"characters": [{"image": "orc.png"}]}`, "#0", "name"},
+		{`{"characters": [{"name": "orc", "image": "orc.png", "tileWidth": 16, "tileHeight": 16, "tint": "red", "animations": {"walk": {"frameCount": 1}}}]}`, "orc", "tint"},
+		{`{"sounds": [{"name": "shoot"}]}`, "shoot", "file"},
+	}
+	for _, c := range cases {
+		_, err := ParseAssetManifest([]byte(c.manifest))
+		var manifestErr *AssetManifestError
+		if !errors.As(err, &manifestErr) {
+			t.Errorf("Expected manifest error for %s, got %v", c.manifest, err)
+			continue
+		}
+		if manifestErr.Entry != c.entry || manifestErr.Field != c.field {
+			t.Errorf("Expected error for %s.%s, got %s", c.entry, c.field, err.Error())
+		}
+	}
+	// Typos should not be ignored silently
+	if _, err := ParseAssetManifest([]byte(`{"characters": [{"name": "orc", "tileWidht": 16}]}`)); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
+
+func TestAssetManifestFrameOutOfBounds(t *testing.T) {
+	files := map[string][]byte{
+		AssetManifestPath: []byte(`{"characters": [{"name": "slime", "image": "slime.png", "tileWidth": 24, "tileHeight": 24, "animations": {"idle": {"startTile": 0, "frameCount": 99}}}]}`),
+		"slime.png":       assets.Slime,
+	}
+	_, err := NewAssetManagerFromReader(nil, NewBundledAssetReader(files))
+	var manifestErr *AssetManifestError
+	if !errors.As(err, &manifestErr) || manifestErr.Field != "animations.idle.frameCount" {
+		t.Errorf("Expected frame count error, got %v", err)
+	}
+}
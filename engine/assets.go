@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"image"
 	_ "image/png"
-	"log"
 	"os"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -16,29 +15,60 @@ type AssetManager interface {
 	CharacterAsset(string) (*CharacterAsset, error)
 	ProjectileAsset(string) (*ProjectileAsset, error)
 	Tileset(string) (*Tileset, error)
+	// Encoded ogg vorbis data
+	Sound(string) ([]byte, error)
 }
 
 type AssetManagerImpl struct {
 	Tilesets         map[string]Tileset
 	characterAssets  map[string]CharacterAsset
 	projectileAssets map[string]ProjectileAsset
+	sounds           map[string][]byte
+
+	// Required to reload assets in dev mode
+	atp      AnimationTimeProvider
+	readFile AssetFileReader
+	manifest *AssetManifest
 }
 
+// Source of all asset files. Defaults to assets bundled into the binary
+var assetReader = NewBundledAssetReader(assets.Files)
+
+// Load assets & manifest from the given source for all following asset managers,
+// e.g. NewDirAssetReader("assets") to pick up new assets without running bundle.sh
+func SetAssetReader(readFile AssetFileReader) { assetReader = readFile }
+
 func NewAssetManager(atp AnimationTimeProvider) (*AssetManagerImpl, error) {
-	am := &AssetManagerImpl{}
-	var err error
+	return NewAssetManagerFromReader(atp, assetReader)
+}
+
+func NewAssetManagerFromReader(atp AnimationTimeProvider, readFile AssetFileReader) (*AssetManagerImpl, error) {
+	data, err := readFile(AssetManifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read asset manifest: %s", err.Error())
+	}
+	manifest, err := ParseAssetManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	am := &AssetManagerImpl{atp: atp, readFile: readFile, manifest: manifest}
+	am.Tilesets, err = loadEnvironmentTilesets(readFile, manifest)
+	if err != nil {
+		return nil, err
+	}
 
-	am.Tilesets, err = loadEnvironmentTilesets()
+	am.characterAssets, err = loadCharacterAssets(atp, readFile, manifest)
 	if err != nil {
 		return nil, err
 	}
 
-	am.characterAssets, err = loadCharacterAssets(atp)
+	am.projectileAssets, err = loadProjectileAssets(atp, readFile, manifest)
 	if err != nil {
 		return nil, err
 	}
 
-	am.projectileAssets, err = loadProjectileAssets(atp)
+	am.sounds, err = loadSounds(readFile, manifest)
 	if err != nil {
 		return nil, err
 	}
@@ -46,6 +76,67 @@ func NewAssetManager(atp AnimationTimeProvider) (*AssetManagerImpl, error) {
 	return am, nil
 }
 
+// Reload the manifest or all assets referencing the changed file.
+// Tilesets & character sheets are swapped in place, so entities keep their assets.
+// Nothing is applied if any of the affected assets fails to load
+func (a *AssetManagerImpl) Reload(path string) error {
+	manifest := a.manifest
+	if path == AssetManifestPath {
+		data, err := a.readFile(AssetManifestPath)
+		if err != nil {
+			return err
+		}
+		if manifest, err = ParseAssetManifest(data); err != nil {
+			return err
+		}
+	} else {
+		manifest = manifest.referencing(path)
+	}
+
+	tilesets, err := loadEnvironmentTilesets(a.readFile, manifest)
+	if err != nil {
+		return err
+	}
+	characters, err := loadCharacterAssets(a.atp, a.readFile, manifest)
+	if err != nil {
+		return err
+	}
+	projectiles, err := loadProjectileAssets(a.atp, a.readFile, manifest)
+	if err != nil {
+		return err
+	}
+	sounds, err := loadSounds(a.readFile, manifest)
+	if err != nil {
+		return err
+	}
+
+	for name, tileset := range tilesets {
+		if prev, ok := a.Tilesets[name]; ok {
+			prev.swap(tileset)
+		} else {
+			a.Tilesets[name] = tileset
+		}
+	}
+	for name, character := range characters {
+		if prev, ok := a.characterAssets[name]; ok {
+			*prev.CharacterSheet = *character.CharacterSheet
+		} else {
+			a.characterAssets[name] = character
+		}
+	}
+	// Projectiles are copied on creation, so only new projectiles pick up changes
+	for name, projectile := range projectiles {
+		a.projectileAssets[name] = projectile
+	}
+	for name, sound := range sounds {
+		a.sounds[name] = sound
+	}
+	if path == AssetManifestPath {
+		a.manifest = manifest
+	}
+	return nil
+}
+
 func (a *AssetManagerImpl) GetTile(tileSetKey string, tileIdx int) (*ebiten.Image, error) {
 	tileSet, ok := a.Tilesets[tileSetKey]
 	if !ok {
@@ -67,6 +158,7 @@ func (a *AssetManagerImpl) CharacterAsset(identifier string) (*CharacterAsset, e
 		return nil, err
 	}
 	asset.animationManager = animationManager
+	asset.name = identifier
 	var initialAnimation string
 	for key := range asset.Animations {
 		initialAnimation = key
@@ -86,6 +178,14 @@ func (a *AssetManagerImpl) ProjectileAsset(identifier string) (*ProjectileAsset,
 	return &res, nil
 }
 
+func (a *AssetManagerImpl) Sound(identifier string) ([]byte, error) {
+	res, ok := a.sounds[identifier]
+	if !ok {
+		return nil, fmt.Errorf("Trying to access unknown sound %s", identifier)
+	}
+	return res, nil
+}
+
 func (a *AssetManagerImpl) Tileset(identifier string) (*Tileset, error) {
 	res, ok := a.Tilesets[identifier]
 	if !ok {
@@ -94,278 +194,136 @@ func (a *AssetManagerImpl) Tileset(identifier string) (*Tileset, error) {
 	return &res, nil
 }
 
-type tileResource struct {
-	Key                  string
-	ImageData            []byte
-	TileSizeX, TileSizeY int
-}
-
-// TODO: Load these from config file
-var tileResources []tileResource = []tileResource{
-	// Used for td
-	{"plains", assets.Plains, 16, 16},
-	{"darkdimension", assets.Darkdimension, 16, 16},
-	{"props", assets.Props, 16, 16},
-}
-
 // Load tilesets for static resources
-func loadEnvironmentTilesets() (map[string]Tileset, error) {
+func loadEnvironmentTilesets(readFile AssetFileReader, manifest *AssetManifest) (map[string]Tileset, error) {
 	tiles := map[string]Tileset{}
-	for _, res := range tileResources {
-		tileset, err := loadTileset(res.ImageData, res.TileSizeX, res.TileSizeY, 1.0)
+	for _, res := range manifest.Tilesets {
+		data, err := readFile(res.Image)
 		if err != nil {
-			return nil, err
+			return nil, manifestError("tileset", res.Name, "image", err.Error())
+		}
+		tileset, err := loadTileset(data, res.TileWidth, res.TileHeight, 1.0)
+		if err != nil {
+			return nil, manifestError("tileset", res.Name, "image", err.Error())
 		}
-		tiles[res.Key] = *tileset
+		tiles[res.Name] = *tileset
 	}
 	return tiles, nil
 }
 
-type characterResource struct {
-	Key                  string
-	ImageData            []byte
-	TileSizeX, TileSizeY int
-	Animations           map[string]GameAssetAnimation
-	OffsetX, OffsetY     float64
-	Scale                float64
-}
-
-const defaultAnimationSpeed = 0.08
-
-// TODO: Load these from config file
-var characterResources []characterResource = []characterResource{
-	// {
-	// 	"player",
-	// 	assets.Player,
-	// 	48,
-	// 	48,
-	// 	map[string]GameAssetAnimation{
-	// 		"walk": {24, 6, 0.2},
-	// 		"idle": { 6, 6},
-	// 		"dash": { 42, FrameCount: 4},
-	// 	},
-	// 	-44,
-	// 	-60,
-	// 	1.8,
-	// },
-	{
-		"ranger",
-		assets.Ranger,
-		288,
-		128,
-		map[string]GameAssetAnimation{
-			"walk":    {StartTile: 22, FrameCount: 10, Speed: defaultAnimationSpeed},
-			"idle":    {StartTile: 0, FrameCount: 12, Speed: defaultAnimationSpeed},
-			"dash":    {StartTile: 198, FrameCount: 11, Speed: defaultAnimationSpeed},
-			"hit":     {StartTile: 330, FrameCount: 6, Speed: defaultAnimationSpeed},
-			"die":     {StartTile: 352, FrameCount: 18, Speed: defaultAnimationSpeed},
-			"dead":    {StartTile: 370, FrameCount: 1, Speed: defaultAnimationSpeed},
-			"shoot":   {StartTile: 242, FrameCount: 14, Speed: 0.05},
-			"harvest": {StartTile: 220, FrameCount: 10, Speed: 0.1},
-		},
-		-115,
-		-85,
-		0.8,
-	},
-	{
-		"npc-torch",
-		assets.NpcTorch,
-		192,
-		192,
-		map[string]GameAssetAnimation{
-			"attack": {StartTile: 14, FrameCount: 6, Speed: defaultAnimationSpeed * 2.5},
-			"idle":   {StartTile: 0, FrameCount: 7, Speed: defaultAnimationSpeed},
-			"walk":   {StartTile: 7, FrameCount: 6, Speed: defaultAnimationSpeed},
-		},
-		-40,
-		-37,
-		0.4,
-	},
-	{
-		"npc-orc",
-		assets.Orc,
-		100,
-		100,
-		map[string]GameAssetAnimation{
-			"walk":   {StartTile: 8, FrameCount: 6, Speed: defaultAnimationSpeed},
-			"idle":   {StartTile: 0, FrameCount: 6, Speed: defaultAnimationSpeed},
-			"attack": {StartTile: 16, FrameCount: 6, Speed: defaultAnimationSpeed * 2},
-		},
-		-60,
-		-60,
-		1.2,
-	},
-	{
-		"npc-slime",
-		assets.Slime,
-		24,
-		24,
-		map[string]GameAssetAnimation{
-			"walk":   {StartTile: 0, FrameCount: 2, Speed: defaultAnimationSpeed},
-			"idle":   {StartTile: 0, FrameCount: 2, Speed: defaultAnimationSpeed},
-			"attack": {StartTile: 0, FrameCount: 2, Speed: defaultAnimationSpeed},
-		},
-		-16,
-		-16,
-		1.5,
-	},
-	{
-		"tower-blue",
-		assets.TowerBlue,
-		256,
-		192,
-		map[string]GameAssetAnimation{
-			"idle": {StartTile: 0, FrameCount: 4, Speed: defaultAnimationSpeed},
-		},
-		-28,
-		-20,
-		0.22,
-	},
-	{
-		"tower-red",
-		assets.TowerRed,
-		256,
-		192,
-		map[string]GameAssetAnimation{
-			"idle": {StartTile: 0, FrameCount: 1, Speed: defaultAnimationSpeed},
-		},
-		-28,
-		-30,
-		0.22,
-	},
-	{
-		"castle",
-		assets.Castle,
-		320,
-		256,
-		map[string]GameAssetAnimation{
-			"idle": {StartTile: 0, FrameCount: 1},
-			"dead": {StartTile: 2, FrameCount: 1},
-		},
-		// -80,
-		// -75,
-		// 0.5,
-		-120,
-		-108,
-		0.75,
-	},
-	{
-		"tree_a",
-		assets.TreeA,
-		16,
-		32,
-		map[string]GameAssetAnimation{
-			"idle": {StartTile: 0, FrameCount: 1},
-		},
-		-16,
-		-56,
-		2.0,
-	},
-	{
-		"tree_b",
-		assets.TreeB,
-		45,
-		64,
-		map[string]GameAssetAnimation{
-			"idle": {StartTile: 0, FrameCount: 1},
-		},
-		-22.5,
-		-48,
-		1.0,
-	},
-	{
-		"tree_small",
-		assets.TreeSmall,
-		32,
-		32,
-		map[string]GameAssetAnimation{
-			"idle": {StartTile: 0, FrameCount: 1},
-		},
-		-16,
-		-16,
-		1.0,
-	},
-	{
-		"wood",
-		assets.Wood,
-		128,
-		128,
-		map[string]GameAssetAnimation{
-			"idle":  {StartTile: 5, FrameCount: 1},
-			"spawn": {StartTile: 0, FrameCount: 6, Speed: defaultAnimationSpeed},
-		},
-		-32,
-		-40,
-		0.5,
-	},
-}
-
 // Load characters
-func loadCharacterAssets(atp AnimationTimeProvider) (map[string]CharacterAsset, error) {
+func loadCharacterAssets(atp AnimationTimeProvider, readFile AssetFileReader, manifest *AssetManifest) (map[string]CharacterAsset, error) {
 	characters := map[string]CharacterAsset{}
-	for _, res := range characterResources {
-		tileset, err := loadTileset(res.ImageData, res.TileSizeX, res.TileSizeY, res.Scale)
+	for _, res := range manifest.Characters {
+		data, err := readFile(res.Image)
 		if err != nil {
-			return nil, err
+			return nil, manifestError("character", res.Name, "image", err.Error())
+		}
+		scale := res.Scale
+		if scale == 0 {
+			scale = 1.0
+		}
+		im, err := loadImageFromBinaryPng(data)
+		if err != nil {
+			return nil, manifestError("character", res.Name, "image", err.Error())
+		}
+		if res.Tint != "" {
+			tint, err := parseHexColor(res.Tint)
+			if err != nil {
+				return nil, manifestError("character", res.Name, "tint", err.Error())
+			}
+			im = TintImg(im, tint)
+		}
+		tileset, err := NewTileset(ebiten.NewImageFromImage(im), res.TileWidth, res.TileHeight, scale)
+		if err != nil {
+			return nil, manifestError("character", res.Name, "image", err.Error())
 		}
 		asset, err := NewCharacterAsset(atp)
 		if err != nil {
 			return nil, err
 		}
 		asset.Tileset = *tileset
-		asset.Animations = res.Animations
+		asset.Animations = map[string]GameAssetAnimation{}
+		for key, anim := range res.Animations {
+			// Animation frames need to exist within the sprite sheet
+			if anim.StartTile+anim.FrameCount > len(tileset.images) {
+				return nil, manifestError("character", res.Name, "animations."+key+".frameCount", fmt.Sprintf("exceeds sprite sheet with %d tiles", len(tileset.images)))
+			}
+			asset.Animations[key] = GameAssetAnimation{StartTile: anim.StartTile, FrameCount: anim.FrameCount, Speed: anim.Speed}
+		}
 		asset.offsetX = res.OffsetX
 		asset.offsetY = res.OffsetY
-		characters[res.Key] = *asset
+		characters[res.Name] = *asset
 	}
 	return characters, nil
 }
 
-func loadProjectileAssets(atp AnimationTimeProvider) (map[string]ProjectileAsset, error) {
+func loadProjectileAssets(atp AnimationTimeProvider, readFile AssetFileReader, manifest *AssetManifest) (map[string]ProjectileAsset, error) {
 	projectiles := map[string]ProjectileAsset{}
-
-	// Load bone
-	im, err := loadImageFromBinaryPng(assets.Bone)
-	if err != nil {
-		return nil, err
-	}
-	// Scale image to target size 16x61
-	targetSize := 16
-	rawIm := ebiten.NewImageFromImage(im)
-	scaledIm := ebiten.NewImage(targetSize, targetSize)
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Scale(float64(targetSize)/float64(rawIm.Bounds().Dx()), float64(targetSize)/float64(rawIm.Bounds().Dy()))
-	scaledIm.DrawImage(rawIm, op)
-	// Add to asset map
-	projectiles["bone"] = ProjectileAsset{
-		Image:          scaledIm,
-		atp:            atp,
-		animationSpeed: 2.0,
+	for _, res := range manifest.Projectiles {
+		data, err := readFile(res.Image)
+		if err != nil {
+			return nil, manifestError("projectile", res.Name, "image", err.Error())
+		}
+		im, err := loadImageFromBinaryPng(data)
+		if err != nil {
+			return nil, manifestError("projectile", res.Name, "image", err.Error())
+		}
+		rawIm := ebiten.NewImageFromImage(im)
+		scaledIm := rawIm
+		if res.Size > 0 {
+			// Scale image to target size
+			scaledIm = ebiten.NewImage(res.Size, res.Size)
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Scale(float64(res.Size)/float64(rawIm.Bounds().Dx()), float64(res.Size)/float64(rawIm.Bounds().Dy()))
+			scaledIm.DrawImage(rawIm, op)
+		} else if res.Scale > 0 {
+			scaledIm = ScaleImg(rawIm, res.Scale)
+		}
+		projectiles[res.Name] = ProjectileAsset{
+			Image:          scaledIm,
+			animationSpeed: res.AnimationSpeed,
+			atp:            atp,
+		}
 	}
+	return projectiles, nil
+}
 
-	// Load arrow
-	im, err = loadImageFromBinaryPng(assets.Arrow)
-	if err != nil {
-		return nil, err
-	}
-	projectiles["arrow"] = ProjectileAsset{
-		Image:          ScaleImg(ebiten.NewImageFromImage(im), 0.3),
-		animationSpeed: 0.0,
-		atp:            atp,
+// Sounds are kept encoded. Decoding happens when playing
+func loadSounds(readFile AssetFileReader, manifest *AssetManifest) (map[string][]byte, error) {
+	sounds := map[string][]byte{}
+	for _, res := range manifest.Sounds {
+		data, err := readFile(res.File)
+		if err != nil {
+			return nil, manifestError("sound", res.Name, "file", err.Error())
+		}
+		sounds[res.Name] = data
 	}
-	return projectiles, nil
+	return sounds, nil
 }
 
 func loadTileset(data []byte, tileSizeX, tileSizeY int, scale float64) (*Tileset, error) {
 	im, err := loadImageFromBinaryPng(data)
 	if err != nil {
-		log.Fatalln("Could not read assets!", err.Error())
 		return nil, err
 	}
 	ebitenImage := ebiten.NewImageFromImage(im)
 	return NewTileset(ebitenImage, tileSizeX, tileSizeY, scale)
 }
 
+// Loads a single png from the current asset source, e.g. for HUD icons
+func LoadImageAsset(path string) (*ebiten.Image, error) {
+	data, err := assetReader(path)
+	if err != nil {
+		return nil, err
+	}
+	im, err := loadImageFromBinaryPng(data)
+	if err != nil {
+		return nil, err
+	}
+	return ebiten.NewImageFromImage(im), nil
+}
+
 func loadImageFromBinaryPng(dat []byte) (image.Image, error) {
 	im, _, err := image.Decode(bytes.NewReader(dat))
 	if err != nil {
@@ -0,0 +1,280 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/jakecoffman/cp"
+)
+
+type AudioBus int
+
+const (
+	AudioBusSFX AudioBus = iota
+	AudioBusMusic
+	audioBusCount
+)
+
+const (
+	// Upper limit of sound effects playing at once
+	maxVoices = 16
+	// Upper limit of instances of the same sound effect playing at once
+	maxVoicesPerSound = 4
+	// Sounds within this distance to the listener play at full volume
+	audioFullVolumeDistance = 200.0
+	// Sounds beyond this distance to the listener are not played
+	audioMaxDistance = 900.0
+	// Horizontal distance at which sounds are panned fully to one side
+	audioPanDistance = 600.0
+
+	defaultSFXVolume   = 0.4
+	defaultMusicVolume = 0.6
+)
+
+type AudioListener interface {
+	// Position of the listener in world coordinates
+	ListenerPosition() cp.Vector
+	// False, if sounds at this position should not be heard, e.g. hidden in fog of war
+	Audible(cp.Vector) bool
+}
+
+type SoundProvider interface {
+	// Encoded ogg vorbis data
+	Sound(string) ([]byte, error)
+}
+
+// Plays sounds decoded once & pooled per sound. All methods are no-ops without audio context, e.g. in headless mode
+type AudioManager struct {
+	context  *audio.Context
+	sounds   SoundProvider
+	listener AudioListener
+
+	// Decoded 16bit stereo PCM by sound name
+	decoded    map[string][]byte
+	voices     map[string][]*soundVoice
+	busVolumes [audioBusCount]float64
+	music      *audio.Player
+}
+
+// Pooled player with its own stream to pan per playback
+type soundVoice struct {
+	player *audio.Player
+	stream *pannedStream
+}
+
+func NewAudioManager() *AudioManager {
+	a := &AudioManager{
+		context: audio.CurrentContext(),
+		decoded: map[string][]byte{},
+		voices:  map[string][]*soundVoice{},
+	}
+	a.busVolumes[AudioBusSFX] = defaultSFXVolume
+	a.busVolumes[AudioBusMusic] = defaultMusicVolume
+	return a
+}
+
+func (a *AudioManager) SetSoundProvider(sounds SoundProvider) { a.sounds = sounds }
+func (a *AudioManager) SetListener(listener AudioListener)    { a.listener = listener }
+func (a *AudioManager) BusVolume(bus AudioBus) float64        { return a.busVolumes[bus] }
+
+// Volume between 0 & 1. Applies to the running music immediately & to all following sound effects
+func (a *AudioManager) SetBusVolume(bus AudioBus, volume float64) {
+	a.busVolumes[bus] = math.Max(0, math.Min(1, volume))
+	if bus == AudioBusMusic && a.music != nil {
+		a.music.SetVolume(a.busVolumes[bus])
+	}
+}
+
+// Play sound effect emitted at the given world position. Volume & pan depend on the
+// distance to the listener. Sounds are dropped if out of range, not audible or no voice is available
+func (a *AudioManager) PlaySE(name string, pos cp.Vector) error {
+	if a.context == nil {
+		return nil
+	}
+	gain, pan := 1.0, 0.0
+	if a.listener != nil {
+		if !a.listener.Audible(pos) {
+			return nil
+		}
+		gain, pan = spatialize(a.listener.ListenerPosition(), pos)
+		if gain <= 0 {
+			return nil
+		}
+	}
+	voice, err := a.voice(name)
+	if err != nil || voice == nil {
+		return err
+	}
+	voice.stream.SetPan(pan)
+	voice.player.SetVolume(gain * a.busVolumes[AudioBusSFX])
+	if err := voice.player.Rewind(); err != nil {
+		return err
+	}
+	voice.player.Play()
+	return nil
+}
+
+// Loop sound on the music bus. Replaces the running music
+func (a *AudioManager) PlayMusic(name string) error {
+	if a.context == nil {
+		return nil
+	}
+	pcm, err := a.decode(name)
+	if err != nil {
+		return err
+	}
+	player, err := a.context.NewPlayer(audio.NewInfiniteLoop(bytes.NewReader(pcm), int64(len(pcm))))
+	if err != nil {
+		return err
+	}
+	a.StopMusic()
+	a.music = player
+	a.music.SetVolume(a.busVolumes[AudioBusMusic])
+	a.music.Play()
+	return nil
+}
+
+func (a *AudioManager) StopMusic() {
+	if a.music != nil {
+		a.music.Close()
+		a.music = nil
+	}
+}
+
+// Returns an idle voice for the sound. Nil, if voice limit has been reached
+func (a *AudioManager) voice(name string) (*soundVoice, error) {
+	playing := 0
+	var idle *soundVoice
+	for key, voices := range a.voices {
+		for _, voice := range voices {
+			if voice.player.IsPlaying() {
+				playing++
+			} else if key == name && idle == nil {
+				idle = voice
+			}
+		}
+	}
+	if playing >= maxVoices {
+		return nil, nil
+	}
+	if idle != nil {
+		return idle, nil
+	}
+	if len(a.voices[name]) >= maxVoicesPerSound {
+		return nil, nil
+	}
+	pcm, err := a.decode(name)
+	if err != nil {
+		return nil, err
+	}
+	stream := &pannedStream{pcm: pcm}
+	stream.SetPan(0)
+	player, err := a.context.NewPlayer(stream)
+	if err != nil {
+		return nil, err
+	}
+	voice := &soundVoice{player, stream}
+	a.voices[name] = append(a.voices[name], voice)
+	return voice, nil
+}
+
+func (a *AudioManager) decode(name string) ([]byte, error) {
+	if pcm, ok := a.decoded[name]; ok {
+		return pcm, nil
+	}
+	if a.sounds == nil {
+		return nil, fmt.Errorf("Cannot play sound %s without sound provider", name)
+	}
+	data, err := a.sounds.Sound(name)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := vorbis.DecodeWithSampleRate(a.context.SampleRate(), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode sound %s: %s", name, err.Error())
+	}
+	pcm, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode sound %s: %s", name, err.Error())
+	}
+	a.decoded[name] = pcm
+	return pcm, nil
+}
+
+// Returns gain between 0 & 1 & pan between -1 (left) & 1 (right)
+func spatialize(listener, source cp.Vector) (float64, float64) {
+	dist := listener.Distance(source)
+	gain := 1.0
+	if dist > audioFullVolumeDistance {
+		gain = 1 - (dist-audioFullVolumeDistance)/(audioMaxDistance-audioFullVolumeDistance)
+	}
+	pan := math.Max(-1, math.Min(1, (source.X-listener.X)/audioPanDistance))
+	return math.Max(0, gain), pan
+}
+
+// Plays sound effect via the audio manager registered to the space of an entity
+func playSpaceSE(space *cp.Space, name string, pos cp.Vector) error {
+	if space == nil {
+		return nil
+	}
+	u, ok := space.StaticBody.UserData.(SpaceUserData)
+	if !ok || u.audio == nil {
+		return nil
+	}
+	return u.audio.PlaySE(name, pos)
+}
+
+// Reads 16bit stereo PCM & applies a gain per channel
+type pannedStream struct {
+	pcm         []byte
+	pos         int64
+	left, right float64
+}
+
+func (s *pannedStream) SetPan(pan float64) {
+	s.left = math.Min(1-pan, 1)
+	s.right = math.Min(1+pan, 1)
+}
+
+func (s *pannedStream) Read(p []byte) (int, error) {
+	if s.pos >= int64(len(s.pcm)) {
+		return 0, io.EOF
+	}
+	// Only read full frames of 2 channels * 2 bytes
+	n := copy(p[:len(p)&^3], s.pcm[s.pos:])
+	for i := 0; i+3 < n; i += 4 {
+		applyGain(p[i:i+2], s.left)
+		applyGain(p[i+2:i+4], s.right)
+	}
+	s.pos += int64(n)
+	return n, nil
+}
+
+func (s *pannedStream) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		s.pos = offset
+	case io.SeekCurrent:
+		s.pos += offset
+	case io.SeekEnd:
+		s.pos = int64(len(s.pcm)) + offset
+	}
+	if s.pos < 0 {
+		s.pos = 0
+	}
+	return s.pos, nil
+}
+
+func applyGain(sample []byte, gain float64) {
+	if gain >= 1 {
+		return
+	}
+	v := int16(uint16(sample[0]) | uint16(sample[1])<<8)
+	v = int16(float64(v) * gain)
+	sample[0] = byte(v)
+	sample[1] = byte(uint16(v) >> 8)
+}
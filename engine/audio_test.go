@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"io"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+func TestSpatialize(t *testing.T) {
+	listener := cp.Vector{X: 500, Y: 500}
+	if gain, pan := spatialize(listener, listener); gain != 1 || pan != 0 {
+		t.Errorf("Expected full volume & centered at listener, got gain %f pan %f", gain, pan)
+	}
+	if gain, _ := spatialize(listener, listener.Add(cp.Vector{X: audioMaxDistance})); gain != 0 {
+		t.Errorf("Expected silence at max distance, got gain %f", gain)
+	}
+	if _, pan := spatialize(listener, listener.Add(cp.Vector{X: -2 * audioPanDistance})); pan != -1 {
+		t.Errorf("Expected sound panned fully left, got %f", pan)
+	}
+}
+
+func TestPannedStream(t *testing.T) {
+	// One frame: left & right sample at 1000
+	stream := &pannedStream{pcm: []byte{0xe8, 0x03, 0xe8, 0x03}}
+	stream.SetPan(1)
+	frame, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := int16(uint16(frame[0]) | uint16(frame[1])<<8)
+	right := int16(uint16(frame[2]) | uint16(frame[3])<<8)
+	if left != 0 || right != 1000 {
+		t.Errorf("Expected sound on right channel only, got left %d right %d", left, right)
+	}
+}
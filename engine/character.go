@@ -17,15 +17,23 @@ type GameAssetAnimation struct {
 
 type CharacterAsset struct {
 	animationManager AnimationController
-	Animations       map[string]GameAssetAnimation
-	Tileset          Tileset
-	offsetX          float64
-	offsetY          float64
-	atp              AnimationTimeProvider
+	// Shared between all copies of the asset, so it can be swapped on hot reload
+	*CharacterSheet
+	atp AnimationTimeProvider
+	// Identifier within asset manager. Used to restore entities from snapshots
+	name string
+}
+
+// Sprite sheet & animation table of a character asset
+type CharacterSheet struct {
+	Animations map[string]GameAssetAnimation
+	Tileset    Tileset
+	offsetX    float64
+	offsetY    float64
 }
 
 func NewCharacterAsset(atp AnimationTimeProvider) (*CharacterAsset, error) {
-	a := &CharacterAsset{atp: atp}
+	a := &CharacterAsset{atp: atp, CharacterSheet: &CharacterSheet{}}
 	return a, nil
 }
 
@@ -80,6 +88,7 @@ func (a *CharacterAsset) Animation(animationKey string) (*GameAssetAnimation, er
 
 func (a *CharacterAsset) AnimationController() AnimationController { return a.animationManager }
 func (a *CharacterAsset) AnimationTime() float64                   { return a.atp.AnimationTime() }
+func (a *CharacterAsset) Name() string                             { return a.name }
 
 func (a *CharacterAsset) DrawHealthbar(t RenderingTarget, shape *cp.Shape, health, maxHealth float64) {
 	// How many px the healthbar should take in height
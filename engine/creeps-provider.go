@@ -6,6 +6,19 @@ type CreepProvider interface {
 	NextNpc(wave Wave) (GameEntity, error)
 }
 
+// Providers of creeps that release further creeps when killed, e.g. splitting slimes
+type SplittingCreepProvider interface {
+	CreepProvider
+	// Creeps to add to the active wave after the given creep died
+	CreepsOnDeath(creep GameEntity, wave Wave) ([]GameEntity, error)
+}
+
+// Providers of bosses spawned at the start of boss waves
+type BossCreepProvider interface {
+	CreepProvider
+	NextBoss(wave Wave) (*NpcBoss, error)
+}
+
 type DefaultCreepProvider struct {
 	asset *CharacterAsset
 	opts  *NpcOpts
@@ -5,6 +5,7 @@ import (
 	"log"
 	"math"
 
+	"github.com/jakecoffman/cp"
 	"github.com/lucb31/game-engine-go/engine/hud"
 )
 
@@ -14,6 +15,21 @@ type CreepManager interface {
 	Round() int
 	SetProvider(c CreepProvider) error
 	IdleTimeout() Timeout
+	// Health of the active boss. Zero if there is none
+	BossProgress() hud.ProgressInfo
+	Snapshot() CreepManagerSnapshot
+	// Restore wave state. Npcs need to be added to the world already
+	Restore(s CreepManagerSnapshot, npcs []GameEntity) error
+}
+
+type CreepManagerSnapshot struct {
+	Round         int
+	CreepsSpawned int
+	CreepsAlive   int
+	WaveCleared   bool
+	BossSpawned   bool `json:",omitempty"`
+	SpawnTimeout  TimerSnapshot
+	IdleTimeout   TimerSnapshot
 }
 
 type BaseCreepManager struct {
@@ -27,6 +43,14 @@ type BaseCreepManager struct {
 	creepsSpawned int
 	creepsAlive   int
 	waveCleared   bool
+	// Ids of alive creeps. Used to count kills
+	creeps map[GameEntityId]bool
+	// Boss waves spawn a boss before any other creep
+	bossSpawned bool
+	// Nil once dead
+	boss *NpcBoss
+	// Every n-th wave is a boss wave. 0 = no boss waves
+	bossInterval int
 	// Timer to control creep spawns during an active wave
 	creepSpawnTimeout Timeout
 
@@ -34,7 +58,12 @@ type BaseCreepManager struct {
 	spawnIdleTimeout Timeout
 }
 
-const idleTimeAfterWaveFinished = 30.0
+const (
+	idleTimeAfterWaveFinished = 30.0
+	defaultBossWaveInterval   = 5
+	// Gap between boss & summoned minions
+	minionSpawnGap = 24.0
+)
 
 type Wave struct {
 	Round              int
@@ -42,10 +71,14 @@ type Wave struct {
 	WaveTicksPerSecond float64
 	CreepsPerTick      int
 	HealthScalingFunc  func(baseHealth float64) float64
+	// Starts with a boss, if the provider supports bosses
+	Boss bool
 }
 
 func NewBaseCreepManager(em GameEntityManager) (*BaseCreepManager, error) {
-	cm := &BaseCreepManager{entityManager: em}
+	cm := &BaseCreepManager{entityManager: em, creeps: map[GameEntityId]bool{}, bossInterval: defaultBossWaveInterval}
+	Subscribe(em.Events(), cm.onEntityDestroyed)
+	Subscribe(em.Events(), cm.onEntityKilled)
 	var err error
 	if cm.creepSpawnTimeout, err = NewIngameTimeout(em); err != nil {
 		return nil, err
@@ -76,6 +109,9 @@ func NewDefaultCreepManager(em GameEntityManager, asset *CharacterAsset) (*BaseC
 }
 
 func (c *BaseCreepManager) Update() error {
+	if c.boss != nil {
+		c.boss.Update()
+	}
 	// As long as the idle timer is running, we're not supposed to do anything
 	if !c.spawnIdleTimeout.Done() {
 		return nil
@@ -90,6 +126,7 @@ func (c *BaseCreepManager) Update() error {
 		log.Println("Wave cleared. Setting idle timeout")
 		c.waveCleared = true
 		c.spawnIdleTimeout.Set(idleTimeAfterWaveFinished)
+		Publish(c.entityManager.Events(), WaveCleared{c.activeWave.Round})
 		return nil
 	}
 	// Next wave ready: Spawn next wave, if cleared & timeout done
@@ -101,14 +138,37 @@ func (c *BaseCreepManager) Update() error {
 	return nil
 }
 
-// Special entity remove callback required to keep track of alive creeps
-func (c *BaseCreepManager) RemoveEntity(entity BaseEntity) error {
-	// Remove npc from game world
-	if err := c.entityManager.RemoveEntity(entity); err != nil {
-		return err
+// Keep track of alive creeps
+func (c *BaseCreepManager) onEntityDestroyed(e EntityDestroyed) {
+	if !c.creeps[e.Entity.Id()] {
+		return
 	}
+	delete(c.creeps, e.Entity.Id())
 	c.creepsAlive--
-	return nil
+	if c.boss != nil && c.boss.Id() == e.Entity.Id() {
+		c.boss = nil
+	}
+}
+
+// Spawn creeps released by killed creeps. They count towards the active wave
+func (c *BaseCreepManager) onEntityKilled(e EntityKilled) {
+	provider, ok := c.creepProvider.(SplittingCreepProvider)
+	if !ok || !c.creeps[e.Entity.Id()] {
+		return
+	}
+	creeps, err := provider.CreepsOnDeath(e.Entity, *c.activeWave)
+	if err != nil {
+		log.Println("Could not spawn creeps on death", err.Error())
+		return
+	}
+	for _, creep := range creeps {
+		if err := c.entityManager.AddEntity(creep); err != nil {
+			log.Println("Could not add creep", err.Error())
+			continue
+		}
+		c.creeps[creep.Id()] = true
+		c.creepsAlive++
+	}
 }
 
 func (c *BaseCreepManager) Progress() hud.ProgressInfo {
@@ -123,6 +183,17 @@ func (c *BaseCreepManager) Progress() hud.ProgressInfo {
 	return hud.ProgressInfo{Min: 0, Max: c.activeWave.TotalCreepsToSpawn, Current: c.creepsSpawned, Label: label}
 }
 
+func (c *BaseCreepManager) BossProgress() hud.ProgressInfo {
+	if c.boss == nil {
+		return hud.ProgressInfo{}
+	}
+	label := fmt.Sprintf("%s - Phase %d", c.boss.Name(), c.boss.Phase()+1)
+	return hud.ProgressInfo{Min: 0, Max: int(c.boss.MaxHealth()), Current: int(c.boss.Health()), Label: label}
+}
+
+// Takes effect from the next wave on. 0 disables boss waves
+func (c *BaseCreepManager) SetBossInterval(n int) { c.bossInterval = n }
+
 func (c *BaseCreepManager) Round() int           { return c.activeWave.Round }
 func (c *BaseCreepManager) IdleTimeout() Timeout { return c.spawnIdleTimeout }
 func (c *BaseCreepManager) SetProvider(p CreepProvider) error {
@@ -130,12 +201,55 @@ func (c *BaseCreepManager) SetProvider(p CreepProvider) error {
 	return c.NextWave()
 }
 
+func (c *BaseCreepManager) Snapshot() CreepManagerSnapshot {
+	return CreepManagerSnapshot{
+		Round:         c.activeWave.Round,
+		CreepsSpawned: c.creepsSpawned,
+		CreepsAlive:   c.creepsAlive,
+		WaveCleared:   c.waveCleared,
+		BossSpawned:   c.bossSpawned,
+		SpawnTimeout:  c.creepSpawnTimeout.Snapshot(),
+		IdleTimeout:   c.spawnIdleTimeout.Snapshot(),
+	}
+}
+
+func (c *BaseCreepManager) Restore(s CreepManagerSnapshot, npcs []GameEntity) error {
+	if s.Round < 1 {
+		return fmt.Errorf("Invalid wave round %d", s.Round)
+	}
+	wave := calculateWaveOpts(s.Round, c.bossInterval)
+	c.activeWave = &wave
+	c.creepsSpawned = s.CreepsSpawned
+	c.creepsAlive = s.CreepsAlive
+	c.waveCleared = s.WaveCleared
+	c.bossSpawned = s.BossSpawned
+	c.boss = nil
+	c.creepSpawnTimeout.Restore(s.SpawnTimeout)
+	c.spawnIdleTimeout.Restore(s.IdleTimeout)
+	// Keep track of alive creeps again
+	c.creeps = map[GameEntityId]bool{}
+	for _, npc := range npcs {
+		c.creeps[npc.Id()] = true
+		if boss, ok := npc.(*NpcBoss); ok {
+			c.boss = boss
+			boss.SetSummoner(c.summonMinions)
+		}
+	}
+	return nil
+}
+
 func (c *BaseCreepManager) spawnCreep() error {
 	// Timeout until creep spawn timer over
 	if !c.creepSpawnTimeout.Done() {
 		return nil
 	}
 
+	if c.activeWave.Boss && !c.bossSpawned {
+		if err := c.spawnBoss(); err != nil {
+			return err
+		}
+	}
+
 	// Initialize an npc
 	for i := 0; i < c.activeWave.CreepsPerTick && c.creepsSpawned < c.activeWave.TotalCreepsToSpawn; i++ {
 		npc, err := c.creepProvider.NextNpc(*c.activeWave)
@@ -143,8 +257,7 @@ func (c *BaseCreepManager) spawnCreep() error {
 			return err
 		}
 		c.entityManager.AddEntity(npc)
-		// Overwrite remove callback
-		npc.SetEntityRemover(c)
+		c.creeps[npc.Id()] = true
 		c.creepsAlive++
 		c.creepsSpawned++
 	}
@@ -154,9 +267,51 @@ func (c *BaseCreepManager) spawnCreep() error {
 	return nil
 }
 
+// Boss is skipped if the provider does not support bosses
+func (c *BaseCreepManager) spawnBoss() error {
+	c.bossSpawned = true
+	provider, ok := c.creepProvider.(BossCreepProvider)
+	if !ok {
+		return nil
+	}
+	boss, err := provider.NextBoss(*c.activeWave)
+	if err != nil {
+		return err
+	}
+	boss.SetSummoner(c.summonMinions)
+	if err := c.entityManager.AddEntity(boss); err != nil {
+		return err
+	}
+	c.creeps[boss.Id()] = true
+	c.creepsAlive++
+	c.boss = boss
+	return nil
+}
+
+// Spawn creeps of the active wave in a circle around the boss. They count towards the wave
+func (c *BaseCreepManager) summonMinions(boss *NpcBoss, count int) error {
+	bb := boss.Shape().BB()
+	distance := max(bb.R-bb.L, bb.T-bb.B)/2 + minionSpawnGap
+	for idx := range count {
+		npc, err := c.creepProvider.NextNpc(*c.activeWave)
+		if err != nil {
+			return err
+		}
+		offset := cp.ForAngle(2 * math.Pi * float64(idx) / float64(count)).Mult(distance)
+		npc.Shape().Body().SetPosition(boss.Shape().Body().Position().Add(offset))
+		if err := c.entityManager.AddEntity(npc); err != nil {
+			return err
+		}
+		c.creeps[npc.Id()] = true
+		c.creepsAlive++
+	}
+	return nil
+}
+
 // Wave scaling
-func calculateWaveOpts(round int) Wave {
+func calculateWaveOpts(round int, bossInterval int) Wave {
 	wave := Wave{Round: round}
+	wave.Boss = bossInterval > 0 && round%bossInterval == 0
 	wave.TotalCreepsToSpawn = int(math.Exp(float64(round)/4) + 29)
 	wave.WaveTicksPerSecond = 0.5
 	wave.CreepsPerTick = 5
@@ -169,12 +324,16 @@ func (c *BaseCreepManager) NextWave() error {
 	if c.activeWave != nil {
 		nextRound = c.activeWave.Round + 1
 	}
-	wave := calculateWaveOpts(nextRound)
+	wave := calculateWaveOpts(nextRound, c.bossInterval)
 	c.activeWave = &wave
 	c.creepsAlive = 0
+	clear(c.creeps)
 	c.creepsSpawned = 0
 	c.waveCleared = false
+	c.bossSpawned = false
+	c.boss = nil
 	log.Printf("Starting wave %v...\n", c.activeWave)
 	c.creepSpawnTimeout.Set(1 / wave.WaveTicksPerSecond)
+	Publish(c.entityManager.Events(), WaveStarted{wave.Round})
 	return nil
 }
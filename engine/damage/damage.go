@@ -2,13 +2,33 @@ package damage
 
 import (
 	"fmt"
-	"log"
+	"math"
+	"math/rand/v2"
 
 	"github.com/jakecoffman/cp"
 )
 
+type DamageType string
+
+const (
+	Physical DamageType = "physical"
+	Fire     DamageType = "fire"
+	Poison   DamageType = "poison"
+)
+
+const (
+	// Armor at which half of the physical damage is mitigated
+	ArmorScaling = 100.0
+	// Upper limit for resistances. Nothing should be immune
+	MaxResistance = 0.75
+	// Default +/- random spread of damage. 0.1 = 90% - 110%
+	DefaultVariance       = 0.1
+	DefaultCritMultiplier = 1.5
+)
+
+// Calculates damage. Applying damage & resolving deaths is up to the engine
 type DamageModel interface {
-	ApplyDamage(atk Attacker, def Defender, gameTime float64) (*DamageRecord, error)
+	CalculateDamage(atk Attacker, def Defender) (Hit, error)
 	DamageLog() DamageLog
 }
 
@@ -20,68 +40,96 @@ type Attacker interface {
 	AtkSpeed() float64
 }
 
+// Optional: Attackers dealing other than physical damage
+type TypedAttacker interface {
+	DamageType() DamageType
+}
+
+// Optional: Attackers that can land critical hits
+type CritAttacker interface {
+	// 0.1 = 10% chance
+	CritChance() float64
+	CritMultiplier() float64
+}
+
 type Defender interface {
 	Health() float64
+	// Mitigates physical damage with diminishing returns
 	Armor() float64
-	Destroy() error
+	// Fraction of damage of the given type that is mitigated. Negative values increase damage
+	Resistance(DamageType) float64
 	SetHealth(float64)
 	Shape() *cp.Shape
 	IsVulnerable() bool
 }
 
+// Result of a damage calculation
+type Hit struct {
+	Type   DamageType
+	Damage float64
+	// Damage prevented by armor & resistances
+	Mitigated float64
+	Crit      bool
+}
+
 type BasicDamageModel struct {
 	damageLog DamageLog
+	// Source of variance & crit rolls. No rolls without generator
+	rng      *rand.Rand
+	variance float64
 }
 
-func NewBasicDamageModel() (*BasicDamageModel, error) {
+func NewBasicDamageModel(rng *rand.Rand) (*BasicDamageModel, error) {
 	log, err := NewInMemoryDamageLog()
 	if err != nil {
 		return nil, err
 	}
-	return &BasicDamageModel{damageLog: log}, nil
+	return &BasicDamageModel{damageLog: log, rng: rng, variance: DefaultVariance}, nil
 }
 
-func (m *BasicDamageModel) CalculateDamage(atk Attacker, def Defender) (float64, error) {
+func (m *BasicDamageModel) SetVariance(v float64) { m.variance = v }
+
+func (m *BasicDamageModel) CalculateDamage(atk Attacker, def Defender) (Hit, error) {
 	// Check if vulnerable
 	if !def.IsVulnerable() {
-		return 0.0, fmt.Errorf("Defender is invulnerable")
+		return Hit{}, fmt.Errorf("Defender is invulnerable")
 	}
-	// TODO: Percentage based armor
-	damage := atk.Power() - def.Armor()
-	// Fix to never return negative damage
-	if damage < 0 {
-		log.Println("Warning: Negative damage, this should not happen")
-		return 0, nil
+	hit := Hit{Type: Physical}
+	if typed, ok := atk.(TypedAttacker); ok {
+		hit.Type = typed.DamageType()
 	}
-	return damage, nil
-}
 
-func (m *BasicDamageModel) ApplyDamage(atk Attacker, def Defender, gameTime float64) (*DamageRecord, error) {
-	// Calculate damage
-	damage, err := m.CalculateDamage(atk, def)
-	if err != nil {
-		return nil, err
+	raw := math.Max(0, atk.Power())
+	if m.rng != nil && m.variance > 0 {
+		raw *= 1 + m.variance*(2*m.rng.Float64()-1)
+	}
+	if crit, ok := atk.(CritAttacker); ok && m.rng != nil && crit.CritChance() > 0 {
+		if m.rng.Float64() < crit.CritChance() {
+			hit.Crit = true
+			raw *= crit.CritMultiplier()
+		}
 	}
 
-	newHealth := def.Health() - damage
-	def.SetHealth(newHealth)
-
-	// Log damage
-	rec := &DamageRecord{gameTime, damage, def.Shape().Body().Position(), newHealth <= 0.0}
-	err = m.damageLog.Add(*rec)
-	if err != nil {
-		log.Println("Could not log damage record. Continuing anyways...", *rec, err.Error())
+	// Multiplicative stacking: 50% armor & 50% resistance = 75% mitigation
+	taken := 1 - ResistanceMitigation(def.Resistance(hit.Type))
+	if hit.Type == Physical {
+		taken *= 1 - ArmorMitigation(def.Armor())
 	}
+	hit.Damage = raw * taken
+	hit.Mitigated = raw - hit.Damage
+	return hit, nil
+}
 
-	if newHealth <= 0.0 {
-		// TODO: dont destroy here, but within engine
-		err := def.Destroy()
-		if err != nil {
-			return nil, err
-		}
+// Fraction of physical damage mitigated by armor: 100 armor = 50%, 300 armor = 75%
+func ArmorMitigation(armor float64) float64 {
+	if armor <= 0 {
+		return 0
 	}
+	return armor / (armor + ArmorScaling)
+}
 
-	return rec, nil
+func ResistanceMitigation(resistance float64) float64 {
+	return math.Min(resistance, MaxResistance)
 }
 
 func (m *BasicDamageModel) DamageLog() DamageLog { return m.damageLog }
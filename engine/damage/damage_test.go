@@ -0,0 +1,101 @@
+package damage
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+type testAttacker struct {
+	power      float64
+	damageType DamageType
+	critChance float64
+}
+
+func (a testAttacker) Power() float64          { return a.power }
+func (a testAttacker) AtkSpeed() float64       { return 1 }
+func (a testAttacker) DamageType() DamageType  { return a.damageType }
+func (a testAttacker) CritChance() float64     { return a.critChance }
+func (a testAttacker) CritMultiplier() float64 { return 2 }
+
+type testDefender struct {
+	armor       float64
+	resistances map[DamageType]float64
+}
+
+func (d testDefender) Health() float64                 { return 100 }
+func (d testDefender) Armor() float64                  { return d.armor }
+func (d testDefender) Resistance(t DamageType) float64 { return d.resistances[t] }
+func (d testDefender) SetHealth(float64)               {}
+func (d testDefender) Shape() *cp.Shape                { return nil }
+func (d testDefender) IsVulnerable() bool              { return true }
+
+func TestArmorAndResistances(t *testing.T) {
+	model, err := NewBasicDamageModel(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := testDefender{armor: 100, resistances: map[DamageType]float64{Fire: 0.5, Poison: 2}}
+	cases := []struct {
+		damageType DamageType
+		expected   float64
+	}{
+		// 100 armor mitigates half of physical damage
+		{Physical, 50},
+		// Armor does not apply to fire, only resistance
+		{Fire, 50},
+		// Resistances are capped
+		{Poison, 100 * (1 - MaxResistance)},
+	}
+	for _, c := range cases {
+		hit, err := model.CalculateDamage(testAttacker{power: 100, damageType: c.damageType}, def)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(hit.Damage-c.expected) > 1e-9 || math.Abs(hit.Mitigated-(100-c.expected)) > 1e-9 {
+			t.Errorf("Expected %.1f %s damage, got %.1f (%.1f mitigated)", c.expected, c.damageType, hit.Damage, hit.Mitigated)
+		}
+	}
+	// Diminishing returns: doubling armor does not double mitigation
+	if ArmorMitigation(200) >= 2*ArmorMitigation(100) {
+		t.Error("Expected diminishing returns for armor")
+	}
+}
+
+func TestCritAndVarianceAreSeeded(t *testing.T) {
+	roll := func(seed uint64) []Hit {
+		model, err := NewBasicDamageModel(rand.New(rand.NewPCG(seed, seed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hits := []Hit{}
+		for range 50 {
+			hit, err := model.CalculateDamage(testAttacker{power: 100, damageType: Physical, critChance: 0.5}, testDefender{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hits = append(hits, hit)
+		}
+		return hits
+	}
+	first, second := roll(42), roll(42)
+	crits := 0
+	for idx, hit := range first {
+		if hit != second[idx] {
+			t.Fatalf("Expected identical rolls for same seed, got %v and %v", hit, second[idx])
+		}
+		low, high := 100*(1-DefaultVariance), 100*(1+DefaultVariance)
+		if hit.Crit {
+			crits++
+			low, high = low*2, high*2
+		}
+		if hit.Damage < low || hit.Damage > high {
+			t.Errorf("Damage %.1f outside of variance range %.1f - %.1f", hit.Damage, low, high)
+		}
+	}
+	if crits == 0 || crits == len(first) {
+		t.Errorf("Expected some but not all hits to crit, got %d of %d", crits, len(first))
+	}
+}
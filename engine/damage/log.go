@@ -11,6 +11,20 @@ type DamageRecord struct {
 	Damage   float64
 	Pos      cp.Vector
 	Fatal    bool
+	Type     DamageType
+	Crit     bool
+	// Damage prevented by armor & resistances
+	Mitigated float64
+	// Id of the attacking entity. -1, if attacker is unknown
+	AttackerId int
+}
+
+func (r DamageRecord) String() string {
+	crit := ""
+	if r.Crit {
+		crit = " (crit)"
+	}
+	return fmt.Sprintf("%.1f %s damage%s, %.1f mitigated, attacker %d, fatal %t", r.Damage, r.Type, crit, r.Mitigated, r.AttackerId, r.Fatal)
 }
 
 type DamageLog interface {
@@ -3,6 +3,7 @@ package engine
 import (
 	"fmt"
 	"math"
+	"math/rand/v2"
 
 	"github.com/jakecoffman/cp"
 	"github.com/lucb31/game-engine-go/engine/damage"
@@ -32,6 +33,10 @@ type Lootable interface {
 	LootTable() loot.LootTable
 }
 
+type LootTableSetter interface {
+	SetLootTable(loot.LootTable)
+}
+
 type BaseEntity interface {
 	Id() GameEntityId
 	SetId(GameEntityId)
@@ -77,8 +82,15 @@ type GameEntityManager interface {
 	AddEntity(object GameEntity) error
 	Space() *cp.Space
 	DamageModel() damage.DamageModel
+	DamageApplier
 	DropLoot(loot loot.LootTable, pos cp.Vector) error
+	// Use to fire projectiles instead of creating new ones
+	Projectiles() *ProjectilePool
 	EndGame()
+	// Publish & subscribe to gameplay events
+	Events() *EventBus
+	// Seeded random generator of the world. Use instead of global math/rand
+	Rng() *rand.Rand
 }
 
 type GameEntityStatReader interface {
@@ -102,30 +114,60 @@ type GameEntityStatWriter interface {
 type GameEntityStatReadWriter interface {
 	GameEntityStatReader
 	GameEntityStatWriter
+	StatModifierStack
 }
 
+// Getters return the final value including modifiers, setters change the base value
 type GameEntityStats struct {
-	armor         float64
-	atkSpeed      float64
-	health        float64
-	maxHealth     float64
-	movementSpeed float64
-	power         float64
+	armor          float64
+	atkSpeed       float64
+	health         float64
+	maxHealth      float64
+	movementSpeed  float64
+	power          float64
+	critChance     float64
+	critMultiplier float64
+	resistances    map[damage.DamageType]float64
+
+	modifiers      []StatModifier
+	nextModifierId StatModifierId
 }
 
 func DefaultGameEntityStats() GameEntityStats {
-	return GameEntityStats{0, 1.0, 100.0, 100.0, 100.0, 30.0}
+	return GameEntityStats{
+		atkSpeed:       1.0,
+		health:         100.0,
+		maxHealth:      100.0,
+		movementSpeed:  100.0,
+		power:          30.0,
+		critMultiplier: damage.DefaultCritMultiplier,
+	}
 }
-func (s *GameEntityStats) Armor() float64         { return s.armor }
-func (s *GameEntityStats) AtkSpeed() float64      { return s.atkSpeed }
+func (s *GameEntityStats) Armor() float64         { return s.final(StatArmor, s.armor) }
+func (s *GameEntityStats) AtkSpeed() float64      { return s.final(StatAtkSpeed, s.atkSpeed) }
 func (s *GameEntityStats) Health() float64        { return s.health }
-func (s *GameEntityStats) MaxHealth() float64     { return s.maxHealth }
-func (s *GameEntityStats) Power() float64         { return s.power }
-func (s *GameEntityStats) MovementSpeed() float64 { return s.movementSpeed }
-
-func (s *GameEntityStats) SetArmor(v float64)         { s.armor = v }
-func (s *GameEntityStats) SetAtkSpeed(v float64)      { s.atkSpeed = v }
-func (s *GameEntityStats) SetHealth(h float64)        { s.health = math.Min(h, s.maxHealth) }
-func (s *GameEntityStats) SetPower(v float64)         { s.power = v }
-func (s *GameEntityStats) SetMaxHealth(v float64)     { s.maxHealth = v }
-func (s *GameEntityStats) SetMovementSpeed(v float64) { s.movementSpeed = v }
+func (s *GameEntityStats) MaxHealth() float64     { return s.final(StatMaxHealth, s.maxHealth) }
+func (s *GameEntityStats) Power() float64         { return s.final(StatPower, s.power) }
+func (s *GameEntityStats) MovementSpeed() float64 { return s.final(StatMovementSpeed, s.movementSpeed) }
+func (s *GameEntityStats) CritChance() float64    { return s.final(StatCritChance, s.critChance) }
+func (s *GameEntityStats) CritMultiplier() float64 {
+	return s.final(StatCritMultiplier, s.critMultiplier)
+}
+func (s *GameEntityStats) Resistance(t damage.DamageType) float64 {
+	return s.resistances[t]
+}
+
+func (s *GameEntityStats) SetArmor(v float64)          { s.armor = v }
+func (s *GameEntityStats) SetAtkSpeed(v float64)       { s.atkSpeed = v }
+func (s *GameEntityStats) SetHealth(h float64)         { s.health = math.Min(h, s.MaxHealth()) }
+func (s *GameEntityStats) SetPower(v float64)          { s.power = v }
+func (s *GameEntityStats) SetMaxHealth(v float64)      { s.maxHealth = v }
+func (s *GameEntityStats) SetMovementSpeed(v float64)  { s.movementSpeed = v }
+func (s *GameEntityStats) SetCritChance(v float64)     { s.critChance = v }
+func (s *GameEntityStats) SetCritMultiplier(v float64) { s.critMultiplier = v }
+func (s *GameEntityStats) SetResistance(t damage.DamageType, v float64) {
+	if s.resistances == nil {
+		s.resistances = map[damage.DamageType]float64{}
+	}
+	s.resistances[t] = v
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+// Items worn by an entity. Affixes of equipped items are applied as stat modifiers
+type Equipment struct {
+	target StatModifierStack
+	slots  map[loot.EquipmentSlot]*loot.ItemInstance
+}
+
+func NewEquipment(target StatModifierStack) (*Equipment, error) {
+	if target == nil {
+		return nil, fmt.Errorf("Cannot init equipment without stats")
+	}
+	return &Equipment{target: target, slots: map[loot.EquipmentSlot]*loot.ItemInstance{}}, nil
+}
+
+// Affixes are applied as stat modifiers. Unknown stats or ops would silently have no effect
+func ValidateAffix(affix loot.AffixDefinition) error {
+	if !Stat(affix.Stat).Valid() {
+		return fmt.Errorf("Unknown stat %q", affix.Stat)
+	}
+	if !StatModifierOp(affix.Op).Valid() {
+		return fmt.Errorf("Unknown stat modifier op %q", affix.Op)
+	}
+	return nil
+}
+
+// Equips the item into its slot. Returns the previously equipped item, if any
+func (e *Equipment) Equip(instance *loot.ItemInstance) (*loot.ItemInstance, error) {
+	if instance == nil || instance.Item.Slot == "" {
+		return nil, fmt.Errorf("Cannot equip %s", instance)
+	}
+	previous := e.Unequip(instance.Item.Slot)
+	e.slots[instance.Item.Slot] = instance
+	for _, affix := range instance.Affixes {
+		e.target.AddModifier(StatModifier{
+			Stat:   Stat(affix.Stat),
+			Op:     StatModifierOp(affix.Op),
+			Value:  affix.Value,
+			Source: equipmentSource(instance),
+		})
+	}
+	return previous, nil
+}
+
+// Removes & returns the item in the slot including its modifiers
+func (e *Equipment) Unequip(slot loot.EquipmentSlot) *loot.ItemInstance {
+	instance, ok := e.slots[slot]
+	if !ok {
+		return nil
+	}
+	delete(e.slots, slot)
+	e.target.RemoveModifiersFrom(equipmentSource(instance))
+	return instance
+}
+
+// Nil, if nothing is equipped
+func (e *Equipment) Slot(slot loot.EquipmentSlot) *loot.ItemInstance { return e.slots[slot] }
+
+// Currently equipped items by slot
+func (e *Equipment) Equipped() map[loot.EquipmentSlot]*loot.ItemInstance { return maps.Clone(e.slots) }
+
+// Restores equipped items without touching modifiers. Those are persisted with the stats
+func (e *Equipment) Restore(slots map[loot.EquipmentSlot]*loot.ItemInstance) {
+	e.slots = maps.Clone(slots)
+	if e.slots == nil {
+		e.slots = map[loot.EquipmentSlot]*loot.ItemInstance{}
+	}
+}
+
+// Instance id keeps modifiers of two copies of the same item apart
+func equipmentSource(instance *loot.ItemInstance) StatSource {
+	return StatSource{Kind: StatSourceItem, Name: fmt.Sprintf("%s #%d", instance.Item.Description, instance.Id)}
+}
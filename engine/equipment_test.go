@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+func TestEquipmentAppliesAffixes(t *testing.T) {
+	sword := &loot.GameItem{Id: "sword", Description: "Sword", Slot: loot.EquipmentWeapon,
+		Affixes: []loot.AffixDefinition{{Stat: string(StatPower), Op: string(ModifierAdd), Min: 5, Max: 15}}}
+	db, err := loot.NewItemDB(sword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewPCG(1, 1))
+	first, _ := db.NewInstance("sword", 1, rng)
+	second, _ := db.NewInstance("sword", 1, rng)
+	if first.Id == second.Id {
+		t.Fatalf("Expected unique instance ids, got %d twice", first.Id)
+	}
+
+	stats := DefaultGameEntityStats()
+	equipment, err := NewEquipment(&stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := equipment.Equip(first); err != nil {
+		t.Fatal(err)
+	}
+	if expected := 30 + first.Affixes[0].Value; stats.Power() != expected {
+		t.Fatalf("Expected power %f, got %f", expected, stats.Power())
+	}
+	// Replacing the weapon swaps modifiers
+	previous, err := equipment.Equip(second)
+	if err != nil || previous != first {
+		t.Fatalf("Expected first sword to be returned, got %v, %v", previous, err)
+	}
+	if expected := 30 + second.Affixes[0].Value; stats.Power() != expected {
+		t.Fatalf("Expected power %f, got %f", expected, stats.Power())
+	}
+	equipment.Unequip(loot.EquipmentWeapon)
+	if stats.Power() != 30 || len(stats.Modifiers(StatPower)) != 0 {
+		t.Fatalf("Expected modifiers removed, got power %f", stats.Power())
+	}
+}
+
+func TestItemDBRejectsUnknownAffixes(t *testing.T) {
+	db, err := loot.NewItemDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetAffixValidator(ValidateAffix)
+	affixes := []loot.AffixDefinition{
+		{Stat: "powr", Op: string(ModifierAdd), Min: 1, Max: 2},
+		{Stat: string(StatPower), Op: "mult", Min: 1, Max: 2},
+	}
+	for _, affix := range affixes {
+		item := &loot.GameItem{Id: "typo-" + affix.Stat + affix.Op, Slot: loot.EquipmentWeapon, Affixes: []loot.AffixDefinition{affix}}
+		if err := db.Register(item); err == nil {
+			t.Errorf("Expected error for %s %s affix", affix.Stat, affix.Op)
+		}
+	}
+	valid := &loot.GameItem{Id: "sword", Slot: loot.EquipmentWeapon, Affixes: []loot.AffixDefinition{{Stat: string(StatPower), Op: string(ModifierAdd), Min: 1, Max: 2}}}
+	if err := db.Register(valid); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"reflect"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/damage"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+// ///////////////
+// Gameplay events
+// ///////////////
+
+// Entity has been added to the world
+type EntitySpawned struct {
+	Entity GameEntity
+}
+
+// Entity has been removed from the world. Published at the end of the step
+type EntityDestroyed struct {
+	Entity GameEntity
+}
+
+// Entity died. Published after its death hook ran. Killer is nil, if the attacker cannot be attributed
+type EntityKilled struct {
+	Entity GameEntity
+	Killer GameEntity
+	Record damage.DamageRecord
+}
+
+type DamageApplied struct {
+	Attacker damage.Attacker
+	Defender damage.Defender
+	Record   damage.DamageRecord
+}
+
+type LootCollected struct {
+	Receiver GameEntityWithInventory
+	// Evaluated loot table
+	Loot []loot.LootTableItem
+	Pos  cp.Vector
+}
+
+type WaveStarted struct {
+	Round int
+}
+
+type WaveCleared struct {
+	Round int
+}
+
+type BuildingEntered struct {
+	Building GameEntityEnterable
+	Entity   GameEntityEntering
+}
+
+type BuildingLeft struct {
+	Building GameEntityEnterable
+	Entity   GameEntityEntering
+}
+
+type GameOver struct{}
+
+// ///////////////
+// Bus
+// ///////////////
+
+// Synchronous publish / subscribe by event type. Handlers are called in order of subscription,
+// so event handling stays deterministic for replays
+type EventBus struct {
+	handlers map[reflect.Type][]eventSubscription
+	nextId   int
+}
+
+type eventSubscription struct {
+	id      int
+	handler any
+}
+
+// Removes the handler from the bus
+type Unsubscribe func()
+
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: map[reflect.Type][]eventSubscription{}}
+}
+
+// Call handler for every published event of type E
+func Subscribe[E any](bus *EventBus, handler func(E)) Unsubscribe {
+	key := reflect.TypeFor[E]()
+	id := bus.nextId
+	bus.nextId++
+	bus.handlers[key] = append(bus.handlers[key], eventSubscription{id, handler})
+	return func() {
+		subs := bus.handlers[key]
+		for idx, sub := range subs {
+			if sub.id == id {
+				bus.handlers[key] = append(subs[:idx:idx], subs[idx+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func Publish[E any](bus *EventBus, event E) {
+	if bus == nil {
+		return
+	}
+	// Copy handlers, since handlers might (un)subscribe while handling the event
+	subs := bus.handlers[reflect.TypeFor[E]()]
+	if len(subs) == 0 {
+		return
+	}
+	for _, sub := range append([]eventSubscription{}, subs...) {
+		sub.handler.(func(E))(event)
+	}
+}
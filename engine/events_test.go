@@ -0,0 +1,29 @@
+package engine
+
+import "testing"
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	rounds := []int{}
+	unsubscribe := Subscribe(bus, func(e WaveStarted) { rounds = append(rounds, e.Round) })
+	Subscribe(bus, func(e WaveStarted) { rounds = append(rounds, e.Round*10) })
+	cleared := 0
+	Subscribe(bus, func(WaveCleared) { cleared++ })
+
+	Publish(bus, WaveStarted{1})
+	unsubscribe()
+	Publish(bus, WaveStarted{2})
+
+	expected := []int{1, 10, 20}
+	if len(rounds) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, rounds)
+	}
+	for idx := range expected {
+		if rounds[idx] != expected[idx] {
+			t.Fatalf("Expected events %v in subscription order, got %v", expected, rounds)
+		}
+	}
+	if cleared != 0 {
+		t.Errorf("Expected no wave cleared events, got %d", cleared)
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/jakecoffman/cp"
+)
+
+// Fires straight at a position chosen by the owner, e.g. ranged npcs. Projectiles miss targets that move
+type AimedGun struct {
+	*BasicGun
+	aim cp.Vector
+}
+
+func NewAimedGun(em GameEntityManager, owner GameEntity, proj *ProjectileAsset, opts BasicGunOpts) (*AimedGun, error) {
+	base, err := newBasicGun(em, owner, proj, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &AimedGun{BasicGun: base}, nil
+}
+
+func (g *AimedGun) SetAim(pos cp.Vector) { g.aim = pos }
+
+func (g *AimedGun) Shoot() error {
+	if g.IsReloading() {
+		return fmt.Errorf("Still Reloading...")
+	}
+	direction := g.aim.Sub(g.Position())
+	if direction.LengthSq() == 0 {
+		return nil
+	}
+	proj, err := g.em.Projectiles().Acquire(g, g.projectileAsset)
+	if err != nil || proj == nil {
+		return err
+	}
+	proj.staticVelocity = direction.Normalize().Mult(proj.velocity)
+	if err := g.em.AddEntity(proj); err != nil {
+		return err
+	}
+	g.reloadTimeout.Set(1 / g.FireRate())
+	if err := g.PlayShootSE(); err != nil {
+		return err
+	}
+	if g.playShootAnimation != nil {
+		orientation := ^West
+		if direction.X > 0 {
+			orientation = West
+		}
+		g.playShootAnimation(g.FireRate(), orientation)
+	}
+	return nil
+}
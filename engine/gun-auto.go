@@ -2,7 +2,6 @@ package engine
 
 import (
 	"fmt"
-	"math/rand/v2"
 )
 
 type AutoAimGun struct {
@@ -37,16 +36,23 @@ func (g *AutoAimGun) Shoot() error {
 		return nil
 	}
 	// Spawn projectile for every target
+	fired := 0
 	for _, target := range targets {
-		proj, err := NewProjectile(g, g.projectileAsset)
+		proj, err := g.em.Projectiles().Acquire(g, g.projectileAsset)
 		if err != nil {
 			return err
 		}
+		// Cap reached
+		if proj == nil {
+			break
+		}
+		fired++
 		proj.SetTarget(target)
-		// Random 1 in 5 chance for the projectile to pierce
-		proj.SetPiercing(rand.IntN(5) == 0)
 		g.em.AddEntity(proj)
 	}
+	if fired == 0 {
+		return nil
+	}
 	// Set one common reload timer
 	g.reloadTimeout.Set(1 / g.FireRate())
 
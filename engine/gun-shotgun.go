@@ -44,10 +44,14 @@ func (g *ShotGun) Shoot() error {
 			X: g.owner.Shape().Body().Position().X + math.Sin(angleInRad)*g.fireRange,
 			Y: g.owner.Shape().Body().Position().Y + math.Cos(angleInRad)*g.fireRange,
 		}
-		proj, err := NewProjectile(g, g.projectileAsset)
+		proj, err := g.em.Projectiles().Acquire(g, g.projectileAsset)
 		if err != nil {
 			return err
 		}
+		// Cap reached
+		if proj == nil {
+			break
+		}
 		proj.direction = direction
 		g.em.AddEntity(proj)
 	}
@@ -26,8 +26,8 @@ func (g *SimpleGun) Shoot() error {
 	}
 
 	// Spawn projectile at owner position & orientation
-	proj, err := NewProjectile(g, g.projectileAsset)
-	if err != nil {
+	proj, err := g.em.Projectiles().Acquire(g, g.projectileAsset)
+	if err != nil || proj == nil {
 		return err
 	}
 	proj.direction = directionFromOrientationAndPos(*g.orientation, g.Owner().Shape().Body().Position())
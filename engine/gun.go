@@ -1,12 +1,9 @@
 package engine
 
 import (
-	"bytes"
+	"slices"
 
-	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
 	"github.com/jakecoffman/cp"
-	"github.com/lucb31/game-engine-go/bin/assets"
 	"github.com/lucb31/game-engine-go/engine/damage"
 )
 
@@ -18,18 +15,37 @@ type Gun interface {
 	// Nr of projectiles per second
 	FireRate() float64
 	ProjectileCount() int
+	// Max nr of projectiles of this gun alive at the same time. 0 = unlimited
+	MaxProjectiles() int
 	Power() float64
 	IsReloading() bool
 	Owner() GameEntity
 
 	SetShootingAnimationCallback(ShootingAnimationCallback)
 	SetProjectileCount(int)
+	// Status effects applied to every target hit by a projectile of this gun
+	OnHitEffects() []StatusEffect
+	AddOnHitEffect(StatusEffect)
+	// Pierce, fork, chain, split & homing of every projectile fired
+	ProjectileBehaviours() []ProjectileBehaviour
+	AddProjectileBehaviour(ProjectileBehaviour)
+	Snapshot() GunSnapshot
+	Restore(GunSnapshot)
+}
+
+type GunSnapshot struct {
+	ProjectileCount int
+	Reload          TimerSnapshot
+	OnHitEffects    []StatusEffect        `json:",omitempty"`
+	Behaviours      []ProjectileBehaviour `json:",omitempty"`
 }
 
 type BasicGunOpts struct {
 	FireRatePerSecond float64
 	FireRange         float64
 	Damage            float64
+	Behaviours        []ProjectileBehaviour
+	MaxProjectiles    int
 }
 
 type BasicGun struct {
@@ -44,7 +60,11 @@ type BasicGun struct {
 	fireRatePerSecond float64
 	fireRange         float64
 	damage            float64
+	damageType        damage.DamageType
 	projectileCount   int
+	maxProjectiles    int
+	onHitEffects      []StatusEffect
+	behaviours        []ProjectileBehaviour
 
 	// Callback to play shooting animation
 	playShootAnimation ShootingAnimationCallback
@@ -73,6 +93,13 @@ func newBasicGun(em GameEntityManager, owner GameEntity, proj *ProjectileAsset,
 	if opts.Damage > 0 {
 		gun.damage = opts.Damage
 	}
+	gun.maxProjectiles = opts.MaxProjectiles
+	for _, b := range opts.Behaviours {
+		if err := b.Validate(); err != nil {
+			return nil, err
+		}
+		gun.behaviours = AddProjectileBehaviour(gun.behaviours, b)
+	}
 	return gun, nil
 }
 
@@ -95,6 +122,7 @@ func (g *BasicGun) Owner() GameEntity    { return g.owner }
 func (g *BasicGun) Position() cp.Vector  { return g.owner.Shape().Body().Position() }
 func (g *BasicGun) FireRange() float64   { return g.fireRange }
 func (g *BasicGun) ProjectileCount() int { return g.projectileCount }
+func (g *BasicGun) MaxProjectiles() int  { return g.maxProjectiles }
 func (g *BasicGun) IsReloading() bool {
 	return !g.reloadTimeout.Done()
 }
@@ -102,19 +130,51 @@ func (g *BasicGun) IsReloading() bool {
 func (g *BasicGun) SetShootingAnimationCallback(playShootAnimation ShootingAnimationCallback) {
 	g.playShootAnimation = playShootAnimation
 }
-func (g *BasicGun) SetProjectileCount(count int) { g.projectileCount = count }
+func (g *BasicGun) SetProjectileCount(count int)      { g.projectileCount = count }
+func (g *BasicGun) SetDamageType(t damage.DamageType) { g.damageType = t }
+func (g *BasicGun) OnHitEffects() []StatusEffect      { return g.onHitEffects }
+func (g *BasicGun) AddOnHitEffect(e StatusEffect) {
+	g.onHitEffects = append(g.onHitEffects, e)
+}
+func (g *BasicGun) ProjectileBehaviours() []ProjectileBehaviour { return g.behaviours }
+func (g *BasicGun) AddProjectileBehaviour(b ProjectileBehaviour) {
+	g.behaviours = AddProjectileBehaviour(g.behaviours, b)
+}
+func (g *BasicGun) DamageType() damage.DamageType {
+	if g.damageType == "" {
+		return damage.Physical
+	}
+	return g.damageType
+}
 
-func (g *BasicGun) PlayShootSE() error {
-	reader := bytes.NewReader(assets.ShootOGG)
-	stream, err := vorbis.DecodeWithoutResampling(reader)
-	if err != nil {
-		return err
+// Crits depend on the stats of the owner
+func (g *BasicGun) CritChance() float64 {
+	if crit, ok := g.owner.(damage.CritAttacker); ok {
+		return crit.CritChance()
 	}
-	player, err := audio.CurrentContext().NewPlayer(stream)
-	if err != nil {
-		return err
+	return 0
+}
+func (g *BasicGun) CritMultiplier() float64 {
+	if crit, ok := g.owner.(damage.CritAttacker); ok {
+		return crit.CritMultiplier()
+	}
+	return damage.DefaultCritMultiplier
+}
+func (g *BasicGun) Snapshot() GunSnapshot {
+	return GunSnapshot{
+		ProjectileCount: g.projectileCount,
+		Reload:          g.reloadTimeout.Snapshot(),
+		OnHitEffects:    slices.Clone(g.onHitEffects),
+		Behaviours:      slices.Clone(g.behaviours),
 	}
-	player.SetVolume(SFX_VOLUME)
-	player.Play()
-	return nil
+}
+func (g *BasicGun) Restore(s GunSnapshot) {
+	g.projectileCount = s.ProjectileCount
+	g.onHitEffects = slices.Clone(s.OnHitEffects)
+	g.behaviours = slices.Clone(s.Behaviours)
+	g.reloadTimeout.Restore(s.Reload)
+}
+
+func (g *BasicGun) PlayShootSE() error {
+	return playSpaceSE(g.owner.Shape().Space(), "shoot", g.Position())
 }
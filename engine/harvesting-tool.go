@@ -1,15 +1,10 @@
 package engine
 
 import (
-	"bytes"
 	"fmt"
 	"log"
-	"math/rand"
 
-	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
 	"github.com/jakecoffman/cp"
-	"github.com/lucb31/game-engine-go/bin/assets"
 	"github.com/lucb31/game-engine-go/engine/damage"
 )
 
@@ -69,8 +64,6 @@ type WoodHarvestingTool struct {
 	target     Harvestable
 	harvesting bool
 
-	// SFX
-	player *audio.Player
 	// Used to sync harvesting SE with animation
 	sfxTimeout Timeout
 }
@@ -110,32 +103,17 @@ func (ht *WoodHarvestingTool) Nearest() Harvestable {
 	return harvestable
 }
 
-func (ht *WoodHarvestingTool) InRange() bool     { return ht.Nearest() != nil }
-func (ht *WoodHarvestingTool) Harvesting() bool  { return ht.harvesting }
-func (ht *WoodHarvestingTool) AtkSpeed() float64 { return ht.harvestingSpeed }
-func (ht *WoodHarvestingTool) Power() float64    { return ht.harvestingPower }
+func (ht *WoodHarvestingTool) InRange() bool           { return ht.Nearest() != nil }
+func (ht *WoodHarvestingTool) Harvesting() bool        { return ht.harvesting }
+func (ht *WoodHarvestingTool) AtkSpeed() float64       { return ht.harvestingSpeed }
+func (ht *WoodHarvestingTool) Power() float64          { return ht.harvestingPower }
+func (ht *WoodHarvestingTool) AttackOwner() GameEntity { return ht.owner }
 func (ht *WoodHarvestingTool) SetAnimationController(ac AnimationController) {
 	ht.animationController = ac
 }
 
 func (ht *WoodHarvestingTool) PlayHarvestingSE() error {
-	// Init player first time, else rewind
-	if ht.player == nil {
-		reader := bytes.NewReader(assets.PunchTreeOGG)
-		stream, err := vorbis.DecodeWithoutResampling(reader)
-		if err != nil {
-			return err
-		}
-		ht.player, err = audio.CurrentContext().NewPlayer(stream)
-		if err != nil {
-			return err
-		}
-		ht.player.SetVolume(SFX_VOLUME)
-	} else if err := ht.player.Rewind(); err != nil {
-		return err
-	}
-	ht.player.Play()
-	return nil
+	return playSpaceSE(ht.owner.Shape().Space(), "punch-tree", ht.owner.Shape().Body().Position())
 }
 
 func (ht *WoodHarvestingTool) Update() error {
@@ -161,29 +139,13 @@ func (ht *WoodHarvestingTool) Update() error {
 	if !ok {
 		return fmt.Errorf("Cannot apply harvest damage: No damage model found\n")
 	}
-	rec, err := spaceUserData.damageModel.ApplyDamage(ht, ht.target, ht.em.IngameTime())
+	rec, err := spaceUserData.applyDamage(ht, ht.target)
 	if err != nil {
 		return fmt.Errorf("Could not apply damage: %e\n", err)
 	}
-	// Fatal -> Harvesting done
-	if rec.Fatal {
+	// Fatal -> Harvesting done. Loot is dropped by the world
+	if rec == nil || rec.Fatal {
 		log.Println("Finished harvesting")
-		// Disabling. Currently damage model is destroying
-		// if err := ht.target.Destroy(); err != nil {
-		// 	return err
-		// }
-
-		// Drop loot at slightly randomized position
-		pos := ht.target.Position().Add(cp.Vector{rand.Float64()*20 - 10, rand.Float64()*20 - 10})
-		if err := ht.em.DropLoot(ht.target.LootTable(), pos); err != nil {
-			return err
-		}
-
-		// Directly add loot for harvesting target
-		// if err := ht.owner.Inventory().AddLoot(ht.target.LootTable()); err != nil {
-		// 	return err
-		// }
-
 		// Reset harvesting tool
 		return ht.Abort()
 	}
@@ -228,8 +190,5 @@ func (ht *WoodHarvestingTool) Abort() error {
 	ht.swingTimer.Stop()
 	ht.sfxTimeout.Stop()
 	ht.animationController.StopPlaying()
-	if ht.player != nil {
-		ht.player.Pause()
-	}
 	return nil
 }
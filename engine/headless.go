@@ -0,0 +1,21 @@
+package engine
+
+// Game that can be stepped without ebiten.RunGame, i.e. without window or GPU
+type HeadlessGame interface {
+	Update() error
+	GameOver() bool
+}
+
+// Advance the game by a fixed amount of ticks. Every tick simulates GameSpeed / 60 seconds of ingame time.
+// Stops early once the game is over. Returns the number of ticks simulated
+func RunHeadless(game HeadlessGame, ticks int) (int, error) {
+	for tick := range ticks {
+		if game.GameOver() {
+			return tick, nil
+		}
+		if err := game.Update(); err != nil {
+			return tick, err
+		}
+	}
+	return ticks, nil
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Default interval between two scans of the assets directory
+const hotReloadInterval = 500 * time.Millisecond
+
+// Resources that can swap their data in place when a file in the assets directory changed.
+// Path is relative to the assets directory. Files that are not used by the resource need to be ignored.
+// On error the previous version needs to be kept
+type HotReloadable interface {
+	Reload(path string) error
+}
+
+// Watches the assets directory by polling modification times. There is no portable
+// file notification API available, and polling twice a second is cheap for a few hundred files
+type HotReloader struct {
+	dir      string
+	interval time.Duration
+	lastPoll time.Time
+	modTimes map[string]time.Time
+	watchers []HotReloadable
+}
+
+// Directory of the dev mode. Empty if hot reload is disabled
+var hotReloadDir string
+
+// Dev mode: Read assets & maps from the given directory and reload them on change
+// for all following worlds. Desktop only
+func EnableHotReload(dir string) {
+	hotReloadDir = dir
+	SetAssetReader(NewDirAssetReader(dir))
+}
+
+func HotReloadEnabled() bool { return hotReloadDir != "" }
+
+// Reader of the current asset source. Use this for files that should be hot reloaded in dev mode
+func AssetReader() AssetFileReader { return assetReader }
+
+func NewHotReloader(dir string) *HotReloader {
+	r := &HotReloader{dir: dir, interval: hotReloadInterval, modTimes: map[string]time.Time{}}
+	// Initial scan. Only report changes after startup
+	r.modTimes = r.scan()
+	r.lastPoll = time.Now()
+	return r
+}
+
+func (r *HotReloader) Watch(watcher HotReloadable) { r.watchers = append(r.watchers, watcher) }
+
+// Dispatch changed files to all watchers. Needs to be called from the game loop,
+// so that data is never swapped mid-frame
+func (r *HotReloader) Update() {
+	if time.Since(r.lastPoll) < r.interval {
+		return
+	}
+	r.lastPoll = time.Now()
+	modTimes := r.scan()
+	for path, modTime := range modTimes {
+		if prev, ok := r.modTimes[path]; ok && prev.Equal(modTime) {
+			continue
+		}
+		if err := r.reload(path); err != nil {
+			log.Printf("Could not reload %s: %s \n", path, err.Error())
+			continue
+		}
+		log.Println("Reloaded", path)
+	}
+	r.modTimes = modTimes
+}
+
+// Every watcher validates before swapping its own data. A failing watcher keeps its
+// previous version, but must not prevent the others from picking up the change
+func (r *HotReloader) reload(path string) error {
+	errs := []error{}
+	for _, watcher := range r.watchers {
+		if err := watcher.Reload(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *HotReloader) scan() map[string]time.Time {
+	res := map[string]time.Time{}
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(r.dir, path)
+		if err != nil {
+			return err
+		}
+		res[filepath.ToSlash(rel)] = info.ModTime()
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("Could not scan assets directory: ", err.Error())
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lucb31/game-engine-go/bin/assets"
+)
+
+func TestAssetManagerReloadKeepsPreviousOnError(t *testing.T) {
+	slime := func(frameCount string) []byte {
+		return []byte(`{"characters": [{"name": "slime", "image": "slime.png", "tileWidth": 24, "tileHeight": 24, "animations": {"idle": {"startTile": 0, "frameCount": ` + frameCount + `}}}]}`)
+	}
+	files := map[string][]byte{AssetManifestPath: slime("2"), "slime.png": assets.Slime}
+	am, err := NewAssetManagerFromReader(staticAnimationTime(1), NewBundledAssetReader(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset, err := am.CharacterAsset("slime")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Broken manifest & frames out of bounds need to keep the loaded version
+	for _, broken := range [][]byte{[]byte(`{"characters": [`), slime("99")} {
+		files[AssetManifestPath] = broken
+		if err := am.Reload(AssetManifestPath); err == nil {
+			t.Error("Expected reload error")
+		}
+		if anim, _ := asset.Animation("idle"); anim.FrameCount != 2 {
+			t.Errorf("Expected previous animation to be kept, got %d frames", anim.FrameCount)
+		}
+	}
+
+	// Valid change is swapped into existing assets
+	files[AssetManifestPath] = slime("1")
+	if err := am.Reload(AssetManifestPath); err != nil {
+		t.Fatal(err)
+	}
+	if anim, _ := asset.Animation("idle"); anim.FrameCount != 1 {
+		t.Errorf("Expected reloaded animation with 1 frame, got %d", anim.FrameCount)
+	}
+}
+
+type hotReloadTestWatcher struct {
+	err      error
+	reloaded []string
+}
+
+func (w *hotReloadTestWatcher) Reload(path string) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.reloaded = append(w.reloaded, path)
+	return nil
+}
+
+func TestHotReloaderContinuesAfterWatcherError(t *testing.T) {
+	r := NewHotReloader(t.TempDir())
+	broken := &hotReloadTestWatcher{err: fmt.Errorf("broken")}
+	second := &hotReloadTestWatcher{err: fmt.Errorf("also broken")}
+	valid := &hotReloadTestWatcher{}
+	r.Watch(broken)
+	r.Watch(valid)
+	r.Watch(second)
+	err := r.reload("manifest.json")
+	if err == nil || !strings.Contains(err.Error(), "broken") || !strings.Contains(err.Error(), "also broken") {
+		t.Errorf("Expected errors of all watchers, got %v", err)
+	}
+	if len(valid.reloaded) != 1 {
+		t.Error("Expected valid watcher to reload despite previous error")
+	}
+}
@@ -0,0 +1,274 @@
+package hud
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+
+	"github.com/ebitenui/ebitenui/image"
+	"github.com/ebitenui/ebitenui/widget"
+	"github.com/golang/freetype/truetype"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/lucb31/game-engine-go/engine/loot"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+type EquipmentHolder interface {
+	Bag() *loot.Bag
+	// Nil, if nothing is equipped
+	EquippedItem(loot.EquipmentSlot) *loot.ItemInstance
+	EquipFromBag(bagIdx int) error
+	UnequipToBag(slot loot.EquipmentSlot, bagIdx int) error
+	MoveInBag(from, to int) error
+}
+
+// Bag & equipment panels. Items are moved via drag & drop
+type EquipmentHud struct {
+	rootContainer *widget.Container
+	holder        EquipmentHolder
+	fontFace      font.Face
+
+	bagSlots       []*itemSlotWidget
+	equipmentSlots []*itemSlotWidget
+
+	// Visibility toggled by I key
+	visible bool
+	// Drops happen while rendering. They are applied during the next update
+	pendingDrop *itemSlotDrop
+}
+
+// Either a bag slot or an equipment slot
+type itemSlotRef struct {
+	bagIdx    int
+	equipment loot.EquipmentSlot
+}
+
+type itemSlotDrop struct {
+	from, to itemSlotRef
+}
+
+type itemSlotWidget struct {
+	ref       itemSlotRef
+	container *widget.Container
+	label     *widget.Text
+}
+
+const (
+	equipmentHudBagColumns = 5
+	equipmentHudSlotSize   = 64
+)
+
+var (
+	itemSlotColor          = color.NRGBA{66, 66, 66, 255}
+	itemSlotHighlightColor = color.NRGBA{100, 100, 255, 255}
+)
+
+func NewEquipmentHud(holder EquipmentHolder) (*EquipmentHud, error) {
+	if holder == nil {
+		return nil, fmt.Errorf("Cannot init equipment hud without equipment holder")
+	}
+	ttfFont, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing font: %s", err.Error())
+	}
+	hud := &EquipmentHud{holder: holder, fontFace: truetype.NewFace(ttfFont, &truetype.Options{Size: 12})}
+	hud.rootContainer = widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(image.NewNineSliceColor(color.NRGBA{0x13, 0x1a, 0x22, 0xbb})),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionHorizontal),
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(20)),
+			widget.RowLayoutOpts.Spacing(20),
+		)),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+				HorizontalPosition: widget.AnchorLayoutPositionStart,
+				VerticalPosition:   widget.AnchorLayoutPositionCenter,
+				Padding:            widget.Insets{Left: 8},
+			}),
+		),
+	)
+	hud.rootContainer.GetWidget().Visibility = widget.Visibility_Hide
+
+	// Equipment panel: One slot per equipment slot
+	equipmentPanel := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Spacing(6),
+		)),
+	)
+	for _, slot := range loot.EquipmentSlots {
+		w := hud.buildSlot(itemSlotRef{bagIdx: -1, equipment: slot})
+		hud.equipmentSlots = append(hud.equipmentSlots, w)
+		equipmentPanel.AddChild(w.container)
+	}
+	hud.rootContainer.AddChild(equipmentPanel)
+
+	// Bag panel
+	bagPanel := widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(equipmentHudBagColumns),
+			widget.GridLayoutOpts.Spacing(6, 6),
+		)),
+	)
+	for idx := range holder.Bag().Size() {
+		w := hud.buildSlot(itemSlotRef{bagIdx: idx})
+		hud.bagSlots = append(hud.bagSlots, w)
+		bagPanel.AddChild(w.container)
+	}
+	hud.rootContainer.AddChild(bagPanel)
+
+	return hud, nil
+}
+
+func (h *EquipmentHud) Update() {
+	// Toggle visibility with I
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		h.visible = !h.visible
+	}
+	if !h.visible {
+		h.rootContainer.GetWidget().Visibility = widget.Visibility_Hide
+		h.pendingDrop = nil
+		return
+	}
+	h.rootContainer.GetWidget().Visibility = widget.Visibility_Show
+
+	if h.pendingDrop != nil {
+		if err := h.applyDrop(*h.pendingDrop); err != nil {
+			log.Println("Could not move item", err.Error())
+		}
+		h.pendingDrop = nil
+	}
+	for _, w := range append(append([]*itemSlotWidget{}, h.equipmentSlots...), h.bagSlots...) {
+		w.label.Label = h.slotLabel(w.ref)
+	}
+}
+
+func (h *EquipmentHud) RootContainer() *widget.Container { return h.rootContainer }
+
+func (h *EquipmentHud) item(ref itemSlotRef) *loot.ItemInstance {
+	if ref.equipment != "" {
+		return h.holder.EquippedItem(ref.equipment)
+	}
+	return h.holder.Bag().Slot(ref.bagIdx)
+}
+
+func (h *EquipmentHud) slotLabel(ref itemSlotRef) string {
+	item := h.item(ref)
+	if item == nil {
+		if ref.equipment != "" {
+			return strings.ToUpper(string(ref.equipment[:1])) + string(ref.equipment[1:])
+		}
+		return ""
+	}
+	return item.String()
+}
+
+// Only equippable items can be dropped into matching equipment slots
+func (h *EquipmentHud) canDrop(drop itemSlotDrop) bool {
+	if drop.from == drop.to {
+		return false
+	}
+	source := h.item(drop.from)
+	if source == nil {
+		return false
+	}
+	switch {
+	case drop.to.equipment != "":
+		return drop.from.equipment == "" && source.Item.Slot == drop.to.equipment
+	case drop.from.equipment != "":
+		// Swapping with a bag item only works if it fits the equipment slot
+		target := h.item(drop.to)
+		return target == nil || target.Item.Slot == drop.from.equipment
+	}
+	return true
+}
+
+func (h *EquipmentHud) applyDrop(drop itemSlotDrop) error {
+	if !h.canDrop(drop) {
+		return fmt.Errorf("Cannot move item from %v to %v", drop.from, drop.to)
+	}
+	switch {
+	case drop.to.equipment != "":
+		return h.holder.EquipFromBag(drop.from.bagIdx)
+	case drop.from.equipment != "":
+		return h.holder.UnequipToBag(drop.from.equipment, drop.to.bagIdx)
+	}
+	return h.holder.MoveInBag(drop.from.bagIdx, drop.to.bagIdx)
+}
+
+func (h *EquipmentHud) buildSlot(ref itemSlotRef) *itemSlotWidget {
+	w := &itemSlotWidget{ref: ref}
+	w.container = widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(image.NewNineSliceColor(itemSlotColor)),
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout()),
+		widget.ContainerOpts.WidgetOpts(
+			widget.WidgetOpts.MinSize(equipmentHudSlotSize, equipmentHudSlotSize),
+			// Drag source
+			widget.WidgetOpts.EnableDragAndDrop(widget.NewDragAndDrop(
+				widget.DragAndDropOpts.ContentsCreater(&itemDragContents{hud: h, ref: ref}),
+				widget.DragAndDropOpts.MinDragStartDistance(5),
+			)),
+			// Drop target
+			widget.WidgetOpts.CanDrop(func(args *widget.DragAndDropDroppedEventArgs) bool {
+				from, ok := args.Data.(itemSlotRef)
+				return ok && h.canDrop(itemSlotDrop{from, ref})
+			}),
+			widget.WidgetOpts.Dropped(func(args *widget.DragAndDropDroppedEventArgs) {
+				h.pendingDrop = &itemSlotDrop{args.Data.(itemSlotRef), ref}
+			}),
+		),
+	)
+	w.label = widget.NewText(
+		widget.TextOpts.Text("", h.fontFace, color.White),
+		widget.TextOpts.MaxWidth(equipmentHudSlotSize-4),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionCenter),
+		widget.TextOpts.WidgetOpts(widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
+			HorizontalPosition: widget.AnchorLayoutPositionCenter,
+			VerticalPosition:   widget.AnchorLayoutPositionCenter,
+		})),
+	)
+	w.container.AddChild(w.label)
+	return w
+}
+
+// Creates the widget following the cursor while dragging an item
+type itemDragContents struct {
+	hud *EquipmentHud
+	ref itemSlotRef
+	// Highlighted drop target
+	target *widget.Container
+}
+
+func (d *itemDragContents) Create(widget.HasWidget) (*widget.Container, interface{}) {
+	item := d.hud.item(d.ref)
+	// Nothing to drag from empty slots
+	if item == nil {
+		return nil, nil
+	}
+	contents := widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(image.NewNineSliceColor(itemSlotHighlightColor)),
+		widget.ContainerOpts.Layout(widget.NewAnchorLayout(widget.AnchorLayoutOpts.Padding(widget.NewInsetsSimple(4)))),
+	)
+	contents.AddChild(widget.NewText(widget.TextOpts.Text(item.String(), d.hud.fontFace, color.White)))
+	return contents, d.ref
+}
+
+func (d *itemDragContents) Update(canDrop bool, target widget.HasWidget, _ interface{}) {
+	d.resetTarget()
+	if container, ok := target.(*widget.Container); ok && canDrop {
+		container.BackgroundImage = image.NewNineSliceColor(itemSlotHighlightColor)
+		d.target = container
+	}
+}
+
+func (d *itemDragContents) EndDrag(bool, widget.HasWidget, interface{}) { d.resetTarget() }
+
+func (d *itemDragContents) resetTarget() {
+	if d.target != nil {
+		d.target.BackgroundImage = image.NewNineSliceColor(itemSlotColor)
+		d.target = nil
+	}
+}
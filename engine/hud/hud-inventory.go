@@ -7,6 +7,7 @@ import (
 
 	"github.com/ebitenui/ebitenui/widget"
 	"github.com/golang/freetype/truetype"
+	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/lucb31/game-engine-go/engine/loot"
 	"golang.org/x/image/font/gofont/goregular"
 )
@@ -14,36 +15,52 @@ import (
 type InventoryHud struct {
 	rootContainer *widget.Container
 	inventory     loot.Inventory
-	goldLabel     *widget.Text
-	woodLabel     *widget.Text
+	// One label per registered resource
+	labels map[loot.ResourceId]*widget.Text
 }
 
-func NewInventoryHud(inventory loot.Inventory) (*InventoryHud, error) {
-	hud := &InventoryHud{inventory: inventory}
-	hud.rootContainer = hud.buildRootContainer()
-
-	hud.goldLabel = hud.buildLabel()
-	hud.rootContainer.AddChild(hud.goldLabel)
+// Loads resource icons by asset path
+type IconLoader func(path string) (*ebiten.Image, error)
 
-	hud.woodLabel = hud.buildLabel()
-	hud.rootContainer.AddChild(hud.woodLabel)
+// Icons are optional. Resources without icon show their name instead
+func NewInventoryHud(inventory loot.Inventory, icons IconLoader) (*InventoryHud, error) {
+	hud := &InventoryHud{inventory: inventory, labels: map[loot.ResourceId]*widget.Text{}}
+	hud.rootContainer = hud.buildRootContainer()
 
+	for _, kind := range inventory.Registry().Kinds() {
+		if kind.Icon != "" && icons != nil {
+			icon, err := icons(kind.Icon)
+			if err != nil {
+				return nil, fmt.Errorf("Could not load %s icon: %s", kind.Id, err.Error())
+			}
+			hud.rootContainer.AddChild(widget.NewGraphic(widget.GraphicOpts.Image(icon)))
+		}
+		hud.labels[kind.Id] = hud.buildLabel()
+		hud.rootContainer.AddChild(hud.labels[kind.Id])
+	}
 	return hud, nil
 }
 
 func (h *InventoryHud) Update() {
-	h.updateGold()
-	h.updateWood()
+	for _, kind := range h.inventory.Registry().Kinds() {
+		label := fmt.Sprintf("%d", h.inventory.Resource(kind.Id).Balance())
+		if kind.Icon == "" {
+			label = fmt.Sprintf("%s: %s", kind.Name, label)
+		}
+		if kind.Cap > 0 {
+			label = fmt.Sprintf("%s / %d", label, kind.Cap)
+		}
+		h.labels[kind.Id].Label = label
+	}
 }
 
 func (h *InventoryHud) RootContainer() *widget.Container { return h.rootContainer }
 
 func (h *InventoryHud) buildRootContainer() *widget.Container {
 	return widget.NewContainer(
-		widget.ContainerOpts.Layout(widget.NewGridLayout(
-			widget.GridLayoutOpts.Columns(2),
-			widget.GridLayoutOpts.Stretch([]bool{false, false}, []bool{false}),
-			widget.GridLayoutOpts.Spacing(10, 0),
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionHorizontal),
+			widget.RowLayoutOpts.Spacing(10),
 		)),
 		widget.ContainerOpts.WidgetOpts(
 			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
@@ -69,11 +86,3 @@ func (h *InventoryHud) buildLabel() *widget.Text {
 	)
 	return labelText
 }
-
-func (h *InventoryHud) updateGold() {
-	h.goldLabel.Label = fmt.Sprintf("Gold: %d", h.inventory.GoldManager().Balance())
-}
-
-func (h *InventoryHud) updateWood() {
-	h.woodLabel.Label = fmt.Sprintf("Wood: %d", h.inventory.WoodManager().Balance())
-}
@@ -18,6 +18,8 @@ import (
 type GameInfo interface {
 	CreepProgress() ProgressInfo
 	CastleProgress() ProgressInfo
+	// Health of the active boss. Hidden if max is 0
+	BossProgress() ProgressInfo
 	// Interface for speed slider to set game speed
 	SetSpeed(float64)
 	// Game over will be displayed if true
@@ -38,6 +40,8 @@ type GameHUD struct {
 	creepProgress     *widget.ProgressBar
 	creepLabel        *widget.Text
 	castleHealth      *widget.ProgressBar
+	bossHealth        *widget.ProgressBar
+	bossLabel         *widget.Text
 	speedSlider       *widget.Slider
 	gameOverContainer *widget.Container
 	gameOverScore     *widget.Text
@@ -66,6 +70,7 @@ func NewHUD(game GameInfo) (*GameHUD, error) {
 
 	hud.creepProgress, hud.creepLabel = initCreepProgressBar(rootContainer)
 	hud.castleHealth = initCastleHealthProgressBar(rootContainer)
+	hud.bossHealth, hud.bossLabel = initBossHealthProgressBar(rootContainer)
 	hud.speedSlider = hud.initGameSpeedSlider(rootContainer)
 	hud.gameOverContainer = hud.initGameOverContainer(rootContainer)
 
@@ -85,6 +90,7 @@ func (h *GameHUD) Update() {
 	h.ui.Update()
 	h.updateCreepProgress()
 	h.updateCastleHealth()
+	h.updateBossHealth()
 	h.updateGameOver()
 
 	// Draw submenus
@@ -133,6 +139,20 @@ func initCastleHealthProgressBar(root *widget.Container) *widget.ProgressBar {
 	return progress
 }
 
+// Below the creep progress. Hidden while there is no boss
+func initBossHealthProgressBar(root *widget.Container) (*widget.ProgressBar, *widget.Text) {
+	layout := widget.AnchorLayoutData{
+		HorizontalPosition: widget.AnchorLayoutPositionCenter,
+		VerticalPosition:   widget.AnchorLayoutPositionStart,
+		Padding:            widget.Insets{Top: 28},
+	}
+	bgColor := color.NRGBA{160, 0, 160, 255}
+	progress, label := progressBarWithLabel(root, "Boss", layout, bgColor)
+	progress.GetWidget().Visibility = widget.Visibility_Hide
+	label.GetWidget().Visibility = widget.Visibility_Hide
+	return progress, label
+}
+
 func (h *GameHUD) initGameSpeedSlider(root *widget.Container) *widget.Slider {
 	container := widget.NewContainer(
 		widget.ContainerOpts.Layout(widget.NewRowLayout()),
@@ -313,6 +333,23 @@ func (h *GameHUD) updateCastleHealth() {
 	h.castleHealth.SetCurrent(progress.Current)
 }
 
+func (h *GameHUD) updateBossHealth() {
+	progress := h.game.BossProgress()
+	visibility := widget.Visibility_Show
+	if progress.Max == 0 {
+		visibility = widget.Visibility_Hide
+	}
+	h.bossHealth.GetWidget().Visibility = visibility
+	h.bossLabel.GetWidget().Visibility = visibility
+	if progress.Max == 0 {
+		return
+	}
+	h.bossHealth.Min = progress.Min
+	h.bossHealth.Max = progress.Max
+	h.bossHealth.SetCurrent(progress.Current)
+	h.bossLabel.Label = progress.Label
+}
+
 func (h *GameHUD) updateGameOver() {
 	// Toggle visibility of game over container
 	if !h.game.GameOver() {
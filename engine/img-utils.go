@@ -1,6 +1,12 @@
 package engine
 
-import "github.com/hajimehoshi/ebiten/v2"
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
 
 func FlipHorizontal(source *ebiten.Image) *ebiten.Image {
 	result := ebiten.NewImage(source.Bounds().Dx(), source.Bounds().Dy())
@@ -21,3 +27,20 @@ func ScaleImg(im *ebiten.Image, scale float64) *ebiten.Image {
 	result.DrawImage(im, &op)
 	return result
 }
+
+// Multiplies every pixel with the tint. Alpha is preserved.
+// Decoded images are tinted in place to avoid copying large sprite sheets
+func TintImg(source image.Image, tint color.RGBA) *image.NRGBA {
+	res, ok := source.(*image.NRGBA)
+	if !ok {
+		bounds := source.Bounds()
+		res = image.NewNRGBA(bounds)
+		draw.Draw(res, bounds, source, bounds.Min, draw.Src)
+	}
+	for idx := 0; idx+3 < len(res.Pix); idx += 4 {
+		res.Pix[idx] = uint8(uint16(res.Pix[idx]) * uint16(tint.R) / 0xff)
+		res.Pix[idx+1] = uint8(uint16(res.Pix[idx+1]) * uint16(tint.G) / 0xff)
+		res.Pix[idx+2] = uint8(uint16(res.Pix[idx+2]) * uint16(tint.B) / 0xff)
+	}
+	return res
+}
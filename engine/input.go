@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Player actions that can be triggered via input
+type InputAction uint8
+
+const (
+	InputMoveNorth InputAction = 1 << iota
+	InputMoveSouth
+	InputMoveEast
+	InputMoveWest
+	InputInteract
+	InputDash
+)
+
+// Bitmask of all actions pressed during a single tick
+type InputFrame uint8
+
+func (f InputFrame) Pressed(a InputAction) bool { return f&InputFrame(a) != 0 }
+
+// Source of player inputs. Needs to be advanced exactly once per tick
+type InputSource interface {
+	Update()
+	// Frame of the current tick
+	Frame() InputFrame
+	Pressed(InputAction) bool
+	// True, if action was not pressed during previous tick
+	JustPressed(InputAction) bool
+	// True, if action was pressed during previous tick
+	JustReleased(InputAction) bool
+}
+
+// Derives just pressed / just released state from current & previous frame
+type BaseInputSource struct {
+	current, previous InputFrame
+}
+
+func (s *BaseInputSource) Frame() InputFrame          { return s.current }
+func (s *BaseInputSource) Pressed(a InputAction) bool { return s.current.Pressed(a) }
+func (s *BaseInputSource) JustPressed(a InputAction) bool {
+	return s.current.Pressed(a) && !s.previous.Pressed(a)
+}
+func (s *BaseInputSource) JustReleased(a InputAction) bool {
+	return !s.current.Pressed(a) && s.previous.Pressed(a)
+}
+
+// Advance to the next frame
+func (s *BaseInputSource) push(frame InputFrame) {
+	s.previous = s.current
+	s.current = frame
+}
+
+// Reads inputs from the keyboard
+type KeyboardInputSource struct {
+	*BaseInputSource
+}
+
+var keyboardBindings = map[InputAction]ebiten.Key{
+	InputMoveNorth: ebiten.KeyW,
+	InputMoveSouth: ebiten.KeyS,
+	InputMoveEast:  ebiten.KeyD,
+	InputMoveWest:  ebiten.KeyA,
+	InputInteract:  ebiten.KeyE,
+	InputDash:      ebiten.KeySpace,
+}
+
+func NewKeyboardInputSource() *KeyboardInputSource {
+	return &KeyboardInputSource{BaseInputSource: &BaseInputSource{}}
+}
+
+func (s *KeyboardInputSource) Update() {
+	frame := InputFrame(0)
+	for action, key := range keyboardBindings {
+		if ebiten.IsKeyPressed(key) {
+			frame |= InputFrame(action)
+		}
+	}
+	s.push(frame)
+}
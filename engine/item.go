@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"image/color"
+
 	"github.com/jakecoffman/cp"
 	"github.com/lucb31/game-engine-go/engine/loot"
 )
@@ -11,6 +13,30 @@ type ItemEntity struct {
 	shape *cp.Shape
 	asset *CharacterAsset
 	loot  loot.LootTable
+
+	// Outline drawn around the sprite
+	rarity     color.Color
+	autoPickup bool
+	// Ingame time of the drop. Lifetime 0 = never despawns
+	droppedAt float64
+	lifetime  float64
+}
+
+// Behaviour of loot dropped into the world
+type LootDropOpts struct {
+	// Max. impulse scattering drops around the drop position
+	ScatterImpulse float64
+	// Velocity loss per second of scattered drops. 0.1 = 90% lost
+	ScatterDamping float64
+	// Drops within this radius are pulled towards the player. 0 disables magnetism
+	MagnetRadius float64
+	MagnetSpeed  float64
+	// Seconds until drops despawn. 0 = never
+	Lifetime float64
+}
+
+func DefaultLootDropOpts() LootDropOpts {
+	return LootDropOpts{ScatterImpulse: 80, ScatterDamping: 0.02, MagnetRadius: 120, MagnetSpeed: 250, Lifetime: 60}
 }
 
 var itemCollisionFilter = cp.NewShapeFilter(cp.NO_GROUP, ItemCategory, PlayerCategory)
@@ -20,9 +46,10 @@ func NewItemEntity(pos cp.Vector) (*ItemEntity, error) {
 	if err != nil {
 		return nil, err
 	}
-	item := &ItemEntity{BaseEntityImpl: base}
+	item := &ItemEntity{BaseEntityImpl: base, rarity: loot.RarityCommon.Color()}
 
-	body := cp.NewKinematicBody()
+	// Dynamic to be scattered & pulled. Collisions are disabled by the item collision handler
+	body := cp.NewBody(1, cp.INFINITY)
 	body.SetPosition(pos)
 	body.UserData = item
 
@@ -41,23 +68,55 @@ func (i *ItemEntity) Draw(t RenderingTarget) error {
 			return err
 		}
 	} else {
-		if err := DrawRectBoundingBox(t, i.shape.BB()); err != nil {
-			return err
-		}
+		bb := i.shape.BB()
+		t.FillRect(cp.Vector{X: bb.L, Y: bb.B}, cp.Vector{X: bb.R, Y: bb.T}, i.rarity, false)
 	}
+	t.StrokeCircle(i.shape.Body().Position(), 12, 2, i.rarity, true)
 	return nil
 }
 
 func (i *ItemEntity) SetLootTable(loot loot.LootTable) { i.loot = loot }
 func (i *ItemEntity) SetAsset(asset *CharacterAsset) error {
 	i.asset = asset
-	if err := i.asset.AnimationController().Loop("idle"); err != nil {
-		return err
+	return i.asset.AnimationController().Loop("idle")
+}
+
+// Applies sprite independent parts of the appearance
+func (i *ItemEntity) SetAppearance(appearance loot.DropAppearance, autoPickup bool) {
+	i.rarity = appearance.Rarity.Color()
+	i.autoPickup = autoPickup
+}
+
+func (i *ItemEntity) SetLifetime(droppedAt, lifetime float64) {
+	i.droppedAt, i.lifetime = droppedAt, lifetime
+}
+
+func (i *ItemEntity) Expired(now float64) bool {
+	return i.lifetime > 0 && now-i.droppedAt > i.lifetime
+}
+
+func (i *ItemEntity) LootTable() loot.LootTable { return i.loot }
+func (i *ItemEntity) AutoPickup() bool          { return i.autoPickup }
+
+func (i *ItemEntity) Snapshot() EntitySnapshot {
+	s := EntitySnapshot{
+		Kind:     EntityKindItem,
+		Position: i.shape.Body().Position(),
+		Velocity: i.shape.Body().Velocity(),
+		Loot:     NewLootSnapshot(i.loot),
+		Timers:   map[string]TimerSnapshot{"despawn": {StartedAt: i.droppedAt, Timeout: i.lifetime}},
 	}
-	if err := i.asset.AnimationController().Play("spawn"); err != nil {
-		return err
+	if i.asset != nil {
+		s.Asset = i.asset.Name()
 	}
+	return s
+}
+
+func (i *ItemEntity) Restore(s EntitySnapshot) error {
+	i.shape.Body().SetPosition(s.Position)
+	i.shape.Body().SetVelocityVector(s.Velocity)
+	i.loot = s.Loot.LootTable()
+	i.droppedAt, i.lifetime = s.Timers["despawn"].StartedAt, s.Timers["despawn"].Timeout
 	return nil
 }
-func (i *ItemEntity) LootTable() loot.LootTable { return i.loot }
-func (i *ItemEntity) Shape() *cp.Shape          { return i.shape }
+func (i *ItemEntity) Shape() *cp.Shape { return i.shape }
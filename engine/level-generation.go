@@ -1,5 +1,11 @@
 package engine
 
+import (
+	"math/rand/v2"
+
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
 type GeneratorResult struct {
 	WorldMap WorldMap
 	Objects  []GameEntity
@@ -7,8 +13,12 @@ type GeneratorResult struct {
 
 type WorldGenerator interface {
 	WorldDimensions() (int64, int64)
+	// Resource kinds of the generated world. Needs to return a new registry on every call
+	Resources() (*loot.ResourceRegistry, error)
 	// Internal interface every generator has to implement
-	Generate(AssetManager) (*GeneratorResult, error)
+	// All random decisions need to be drawn from the provided generator.
+	// Loot tables are used to declare drops of generated entities
+	Generate(AssetManager, *loot.LootTableDB, *rand.Rand) (*GeneratorResult, error)
 }
 
 type BaseLevelGenerator struct {
@@ -24,3 +34,8 @@ func (g *BaseLevelGenerator) WorldDimensions() (int64, int64) { return g.worldWi
 func (g *BaseLevelGenerator) ScreenDimensions() (int, int)    { return g.screenWidth, g.screenHeight }
 func (g *BaseLevelGenerator) SetWorldDimensions(w, h int64)   { g.worldWidth, g.worldHeight = w, h }
 func (g *BaseLevelGenerator) SetScreenDimension(w, h int)     { g.screenWidth, g.screenHeight = w, h }
+
+// Gold & wood
+func (g *BaseLevelGenerator) Resources() (*loot.ResourceRegistry, error) {
+	return loot.DefaultResourceRegistry(), nil
+}
@@ -0,0 +1,126 @@
+package loot
+
+import "fmt"
+
+// Fixed number of item slots. Empty slots are nil
+type Bag struct {
+	slots []*ItemInstance
+}
+
+func NewBag(size int) (*Bag, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("Invalid bag size %d", size)
+	}
+	return &Bag{slots: make([]*ItemInstance, size)}, nil
+}
+
+func (b *Bag) Size() int { return len(b.slots) }
+
+// Nil, if the slot is empty or out of range
+func (b *Bag) Slot(idx int) *ItemInstance {
+	if idx < 0 || idx >= len(b.slots) {
+		return nil
+	}
+	return b.slots[idx]
+}
+
+// Fills existing stacks first, remainder goes into the first free slot.
+// Nothing is added if the whole instance does not fit
+func (b *Bag) Add(instance *ItemInstance) error {
+	if !b.Fits(instance) {
+		return fmt.Errorf("Bag is full. Could not add %s", instance)
+	}
+	b.add(instance)
+	return nil
+}
+
+// True, if all instances fit into free slots & the free space of existing stacks
+func (b *Bag) Fits(instances ...*ItemInstance) bool {
+	// Simulate on copies of the stack counts
+	sim := &Bag{slots: make([]*ItemInstance, len(b.slots))}
+	for idx, slot := range b.slots {
+		if slot != nil {
+			copied := *slot
+			sim.slots[idx] = &copied
+		}
+	}
+	for _, instance := range instances {
+		copied := *instance
+		if !sim.add(&copied) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns false, if there was no space left for the remainder
+func (b *Bag) add(instance *ItemInstance) bool {
+	for _, slot := range b.slots {
+		if slot == nil || !slot.Stackable(instance) {
+			continue
+		}
+		moved := min(instance.Count, slot.Item.StackSize-slot.Count)
+		slot.Count += moved
+		instance.Count -= moved
+		if instance.Count == 0 {
+			return true
+		}
+	}
+	idx := b.FreeSlot()
+	if idx < 0 {
+		return false
+	}
+	b.slots[idx] = instance
+	return true
+}
+
+// Removes & returns the item in the slot
+func (b *Bag) Take(idx int) *ItemInstance {
+	instance := b.Slot(idx)
+	if instance != nil {
+		b.slots[idx] = nil
+	}
+	return instance
+}
+
+// Puts the instance into the slot. Returns the previous item that has been replaced, if any
+func (b *Bag) Put(idx int, instance *ItemInstance) (*ItemInstance, error) {
+	if idx < 0 || idx >= len(b.slots) {
+		return nil, fmt.Errorf("Invalid bag slot %d", idx)
+	}
+	previous := b.slots[idx]
+	b.slots[idx] = instance
+	return previous, nil
+}
+
+// Swaps two slots or merges stacks of the same item
+func (b *Bag) Move(from, to int) error {
+	if from < 0 || from >= len(b.slots) || to < 0 || to >= len(b.slots) {
+		return fmt.Errorf("Invalid bag move from %d to %d", from, to)
+	}
+	if from == to || b.slots[from] == nil {
+		return nil
+	}
+	source, target := b.slots[from], b.slots[to]
+	if target != nil && target.Stackable(source) {
+		moved := min(source.Count, target.Item.StackSize-target.Count)
+		target.Count += moved
+		source.Count -= moved
+		if source.Count == 0 {
+			b.slots[from] = nil
+		}
+		return nil
+	}
+	b.slots[from], b.slots[to] = target, source
+	return nil
+}
+
+// Index of the first empty slot. -1 if the bag is full
+func (b *Bag) FreeSlot() int {
+	for idx, slot := range b.slots {
+		if slot == nil {
+			return idx
+		}
+	}
+	return -1
+}
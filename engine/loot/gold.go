@@ -7,18 +7,28 @@ type ResourceManager interface {
 	Balance() int64
 	CanAfford(int64) bool
 	Revenue() int64
+	// Overwrite balance & revenue, e.g. when loading a saved game
+	Restore(balance, revenue int64)
 }
 
 type InMemoryResourceManager struct {
 	balance int64
 	revenue int64
+	// 0 = uncapped
+	cap int64
 }
 
 func NewInMemoryResourceManager() (*InMemoryResourceManager, error) {
 	return &InMemoryResourceManager{}, nil
 }
 
+// Balance will never exceed the cap. Excess is lost
+func NewCappedResourceManager(cap int64) (*InMemoryResourceManager, error) {
+	return &InMemoryResourceManager{cap: cap}, nil
+}
+
 func (g *InMemoryResourceManager) Add(amount int64) (int64, error) {
+	amount = g.capped(amount)
 	g.balance += amount
 	if amount > 0 {
 		g.revenue += amount
@@ -27,7 +37,7 @@ func (g *InMemoryResourceManager) Add(amount int64) (int64, error) {
 }
 
 func (g *InMemoryResourceManager) Refund(amount int64) (int64, error) {
-	g.balance += amount
+	g.balance += g.capped(amount)
 	return g.balance, nil
 }
 
@@ -45,3 +55,15 @@ func (g *InMemoryResourceManager) CanAfford(amount int64) bool {
 }
 
 func (g *InMemoryResourceManager) Revenue() int64 { return g.revenue }
+func (g *InMemoryResourceManager) Restore(balance, revenue int64) {
+	g.balance = balance
+	g.revenue = revenue
+}
+
+// Part of amount that fits below the cap
+func (g *InMemoryResourceManager) capped(amount int64) int64 {
+	if g.cap > 0 && g.balance+amount > g.cap {
+		return max(0, g.cap-g.balance)
+	}
+	return amount
+}
@@ -6,57 +6,77 @@ import (
 )
 
 type Inventory interface {
-	GoldManager() ResourceManager
-	WoodManager() ResourceManager
-	AddLoot(LootTable) error
+	// Nil, if the resource is not registered
+	Resource(ResourceId) ResourceManager
+	Registry() *ResourceRegistry
+	// Items held in addition to resources
+	Bag() *Bag
+	// Adds the result of an evaluated loot table
+	AddLoot([]LootTableItem) error
 	Buy(*GameItem) error
 	CanAfford(*GameItem) (bool, error)
 }
 
 type InMemoryInventory struct {
-	goldManager ResourceManager
-	woodManager ResourceManager
+	registry  *ResourceRegistry
+	resources map[ResourceId]ResourceManager
+	bag       *Bag
 }
 
-func NewInventory() (*InMemoryInventory, error) {
-	goldManager, err := NewInMemoryResourceManager()
-	if err != nil {
-		return nil, err
-	}
-	// Add starting gold
-	goldManager.Add(5000)
+const defaultBagSize = 20
 
-	woodManager, err := NewInMemoryResourceManager()
+func NewInventory(registry *ResourceRegistry) (*InMemoryInventory, error) {
+	if registry == nil {
+		return nil, fmt.Errorf("Cannot init inventory without resource registry")
+	}
+	bag, err := NewBag(defaultBagSize)
 	if err != nil {
 		return nil, err
 	}
-
-	inv := &InMemoryInventory{goldManager: goldManager, woodManager: woodManager}
+	inv := &InMemoryInventory{registry: registry, resources: map[ResourceId]ResourceManager{}, bag: bag}
+	for _, kind := range registry.Kinds() {
+		manager, err := NewCappedResourceManager(kind.Cap)
+		if err != nil {
+			return nil, err
+		}
+		// Add starting balance
+		manager.Add(kind.Initial)
+		inv.resources[kind.Id] = manager
+	}
 	return inv, nil
 }
 
-func (i *InMemoryInventory) AddLoot(loot LootTable) error {
-	// Evaluate loot table
-	lootResult := loot.Result()
-
-	// Add loot table results to inventory
+// All or nothing: Loot is validated & checked against the bag capacity before adding anything
+func (i *InMemoryInventory) AddLoot(lootResult []LootTableItem) error {
+	instances := []*ItemInstance{}
 	for _, lootItem := range lootResult {
-		log.Println("processing loot item", lootItem)
-		switch v := lootItem.(type) {
-		case *GoldItem:
-			_, err := i.goldManager.Add(v.Value())
-			if err != nil {
-				return err
-			}
-		case *WoodItem:
-			_, err := i.woodManager.Add(v.Value())
-			if err != nil {
-				return err
+		switch item := lootItem.(type) {
+		case *ItemInstance:
+			instances = append(instances, item)
+		case *ResourceItem:
+			if i.Resource(item.Resource) == nil {
+				return fmt.Errorf("Unknown resource %s", item.Resource)
 			}
 		default:
 			return fmt.Errorf("Dont know how to handle loot item: %v", lootItem)
 		}
 	}
+	if !i.bag.Fits(instances...) {
+		return fmt.Errorf("Bag is full. Could not add %v", instances)
+	}
+
+	// Add loot table results to inventory
+	for _, lootItem := range lootResult {
+		log.Println("processing loot item", lootItem)
+		if instance, ok := lootItem.(*ItemInstance); ok {
+			i.bag.add(instance)
+			continue
+		}
+		resource := lootItem.(*ResourceItem)
+		if _, err := i.Resource(resource.Resource).Add(resource.Value()); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -67,26 +87,30 @@ func (i *InMemoryInventory) Buy(item *GameItem) error {
 		return fmt.Errorf("Cannot afford item %v: %s", item, err.Error())
 	}
 	// Update resources
-	_, err = i.GoldManager().Remove(item.GoldPrice)
-	if err != nil {
-		return fmt.Errorf("Error removing item gold cost: %s", err.Error())
-	}
-	_, err = i.WoodManager().Remove(item.WoodPrice)
-	if err != nil {
-		return fmt.Errorf("Error removing item wood cost: %s", err.Error())
+	for _, kind := range i.registry.Kinds() {
+		if item.Price[kind.Id] == 0 {
+			continue
+		}
+		if _, err := i.resources[kind.Id].Remove(item.Price[kind.Id]); err != nil {
+			return fmt.Errorf("Error removing item %s cost: %s", kind.Id, err.Error())
+		}
 	}
 	return nil
 }
 
 func (i *InMemoryInventory) CanAfford(item *GameItem) (bool, error) {
-	if !i.goldManager.CanAfford(item.GoldPrice) {
-		return false, fmt.Errorf("Insufficient gold funds")
-	}
-	if !i.woodManager.CanAfford(item.WoodPrice) {
-		return false, fmt.Errorf("Insufficient wood funds")
+	for id, price := range item.Price {
+		manager := i.Resource(id)
+		if manager == nil {
+			return false, fmt.Errorf("Unknown resource %s", id)
+		}
+		if !manager.CanAfford(price) {
+			return false, fmt.Errorf("Insufficient %s funds", id)
+		}
 	}
 	return true, nil
 }
 
-func (i *InMemoryInventory) GoldManager() ResourceManager { return i.goldManager }
-func (i *InMemoryInventory) WoodManager() ResourceManager { return i.woodManager }
+func (i *InMemoryInventory) Resource(id ResourceId) ResourceManager { return i.resources[id] }
+func (i *InMemoryInventory) Registry() *ResourceRegistry            { return i.registry }
+func (i *InMemoryInventory) Bag() *Bag                              { return i.bag }
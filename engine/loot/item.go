@@ -1,26 +1,158 @@
 package loot
 
-type ItemEffectId = int64
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+type EquipmentSlot string
 
 const (
-	ItemEffectAddMaxHealth ItemEffectId = iota
-	ItemEffectAddMovementSpeed
-	ItemEffectAddArmor
-	ItemEffectAddPower
-	ItemEffectAddAtkSpeed
-	ItemEffectAddCastleProjectile
+	EquipmentWeapon  EquipmentSlot = "weapon"
+	EquipmentArmor   EquipmentSlot = "armor"
+	EquipmentTrinket EquipmentSlot = "trinket"
 )
 
+// All equipment slots in display order
+var EquipmentSlots = []EquipmentSlot{EquipmentWeapon, EquipmentArmor, EquipmentTrinket}
+
 // Item definition stored in ItemDB, not copy / instance of one particular item
 type GameItem struct {
+	Id string
+
 	// Store
-	GoldPrice   int64
-	WoodPrice   int64
+	Price       map[ResourceId]int64
 	Description string
 
-	// Effect
-	ItemEffectId
-
-	// Inventory
+	// Inventory. Items with a stack size above 1 share a bag slot
 	StackSize int64
+
+	// Equipment. Empty slot = cannot be equipped
+	Slot    EquipmentSlot
+	Affixes []AffixDefinition
+
+	// World drops
+	DropAppearance
+}
+
+// Stat bonus rolled within [Min, Max] when an instance is created.
+// Stat & op use the names of the engine stat modifiers, e.g. "power" & "add"
+type AffixDefinition struct {
+	Stat string
+	Op   string
+	Min  float64
+	Max  float64
+}
+
+type ItemAffix struct {
+	Stat  string
+	Op    string
+	Value float64
+}
+
+// Unique per ItemDB. 0 is never assigned
+type ItemInstanceId int64
+
+// One particular item held by an entity
+type ItemInstance struct {
+	Id      ItemInstanceId
+	Item    *GameItem
+	Count   int64
+	Affixes []ItemAffix
+}
+
+func (i *ItemInstance) Always() bool         { return true }
+func (i *ItemInstance) Enabled() bool        { return true }
+func (i *ItemInstance) Probability() float64 { return 1.0 }
+func (i *ItemInstance) Unique() bool         { return false }
+
+// Instances can only be merged if they are interchangeable
+func (i *ItemInstance) Stackable(other *ItemInstance) bool {
+	return i.Item == other.Item && i.Item.StackSize > 1 && len(i.Affixes) == 0 && len(other.Affixes) == 0
+}
+
+func (i *ItemInstance) String() string {
+	if i.Count > 1 {
+		return fmt.Sprintf("%s x%d", i.Item.Description, i.Count)
+	}
+	return i.Item.Description
+}
+
+// Checks affixes of registered items, e.g. against the stats known to the engine
+type AffixValidator func(AffixDefinition) error
+
+// Registry of item definitions. Creates instances with unique ids
+type ItemDB struct {
+	items  map[string]*GameItem
+	nextId ItemInstanceId
+	// Affixes are not checked if nil
+	validateAffix AffixValidator
+}
+
+func NewItemDB(items ...*GameItem) (*ItemDB, error) {
+	db := &ItemDB{items: map[string]*GameItem{}}
+	for _, item := range items {
+		if err := db.Register(item); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func (db *ItemDB) Register(item *GameItem) error {
+	if item.Id == "" {
+		return fmt.Errorf("Cannot register item without id: %s", item.Description)
+	}
+	if _, ok := db.items[item.Id]; ok {
+		return fmt.Errorf("Item %s already registered", item.Id)
+	}
+	if db.validateAffix != nil {
+		for _, affix := range item.Affixes {
+			if err := db.validateAffix(affix); err != nil {
+				return fmt.Errorf("Invalid affix of item %s: %s", item.Id, err.Error())
+			}
+		}
+	}
+	db.items[item.Id] = item
+	return nil
+}
+
+// Applies to items registered afterwards
+func (db *ItemDB) SetAffixValidator(v AffixValidator) { db.validateAffix = v }
+
+func (db *ItemDB) Item(id string) *GameItem { return db.items[id] }
+
+// Creates a new instance & rolls its affixes
+func (db *ItemDB) NewInstance(id string, count int64, rng *rand.Rand) (*ItemInstance, error) {
+	item, ok := db.items[id]
+	if !ok {
+		return nil, fmt.Errorf("Unknown item %s", id)
+	}
+	if count < 1 {
+		return nil, fmt.Errorf("Invalid item count %d", count)
+	}
+	if count > max(1, item.StackSize) {
+		return nil, fmt.Errorf("Item count %d exceeds stack size of %s", count, id)
+	}
+	instance := &ItemInstance{Item: item, Count: count}
+	for _, affix := range item.Affixes {
+		value := affix.Min
+		if affix.Max > affix.Min {
+			value += rng.Float64() * (affix.Max - affix.Min)
+		}
+		instance.Affixes = append(instance.Affixes, ItemAffix{Stat: affix.Stat, Op: affix.Op, Value: value})
+	}
+	db.nextId++
+	instance.Id = db.nextId
+	return instance, nil
+}
+
+// Recreates a persisted instance. Keeps new ids unique
+func (db *ItemDB) RestoreInstance(id ItemInstanceId, itemId string, count int64, affixes []ItemAffix) (*ItemInstance, error) {
+	item, ok := db.items[itemId]
+	if !ok {
+		return nil, fmt.Errorf("Unknown item %s", itemId)
+	}
+	db.nextId = max(db.nextId, id)
+	return &ItemInstance{Id: id, Item: item, Count: count, Affixes: affixes}, nil
 }
@@ -0,0 +1,237 @@
+package loot
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+const LootDefinitionsPath = "loot.json"
+
+// Item definitions, resource drop appearances & named loot tables, e.g. drops of npc types & trees
+type LootDefinitions struct {
+	Items []*GameItem `json:"items"`
+	// Resources need to be registered already
+	Resources map[ResourceId]DropAppearance  `json:"resources"`
+	Tables    map[string]LootEntryDefinition `json:"tables"`
+}
+
+// Either a table (entries), a reference to another table, a resource, an item or nothing
+type LootEntryDefinition struct {
+	Weight   float64 `json:"weight,omitempty"`
+	Always   bool    `json:"always,omitempty"`
+	Unique   bool    `json:"unique,omitempty"`
+	Disabled bool    `json:"disabled,omitempty"`
+
+	Entries  []LootEntryDefinition `json:"entries,omitempty"`
+	Table    string                `json:"table,omitempty"`
+	Resource ResourceId            `json:"resource,omitempty"`
+	Item     string                `json:"item,omitempty"`
+
+	// Resource amount, item count or number of picks of a table. Defaults to 1
+	Min int64 `json:"min,omitempty"`
+	Max int64 `json:"max,omitempty"`
+}
+
+// Named loot tables
+type LootTableDB struct {
+	tables    map[string]*WeightedLootTable
+	resources *ResourceRegistry
+	items     *ItemDB
+}
+
+// Registers the item definitions in the db & builds all tables
+func ParseLootDefinitions(data []byte, resources *ResourceRegistry, items *ItemDB) (*LootTableDB, error) {
+	defs := LootDefinitions{}
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("Could not parse loot definitions: %s", err.Error())
+	}
+	return NewLootTableDB(defs, resources, items)
+}
+
+func NewLootTableDB(defs LootDefinitions, resources *ResourceRegistry, items *ItemDB) (*LootTableDB, error) {
+	for _, item := range defs.Items {
+		if err := items.Register(item); err != nil {
+			return nil, err
+		}
+	}
+	// Sorted to report errors deterministically
+	resourceIds := make([]ResourceId, 0, len(defs.Resources))
+	for id := range defs.Resources {
+		resourceIds = append(resourceIds, id)
+	}
+	slices.Sort(resourceIds)
+	for _, id := range resourceIds {
+		if err := resources.SetDropAppearance(id, defs.Resources[id]); err != nil {
+			return nil, err
+		}
+	}
+	db := &LootTableDB{tables: map[string]*WeightedLootTable{}, resources: resources, items: items}
+	b := &lootTableBuilder{defs: defs, resources: resources, items: items, db: db}
+	// Sorted to report errors deterministically
+	ids := make([]string, 0, len(defs.Tables))
+	for id := range defs.Tables {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		if _, err := b.table(id, nil); err != nil {
+			return nil, err
+		}
+	}
+	return b.db, nil
+}
+
+func (t *WeightedLootTable) wrappedTable() (*WeightedLootTable, bool) {
+	if len(t.contents) != 1 {
+		return nil, false
+	}
+	named, ok := t.contents[0].(*WeightedLootTable)
+	return named, ok && named.Id != ""
+}
+
+func (db *LootTableDB) Table(id string) (*WeightedLootTable, error) {
+	table, ok := db.tables[id]
+	if !ok {
+		return nil, fmt.Errorf("Unknown loot table %s", id)
+	}
+	return table, nil
+}
+
+// Builds an unnamed table. References need to point to tables of this db
+func (db *LootTableDB) NewTable(def LootEntryDefinition) (*WeightedLootTable, error) {
+	if len(def.Entries) == 0 {
+		return nil, fmt.Errorf("Loot table has no entries")
+	}
+	b := &lootTableBuilder{resources: db.resources, items: db.items, db: db}
+	entry, err := b.entry(def, nil)
+	if err != nil {
+		return nil, err
+	}
+	return entry.(*WeightedLootTable), nil
+}
+
+// Inverse of LootTableDB.NewTable. Used to persist tables built in code.
+// Named tables are referenced by id. Direct references are restored wrapped like
+// references in loot.json, so they pick the same loot, but draw one more random number.
+// Minimums below 1 cannot be expressed
+func NewLootEntryDefinition(item LootTableItem) (LootEntryDefinition, error) {
+	var def LootEntryDefinition
+	var entry *LootEntry
+	switch v := item.(type) {
+	case *WeightedLootTable:
+		entry = &v.LootEntry
+		if v.Id != "" {
+			def.Table = v.Id
+			break
+		}
+		if v.CountMin < 1 {
+			return def, fmt.Errorf("Cannot persist loot table with count %d", v.CountMin)
+		}
+		def.Min, def.Max = v.CountMin, v.CountMax
+		// Wrapped reference as built from loot.json
+		if named, ok := v.wrappedTable(); ok {
+			def.Table = named.Id
+			break
+		}
+		for _, child := range v.contents {
+			childDef, err := NewLootEntryDefinition(child)
+			if err != nil {
+				return def, err
+			}
+			def.Entries = append(def.Entries, childDef)
+		}
+	case *ResourceRoll:
+		entry = &v.LootEntry
+		def.Resource, def.Min, def.Max = v.Resource, v.Min, v.Max
+		if v.Min < 1 {
+			return def, fmt.Errorf("Cannot persist %s roll with minimum %d", v.Resource, v.Min)
+		}
+	case *ItemRoll:
+		entry = &v.LootEntry
+		def.Item, def.Min, def.Max = v.Item, v.Min, v.Max
+		if v.Min < 1 {
+			return def, fmt.Errorf("Cannot persist %s roll with minimum %d", v.Item, v.Min)
+		}
+	case *NothingRoll:
+		entry = &v.LootEntry
+	default:
+		return def, fmt.Errorf("Cannot persist loot entry %v", item)
+	}
+	def.Weight, def.Always, def.Unique, def.Disabled = entry.weight, entry.always, entry.unique, entry.disabled
+	return def, nil
+}
+
+type lootTableBuilder struct {
+	defs      LootDefinitions
+	resources *ResourceRegistry
+	items     *ItemDB
+	db        *LootTableDB
+}
+
+// Named tables are built once & shared by all references
+func (b *lootTableBuilder) table(id string, visiting []string) (*WeightedLootTable, error) {
+	if table, ok := b.db.tables[id]; ok {
+		return table, nil
+	}
+	if slices.Contains(visiting, id) {
+		return nil, fmt.Errorf("Circular loot table reference %s", id)
+	}
+	def, ok := b.defs.Tables[id]
+	if !ok {
+		return nil, fmt.Errorf("Unknown loot table %s", id)
+	}
+	if len(def.Entries) == 0 {
+		return nil, fmt.Errorf("Loot table %s has no entries", id)
+	}
+	entry, err := b.entry(def, append(visiting, id))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid loot table %s: %s", id, err.Error())
+	}
+	table := entry.(*WeightedLootTable)
+	table.Id = id
+	// Weight & flags of named tables are set by the entries referencing them
+	table.LootEntry = NewLootEntry(1, false, false)
+	b.db.tables[id] = table
+	return table, nil
+}
+
+func (b *lootTableBuilder) entry(def LootEntryDefinition, visiting []string) (LootTableItem, error) {
+	base := LootEntry{weight: def.Weight, always: def.Always, unique: def.Unique, disabled: def.Disabled}
+	// Single value unless a range is given
+	minimum := max(def.Min, 1)
+	maximum := max(def.Max, minimum)
+	if def.Max != 0 && def.Max < def.Min {
+		return nil, fmt.Errorf("Max %d below min %d", def.Max, def.Min)
+	}
+	switch {
+	case len(def.Entries) > 0:
+		table := &WeightedLootTable{LootEntry: base, CountMin: minimum, CountMax: maximum}
+		for _, child := range def.Entries {
+			item, err := b.entry(child, visiting)
+			if err != nil {
+				return nil, err
+			}
+			table.Add(item)
+		}
+		return table, nil
+	case def.Table != "":
+		named, err := b.table(def.Table, visiting)
+		if err != nil {
+			return nil, err
+		}
+		// Wrapped to apply the settings of this entry. Min & max set how often the table is evaluated
+		return &WeightedLootTable{LootEntry: base, contents: []LootTableItem{named}, CountMin: minimum, CountMax: maximum}, nil
+	case def.Resource != "":
+		if _, ok := b.resources.Kind(def.Resource); !ok {
+			return nil, fmt.Errorf("Unknown resource %s", def.Resource)
+		}
+		return &ResourceRoll{LootEntry: base, Resource: def.Resource, Min: minimum, Max: maximum}, nil
+	case def.Item != "":
+		if b.items.Item(def.Item) == nil {
+			return nil, fmt.Errorf("Unknown item %s", def.Item)
+		}
+		return &ItemRoll{LootEntry: base, db: b.items, Item: def.Item, Min: minimum, Max: maximum}, nil
+	}
+	return &NothingRoll{LootEntry: base}, nil
+}
@@ -1,65 +1,63 @@
 package loot
 
-type ResourceItem struct {
-	Amount int64
-}
+import (
+	"math/rand/v2"
+	"slices"
+)
 
-type GoldItem struct {
-	*ResourceItem
-}
-
-func NewGoldItem(amount int64) *GoldItem {
-	resource := &ResourceItem{amount}
-	return &GoldItem{ResourceItem: resource}
-}
-
-type WoodItem struct {
-	*ResourceItem
+type ResourceItem struct {
+	Resource ResourceId
+	Amount   int64
 }
 
-func NewWoodItem(amount int64) *WoodItem {
-	resource := &ResourceItem{amount}
-	return &WoodItem{ResourceItem: resource}
+func NewResourceItem(resource ResourceId, amount int64) *ResourceItem {
+	return &ResourceItem{Resource: resource, Amount: amount}
 }
 
 func (t *ResourceItem) Always() bool         { return true }
 func (t *ResourceItem) Enabled() bool        { return true }
 func (t *ResourceItem) Probability() float64 { return 1.0 }
 func (t *ResourceItem) Value() int64         { return t.Amount }
+func (t *ResourceItem) Unique() bool         { return false }
 
+// Fixed amounts of resources
 type ResourcesLootTable struct {
-	Gold int64
-	Wood int64
+	Amounts map[ResourceId]int64
 }
 
-func (t *ResourcesLootTable) Result() []LootTableItem {
-	res := []LootTableItem{}
-	if t.Gold > 0 {
-		res = append(res, NewGoldItem(t.Gold))
+// Sorted by resource id to keep results deterministic
+func (t *ResourcesLootTable) Result(*rand.Rand) []LootTableItem {
+	ids := make([]ResourceId, 0, len(t.Amounts))
+	for id := range t.Amounts {
+		ids = append(ids, id)
 	}
-	if t.Wood > 0 {
-		res = append(res, NewWoodItem(t.Wood))
+	slices.Sort(ids)
+	res := []LootTableItem{}
+	for _, id := range ids {
+		if t.Amounts[id] > 0 {
+			res = append(res, NewResourceItem(id, t.Amounts[id]))
+		}
 	}
 	return res
 }
 
-func (t *ResourcesLootTable) AddWood(amount int64) { t.Wood += amount }
-func (t *ResourcesLootTable) AddGold(amount int64) { t.Gold += amount }
+func (t *ResourcesLootTable) Add(resource ResourceId, amount int64) { t.Amounts[resource] += amount }
 
 func (t *ResourcesLootTable) Always() bool              { return true }
 func (t *ResourcesLootTable) Contents() []LootTableItem { return []LootTableItem{} }
 func (t *ResourcesLootTable) Enabled() bool             { return true }
 func (t *ResourcesLootTable) Probability() float64      { return 1.0 }
 func (t *ResourcesLootTable) Count() int64              { return 1 }
+func (t *ResourcesLootTable) Unique() bool              { return false }
 
 func NewResourcesLootTable() *ResourcesLootTable {
-	return &ResourcesLootTable{}
+	return &ResourcesLootTable{Amounts: map[ResourceId]int64{}}
 }
 
-// Shortcut to init a loot table with only gold inside
-// TODO: Implement min, max
+// Shortcut to init a loot table with a fixed amount of gold inside.
+// See ResourceRoll for random amounts
 func NewGoldLootTable(amount int64) *ResourcesLootTable {
 	t := NewResourcesLootTable()
-	t.AddGold(amount)
+	t.Add(ResourceGold, amount)
 	return t
 }
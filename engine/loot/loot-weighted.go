@@ -0,0 +1,166 @@
+package loot
+
+import (
+	"log"
+	"math/rand/v2"
+)
+
+// Settings shared by all entries of a weighted loot table
+type LootEntry struct {
+	weight   float64
+	always   bool
+	unique   bool
+	disabled bool
+}
+
+func NewLootEntry(weight float64, always, unique bool) LootEntry {
+	return LootEntry{weight: weight, always: always, unique: unique}
+}
+
+func (e *LootEntry) Probability() float64 { return e.weight }
+func (e *LootEntry) Always() bool         { return e.always }
+func (e *LootEntry) Enabled() bool        { return !e.disabled }
+func (e *LootEntry) Unique() bool         { return e.unique }
+func (e *LootEntry) SetEnabled(v bool)    { e.disabled = !v }
+
+// Entries that turn into concrete loot when picked
+type LootRoll interface {
+	LootTableItem
+	Roll(*rand.Rand) []LootTableItem
+}
+
+// Random integer within [min, max]
+func rollRange(rng *rand.Rand, min, max int64) int64 {
+	if max <= min {
+		return min
+	}
+	return min + rng.Int64N(max-min+1)
+}
+
+// Resource amount rolled within [Min, Max]
+type ResourceRoll struct {
+	LootEntry
+	Resource ResourceId
+	Min, Max int64
+}
+
+func (r *ResourceRoll) Roll(rng *rand.Rand) []LootTableItem {
+	amount := rollRange(rng, r.Min, r.Max)
+	if amount <= 0 {
+		return []LootTableItem{}
+	}
+	return []LootTableItem{NewResourceItem(r.Resource, amount)}
+}
+
+// New item instances with a total count rolled within [Min, Max].
+// Counts above the stack size are split into multiple instances
+type ItemRoll struct {
+	LootEntry
+	db       *ItemDB
+	Item     string
+	Min, Max int64
+}
+
+func (r *ItemRoll) Roll(rng *rand.Rand) []LootTableItem {
+	res := []LootTableItem{}
+	stackSize := max(1, r.db.Item(r.Item).StackSize)
+	for count := rollRange(rng, r.Min, r.Max); count > 0; count -= stackSize {
+		instance, err := r.db.NewInstance(r.Item, min(count, stackSize), rng)
+		if err != nil {
+			log.Println("Could not roll item", err.Error())
+			return res
+		}
+		res = append(res, instance)
+	}
+	return res
+}
+
+// Picked like any other entry, but drops nothing. Used to model drop chances
+type NothingRoll struct {
+	LootEntry
+}
+
+func (r *NothingRoll) Roll(*rand.Rand) []LootTableItem { return []LootTableItem{} }
+
+// Evaluates entries as described in https://www.codeproject.com/Articles/420046/Loot-Tables-Random-Maps-and-Monsters-Part-I:
+// All enabled "always" entries are added first, followed by a number of weighted picks
+// within [CountMin, CountMax]. Picked tables are evaluated recursively
+type WeightedLootTable struct {
+	LootEntry
+	// Set for tables registered in a LootTableDB. Used to persist the table
+	Id       string
+	contents []LootTableItem
+	CountMin int64
+	CountMax int64
+}
+
+func NewWeightedLootTable(count int64, contents ...LootTableItem) *WeightedLootTable {
+	return &WeightedLootTable{LootEntry: NewLootEntry(1, false, false), contents: contents, CountMin: count, CountMax: count}
+}
+
+func (t *WeightedLootTable) Add(item LootTableItem)    { t.contents = append(t.contents, item) }
+func (t *WeightedLootTable) Contents() []LootTableItem { return t.contents }
+
+// Upper bound of weighted picks
+func (t *WeightedLootTable) Count() int64 { return max(t.CountMin, t.CountMax) }
+
+func (t *WeightedLootTable) Result(rng *rand.Rand) []LootTableItem {
+	return t.evaluate(rng, map[LootTableItem]bool{})
+}
+
+// Unique entries that have already been dropped are shared with nested tables
+func (t *WeightedLootTable) evaluate(rng *rand.Rand, dropped map[LootTableItem]bool) []LootTableItem {
+	res := []LootTableItem{}
+	for _, entry := range t.contents {
+		if entry.Enabled() && entry.Always() && !dropped[entry] {
+			res = append(res, resolveLootEntry(entry, rng, dropped)...)
+		}
+	}
+	for range rollRange(rng, t.CountMin, t.CountMax) {
+		entry := t.pick(rng, dropped)
+		if entry == nil {
+			break
+		}
+		res = append(res, resolveLootEntry(entry, rng, dropped)...)
+	}
+	return res
+}
+
+// Weighted pick among enabled entries. Nil if there is nothing left to pick
+func (t *WeightedLootTable) pick(rng *rand.Rand, dropped map[LootTableItem]bool) LootTableItem {
+	candidates := []LootTableItem{}
+	total := 0.0
+	for _, entry := range t.contents {
+		if entry.Enabled() && !entry.Always() && entry.Probability() > 0 && !dropped[entry] {
+			candidates = append(candidates, entry)
+			total += entry.Probability()
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	hit := rng.Float64() * total
+	for _, entry := range candidates {
+		hit -= entry.Probability()
+		if hit < 0 {
+			return entry
+		}
+	}
+	// Rounding errors
+	return candidates[len(candidates)-1]
+}
+
+func resolveLootEntry(entry LootTableItem, rng *rand.Rand, dropped map[LootTableItem]bool) []LootTableItem {
+	if entry.Unique() {
+		dropped[entry] = true
+	}
+	switch v := entry.(type) {
+	case *WeightedLootTable:
+		return v.evaluate(rng, dropped)
+	case LootRoll:
+		return v.Roll(rng)
+	case LootTable:
+		return v.Result(rng)
+	}
+	return []LootTableItem{entry}
+}
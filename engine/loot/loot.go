@@ -1,10 +1,16 @@
 package loot
 
+import "math/rand/v2"
+
 // Inspired by https://www.codeproject.com/Articles/420046/Loot-Tables-Random-Maps-and-Monsters-Part-I
 type LootTableItem interface {
+	// Relative weight when picking from a table
 	Probability() float64
+	// Part of every result, in addition to the weighted picks
 	Always() bool
 	Enabled() bool
+	// Dropped at most once per evaluation
+	Unique() bool
 }
 
 type LootTableItemValue interface {
@@ -18,8 +24,9 @@ type LootTable interface {
 	// Used for recursion
 	Contents() []LootTableItem
 	Count() int64
-	// Result of (usually randomly) evaluating loot table
-	Result() []LootTableItem
+	// Result of (usually randomly) evaluating loot table.
+	// Deterministic tables ignore the generator, so it may be nil
+	Result(*rand.Rand) []LootTableItem
 }
 
 type GuaranteedLootTable struct {
@@ -31,9 +38,11 @@ func (t *GuaranteedLootTable) Contents() []LootTableItem { return t.items }
 func (t *GuaranteedLootTable) Enabled() bool             { return true }
 func (t *GuaranteedLootTable) Probability() float64      { return 1.0 }
 func (t *GuaranteedLootTable) Count() int64              { return 1 }
-func (t *GuaranteedLootTable) Result() []LootTableItem   { return t.items }
+func (t *GuaranteedLootTable) Unique() bool              { return false }
+
+func (t *GuaranteedLootTable) Result(*rand.Rand) []LootTableItem { return t.items }
 
 // Constructor for guaranteed drop loot table
-func NewGuaranteedLootTable(item LootTableItem) *GuaranteedLootTable {
-	return &GuaranteedLootTable{[]LootTableItem{item}}
+func NewGuaranteedLootTable(items ...LootTableItem) *GuaranteedLootTable {
+	return &GuaranteedLootTable{items}
 }
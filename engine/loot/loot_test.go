@@ -1,19 +1,225 @@
 package loot
 
-import "testing"
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
 
 func TestGoldLoot(t *testing.T) {
 	goldVal := int64(50)
 	lootTable := NewGoldLootTable(goldVal)
-	res := lootTable.Result()
+	res := lootTable.Result(nil)
 	if len(res) == 0 {
 		t.Fatalf("Empty loot table")
 	}
-	goldItem, ok := res[0].(*GoldItem)
-	if !ok {
-		t.Fatalf("Expected gold item, but could not cast")
+	goldItem, ok := res[0].(*ResourceItem)
+	if !ok || goldItem.Resource != ResourceGold {
+		t.Fatalf("Expected gold item, but received %v", res[0])
 	}
 	if goldItem.Value() != goldVal {
 		t.Fatalf("Expected gold value %d, but received %d", goldVal, goldItem.Value())
 	}
 }
+
+func TestInventoryRegisteredResources(t *testing.T) {
+	registry := DefaultResourceRegistry()
+	if err := registry.Register(ResourceKind{Id: "stone", Cap: 100}); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := NewInventory(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := NewResourcesLootTable()
+	table.Add("stone", 150)
+	if err := inv.AddLoot(table.Result(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if balance := inv.Resource("stone").Balance(); balance != 100 {
+		t.Fatalf("Expected stone capped at 100, got %d", balance)
+	}
+
+	item := &GameItem{Price: map[ResourceId]int64{"stone": 60, ResourceGold: 10}}
+	if err := inv.Buy(item); err != nil {
+		t.Fatal(err)
+	}
+	if canAfford, _ := inv.CanAfford(item); canAfford {
+		t.Fatal("Expected insufficient stone for second purchase")
+	}
+
+	unknown := NewResourcesLootTable()
+	unknown.Add("crystals", 1)
+	unknown.Add(ResourceGold, 5)
+	gold := inv.Resource(ResourceGold).Balance()
+	if err := inv.AddLoot(unknown.Result(nil)); err == nil {
+		t.Fatal("Expected error for unregistered resource")
+	}
+	if inv.Resource(ResourceGold).Balance() != gold {
+		t.Fatal("Expected no gold to be added with invalid loot")
+	}
+}
+
+func TestBagStacksItems(t *testing.T) {
+	arrows := &GameItem{Id: "arrows", Description: "Arrows", StackSize: 10}
+	db, err := NewItemDB(arrows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bag, err := NewBag(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		instance, err := db.NewInstance("arrows", 6, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bag.Add(instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bag.Slot(0).Count != 10 || bag.Slot(1).Count != 8 {
+		t.Fatalf("Expected stacks of 10 & 8, got %s & %s", bag.Slot(0), bag.Slot(1))
+	}
+	// Merging onto a full stack keeps the source
+	if err := bag.Move(1, 0); err != nil || bag.Slot(1).Count != 8 {
+		t.Fatalf("Expected source stack untouched, got %v", err)
+	}
+	// Partial fit must not change any stack
+	instance, _ := db.NewInstance("arrows", 3, nil)
+	if err := bag.Add(instance); err == nil {
+		t.Fatal("Expected full bag error")
+	}
+	if bag.Slot(1).Count != 8 || instance.Count != 3 {
+		t.Fatalf("Expected bag & instance untouched, got %s & %s", bag.Slot(1), instance)
+	}
+	instance, _ = db.NewInstance("arrows", 2, nil)
+	if err := bag.Add(instance); err != nil || bag.Slot(1).Count != 10 {
+		t.Fatalf("Expected remaining space to be filled, got %v", err)
+	}
+}
+
+func TestWeightedLootTable(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	gold := &ResourceRoll{LootEntry: NewLootEntry(0, true, false), Resource: ResourceGold, Min: 5, Max: 10}
+	rare := &ResourceRoll{LootEntry: NewLootEntry(1, false, true), Resource: "crystals", Min: 1, Max: 1}
+	common := &ResourceRoll{LootEntry: NewLootEntry(9, false, false), Resource: ResourceWood, Min: 1, Max: 1}
+	nested := NewWeightedLootTable(1, rare, common)
+	nested.LootEntry = NewLootEntry(1, false, false)
+	table := NewWeightedLootTable(3, gold, nested)
+
+	totals := map[ResourceId]int64{}
+	for range 1000 {
+		res := table.Result(rng)
+		crystals := 0
+		for _, item := range res {
+			v := item.(*ResourceItem)
+			totals[v.Resource] += v.Amount
+			if v.Resource == "crystals" {
+				crystals++
+			}
+		}
+		// Guaranteed gold + 3 picks
+		if len(res) != 4 {
+			t.Fatalf("Expected 4 results, got %d", len(res))
+		}
+		if crystals > 1 {
+			t.Fatalf("Expected unique entry to drop at most once, got %d", crystals)
+		}
+		if v := res[0].(*ResourceItem); v.Resource != ResourceGold || v.Amount < 5 || v.Amount > 10 {
+			t.Fatalf("Expected 5-10 gold first, got %v", v)
+		}
+	}
+	if totals["crystals"] < 100 || totals[ResourceWood] < totals["crystals"]*5 {
+		t.Fatalf("Unexpected weight distribution %v", totals)
+	}
+
+	// Same seed, same drops
+	first := NewWeightedLootTable(2, common, rare).Result(rand.New(rand.NewPCG(5, 5)))
+	second := NewWeightedLootTable(2, common, rare).Result(rand.New(rand.NewPCG(5, 5)))
+	if len(first) != len(second) {
+		t.Fatalf("Expected deterministic results, got %v & %v", first, second)
+	}
+	for idx := range first {
+		if *first[idx].(*ResourceItem) != *second[idx].(*ResourceItem) {
+			t.Fatalf("Expected deterministic results, got %v & %v", first, second)
+		}
+	}
+}
+
+func TestLootDefinitions(t *testing.T) {
+	data := []byte(`{
+		"items": [{"id": "sword", "description": "Sword", "slot": "weapon"}],
+		"resources": {"wood": {"sprite": "wood", "spawnAnimation": "spawn", "rarity": "common"}},
+		"tables": {
+			"npc": {"min": 2, "max": 2, "entries": [{"table": "drops", "weight": 1}]},
+			"drops": {"entries": [{"item": "sword", "weight": 1}, {"resource": "gold", "weight": 1, "min": 1, "max": 3}]}
+		}
+	}`)
+	items, _ := NewItemDB()
+	resources := DefaultResourceRegistry()
+	db, err := ParseLootDefinitions(data, resources, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wood, _ := resources.Kind(ResourceWood); wood.DropAppearance != (DropAppearance{"wood", "spawn", RarityCommon}) {
+		t.Fatalf("Expected wood appearance from definitions, got %v", wood.DropAppearance)
+	}
+	table, err := db.Table("npc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Id != "npc" {
+		t.Fatalf("Expected table id npc, got %s", table.Id)
+	}
+	res := table.Result(rand.New(rand.NewPCG(1, 1)))
+	if len(res) != 2 {
+		t.Fatalf("Expected 2 drops, got %v", res)
+	}
+
+	// Tables built in code round trip through definitions & keep references to named tables
+	gold := &ResourceRoll{LootEntry: NewLootEntry(0, true, false), Resource: ResourceGold, Min: 5, Max: 10}
+	sword := &ItemRoll{LootEntry: NewLootEntry(1, false, true), db: items, Item: "sword", Min: 1, Max: 1}
+	code := NewWeightedLootTable(3, gold, &NothingRoll{NewLootEntry(2, false, false)}, sword, table.Contents()[0])
+	def, err := NewLootEntryDefinition(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := db.NewTable(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	describe := func(items []LootTableItem) string {
+		res := ""
+		for _, item := range items {
+			if v, ok := item.(*ResourceItem); ok {
+				res += fmt.Sprintf("%s %d,", v.Resource, v.Amount)
+			} else {
+				res += fmt.Sprintf("%s,", item)
+			}
+		}
+		return res
+	}
+	first, second := code.Result(rand.New(rand.NewPCG(3, 3))), restored.Result(rand.New(rand.NewPCG(3, 3)))
+	if describe(first) != describe(second) {
+		t.Fatalf("Expected restored table to drop %s, got %s", describe(first), describe(second))
+	}
+	if restoredDef, _ := NewLootEntryDefinition(restored); !reflect.DeepEqual(def, restoredDef) {
+		t.Fatalf("Expected identical definitions, got %v & %v", def, restoredDef)
+	}
+
+	circular := []byte(`{"tables": {"a": {"entries": [{"table": "b", "weight": 1}]}, "b": {"entries": [{"table": "a", "weight": 1}]}}}`)
+	if _, err := ParseLootDefinitions(circular, DefaultResourceRegistry(), items); err == nil {
+		t.Fatal("Expected circular reference error")
+	}
+	unknown := []byte(`{"tables": {"a": {"entries": [{"item": "axe", "weight": 1}]}}}`)
+	if _, err := ParseLootDefinitions(unknown, DefaultResourceRegistry(), items); err == nil {
+		t.Fatal("Expected unknown item error")
+	}
+	unknownResource := []byte(`{"resources": {"stone": {"sprite": "stone"}}}`)
+	if _, err := ParseLootDefinitions(unknownResource, DefaultResourceRegistry(), items); err == nil {
+		t.Fatal("Expected unknown resource error")
+	}
+}
@@ -0,0 +1,49 @@
+package loot
+
+import "image/color"
+
+type Rarity string
+
+const (
+	RarityCommon    Rarity = "common"
+	RarityUncommon  Rarity = "uncommon"
+	RarityRare      Rarity = "rare"
+	RarityEpic      Rarity = "epic"
+	RarityLegendary Rarity = "legendary"
+)
+
+// Outline color of dropped items. Unknown rarities are drawn as common
+func (r Rarity) Color() color.Color {
+	switch r {
+	case RarityUncommon:
+		return color.RGBA{30, 255, 0, 255}
+	case RarityRare:
+		return color.RGBA{0, 112, 221, 255}
+	case RarityEpic:
+		return color.RGBA{163, 53, 238, 255}
+	case RarityLegendary:
+		return color.RGBA{255, 128, 0, 255}
+	}
+	return color.RGBA{200, 200, 200, 255}
+}
+
+// How a dropped resource or item looks in the world
+type DropAppearance struct {
+	// Name of the character asset. Optional, drops without sprite are drawn as rarity colored box
+	Sprite string
+	// Played once when dropped, e.g. "spawn"
+	SpawnAnimation string
+	Rarity         Rarity
+}
+
+// Appearance of an evaluated loot table item
+func (r *ResourceRegistry) DropAppearance(item LootTableItem) DropAppearance {
+	switch v := item.(type) {
+	case *ResourceItem:
+		kind, _ := r.Kind(v.Resource)
+		return kind.DropAppearance
+	case *ItemInstance:
+		return v.Item.DropAppearance
+	}
+	return DropAppearance{}
+}
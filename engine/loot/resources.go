@@ -0,0 +1,87 @@
+package loot
+
+import (
+	"fmt"
+	"slices"
+)
+
+type ResourceId string
+
+// Resources used by the engine itself, e.g. trees drop wood
+const (
+	ResourceGold ResourceId = "gold"
+	ResourceWood ResourceId = "wood"
+)
+
+type ResourceKind struct {
+	Id ResourceId
+	// Display name
+	Name string
+	// Path of the icon image within the assets. Optional
+	Icon string
+	// Upper limit of the balance. 0 = uncapped
+	Cap int64
+	// Balance of new inventories
+	Initial int64
+	// Collected by walking over drops instead of pressing E
+	AutoPickup bool
+	DropAppearance
+}
+
+// Resource kinds known to the game. Inventories, prices & loot tables only work with registered kinds
+type ResourceRegistry struct {
+	// Ordered by registration to keep display & evaluation order stable
+	kinds []ResourceKind
+}
+
+func NewResourceRegistry(kinds ...ResourceKind) (*ResourceRegistry, error) {
+	r := &ResourceRegistry{}
+	for _, kind := range kinds {
+		if err := r.Register(kind); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Gold & wood
+func DefaultResourceRegistry() *ResourceRegistry {
+	return &ResourceRegistry{kinds: []ResourceKind{
+		{Id: ResourceGold, Name: "Gold", Initial: 5000, AutoPickup: true},
+		{Id: ResourceWood, Name: "Wood", AutoPickup: true},
+	}}
+}
+
+func (r *ResourceRegistry) Register(kind ResourceKind) error {
+	if kind.Id == "" {
+		return fmt.Errorf("Cannot register resource without id")
+	}
+	if _, ok := r.Kind(kind.Id); ok {
+		return fmt.Errorf("Resource %s already registered", kind.Id)
+	}
+	if kind.Name == "" {
+		kind.Name = string(kind.Id)
+	}
+	r.kinds = append(r.kinds, kind)
+	return nil
+}
+
+func (r *ResourceRegistry) Kind(id ResourceId) (ResourceKind, bool) {
+	idx := slices.IndexFunc(r.kinds, func(k ResourceKind) bool { return k.Id == id })
+	if idx == -1 {
+		return ResourceKind{}, false
+	}
+	return r.kinds[idx], true
+}
+
+func (r *ResourceRegistry) Kinds() []ResourceKind { return slices.Clone(r.kinds) }
+
+// Appearances are part of the loot definitions, so they can be changed without recompiling
+func (r *ResourceRegistry) SetDropAppearance(id ResourceId, appearance DropAppearance) error {
+	idx := slices.IndexFunc(r.kinds, func(k ResourceKind) bool { return k.Id == id })
+	if idx == -1 {
+		return fmt.Errorf("Unknown resource %s", id)
+	}
+	r.kinds[idx].DropAppearance = appearance
+	return nil
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+// Cells followed ahead of the agent to smooth its movement
+const flowFieldLookAhead = 12
+
+// Direction field towards a single target shared by all agents of the same size.
+// Replaces one path query per agent by one Dijkstra pass over the grid
+type FlowField struct {
+	grid   *NavGrid
+	target GameEntity
+	radius float64
+
+	// Cost to reach the target from each cell. Inf if unreachable
+	cost []float64
+	// Index of the next cell towards the target. -1 for targets & unreachable cells
+	next []int
+	// Cells next to the target
+	seeds map[int]bool
+
+	// State of the last computation
+	targetCell  NavCell
+	gridVersion int
+	computed    bool
+}
+
+func NewFlowField(grid *NavGrid, target GameEntity, radius float64) (*FlowField, error) {
+	if grid == nil || target == nil {
+		return nil, fmt.Errorf("Cannot create flow field without grid or target")
+	}
+	return &FlowField{grid: grid, target: target, radius: radius}, nil
+}
+
+func (f *FlowField) Target() GameEntity { return f.target }
+func (f *FlowField) Radius() float64    { return f.radius }
+
+// Recompute if the target moved to another cell or the grid changed
+func (f *FlowField) Update() {
+	targetCell := f.grid.Cell(f.target.Shape().Body().Position())
+	if f.computed && targetCell == f.targetCell && f.grid.Version() == f.gridVersion {
+		return
+	}
+	f.targetCell = targetCell
+	f.gridVersion = f.grid.Version()
+	f.computed = true
+	f.compute()
+}
+
+func (f *FlowField) compute() {
+	g := f.grid
+	f.cost = make([]float64, g.cols*g.rows)
+	f.next = make([]int, g.cols*g.rows)
+	for idx := range f.cost {
+		f.cost[idx] = math.Inf(1)
+		f.next[idx] = -1
+	}
+	f.seeds = map[int]bool{}
+	open := &navQueue{}
+	for _, cell := range f.seedCells() {
+		idx := g.index(cell)
+		f.cost[idx] = 0
+		f.seeds[idx] = true
+		heap.Push(open, navQueueItem{cell: cell})
+	}
+	for open.Len() > 0 {
+		current := heap.Pop(open).(navQueueItem)
+		currentIdx := g.index(current.cell)
+		if current.priority > f.cost[currentIdx] {
+			continue
+		}
+		g.neighbours(current.cell, f.radius, func(cell NavCell, stepCost float64) {
+			idx := g.index(cell)
+			cost := f.cost[currentIdx] + stepCost
+			if cost >= f.cost[idx] {
+				return
+			}
+			f.cost[idx] = cost
+			f.next[idx] = currentIdx
+			heap.Push(open, navQueueItem{cell: cell, priority: cost})
+		})
+	}
+}
+
+// Walkable cells touching the target shape. Falls back to the nearest walkable cell
+func (f *FlowField) seedCells() []NavCell {
+	res := []NavCell{}
+	if f.target.Shape() != nil {
+		bb := f.target.Shape().BB()
+		margin := f.radius + f.grid.cellSize
+		bb = cp.BB{L: bb.L - margin, B: bb.B - margin, R: bb.R + margin, T: bb.T + margin}
+		minCell := f.grid.Cell(cp.Vector{X: bb.L, Y: bb.B})
+		maxCell := f.grid.Cell(cp.Vector{X: bb.R, Y: bb.T})
+		for row := minCell.Row; row <= maxCell.Row; row++ {
+			for col := minCell.Col; col <= maxCell.Col; col++ {
+				if cell := (NavCell{col, row}); f.grid.Walkable(cell, f.radius) {
+					res = append(res, cell)
+				}
+			}
+		}
+	}
+	if len(res) == 0 {
+		if cell, ok := f.grid.nearestWalkable(f.targetCell, f.radius, navSearchCells); ok {
+			res = append(res, cell)
+		}
+	}
+	return res
+}
+
+// Cost to reach the target from pos in cells. Inf if unreachable
+func (f *FlowField) Cost(pos cp.Vector) float64 {
+	cell := f.grid.Cell(pos)
+	if !f.computed || !f.grid.InBounds(cell) {
+		return math.Inf(1)
+	}
+	return f.cost[f.grid.index(cell)]
+}
+
+// Follows the field ahead of pos & returns the furthest position in line of sight.
+// Once the target is in reach, returns the target position
+func (f *FlowField) NextPosition(pos cp.Vector) (cp.Vector, bool) {
+	if !f.computed {
+		f.Update()
+	}
+	g := f.grid
+	cell, ok := g.nearestWalkable(g.Cell(pos), f.radius, navSearchCells)
+	if !ok {
+		return cp.Vector{}, false
+	}
+	idx := g.index(cell)
+	if math.IsInf(f.cost[idx], 1) {
+		return cp.Vector{}, false
+	}
+	// Pushed into a blocked cell: Return to the walkable one first
+	if cell != g.Cell(pos) {
+		return g.Center(cell), true
+	}
+	res := g.Center(cell)
+	for range flowFieldLookAhead {
+		if f.seeds[idx] {
+			return f.target.Shape().Body().Position(), true
+		}
+		idx = f.next[idx]
+		candidate := g.Center(NavCell{idx % g.cols, idx / g.cols})
+		if !g.LineOfSight(pos, candidate, f.radius) {
+			break
+		}
+		res = candidate
+	}
+	return res, true
+}
@@ -0,0 +1,262 @@
+package engine
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+// Shapes blocking npc movement. Trees are excluded, because npcs walk through them
+var navObstacleFilter = cp.NewShapeFilter(cp.NO_GROUP, NpcCategory, OuterWallsCategory|TowerCategory)
+
+const (
+	// One cell per map tile
+	DefaultNavCellSize = mapTileSize
+	// Distances above this are not tracked. Limits the agent radius
+	navMaxClearance = 64.0
+	// Agents & targets within blocked cells look for walkable cells within this distance
+	navSearchCells = 16
+)
+
+type NavCell struct {
+	Col, Row int
+}
+
+// Grid of walkable cells rasterized from the collision shapes of a space.
+// Every cell stores the distance from its center to the nearest obstacle,
+// so agents of any size can query paths that respect their bounding box
+type NavGrid struct {
+	space     *cp.Space
+	cellSize  float64
+	cols      int
+	rows      int
+	clearance []float64
+	// Incremented on every change. Used to invalidate cached paths & flow fields
+	version int
+}
+
+func NewNavGrid(space *cp.Space, width, height int64, cellSize float64) (*NavGrid, error) {
+	if cellSize <= 0 {
+		return nil, fmt.Errorf("Invalid nav grid cell size %f", cellSize)
+	}
+	g := &NavGrid{
+		space:    space,
+		cellSize: cellSize,
+		cols:     int(math.Ceil(float64(width) / cellSize)),
+		rows:     int(math.Ceil(float64(height) / cellSize)),
+	}
+	g.clearance = make([]float64, g.cols*g.rows)
+	g.Rebuild(cp.BB{L: 0, B: 0, R: float64(width), T: float64(height)})
+	return g, nil
+}
+
+// Recalculate clearance of all cells that may be affected by changes within bb,
+// e.g. after an obstacle has been added or removed
+func (g *NavGrid) Rebuild(bb cp.BB) {
+	minCell := g.Cell(cp.Vector{X: bb.L - navMaxClearance, Y: bb.B - navMaxClearance})
+	maxCell := g.Cell(cp.Vector{X: bb.R + navMaxClearance, Y: bb.T + navMaxClearance})
+	for row := max(minCell.Row, 0); row <= min(maxCell.Row, g.rows-1); row++ {
+		for col := max(minCell.Col, 0); col <= min(maxCell.Col, g.cols-1); col++ {
+			cell := NavCell{col, row}
+			clearance := navMaxClearance
+			query := g.space.PointQueryNearest(g.Center(cell), navMaxClearance, navObstacleFilter)
+			if query.Shape != nil {
+				clearance = max(query.Distance, 0)
+			}
+			g.clearance[g.index(cell)] = clearance
+		}
+	}
+	g.version++
+}
+
+func (g *NavGrid) CellSize() float64 { return g.cellSize }
+func (g *NavGrid) Version() int      { return g.version }
+
+func (g *NavGrid) Cell(pos cp.Vector) NavCell {
+	return NavCell{int(math.Floor(pos.X / g.cellSize)), int(math.Floor(pos.Y / g.cellSize))}
+}
+
+func (g *NavGrid) Center(cell NavCell) cp.Vector {
+	return cp.Vector{X: (float64(cell.Col) + 0.5) * g.cellSize, Y: (float64(cell.Row) + 0.5) * g.cellSize}
+}
+
+func (g *NavGrid) InBounds(cell NavCell) bool {
+	return cell.Col >= 0 && cell.Row >= 0 && cell.Col < g.cols && cell.Row < g.rows
+}
+
+// True if an agent with the given radius fits into the cell
+func (g *NavGrid) Walkable(cell NavCell, radius float64) bool {
+	return g.InBounds(cell) && g.clearance[g.index(cell)] >= radius
+}
+
+func (g *NavGrid) index(cell NavCell) int { return cell.Row*g.cols + cell.Col }
+
+// 8-connected neighbours. Diagonals are only walkable if both adjacent cells are,
+// to prevent agents from cutting corners
+var navDirections = []NavCell{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+func (g *NavGrid) neighbours(cell NavCell, radius float64, fn func(NavCell, float64)) {
+	for _, d := range navDirections {
+		next := NavCell{cell.Col + d.Col, cell.Row + d.Row}
+		if !g.Walkable(next, radius) {
+			continue
+		}
+		cost := 1.0
+		if d.Col != 0 && d.Row != 0 {
+			if !g.Walkable(NavCell{cell.Col + d.Col, cell.Row}, radius) || !g.Walkable(NavCell{cell.Col, cell.Row + d.Row}, radius) {
+				continue
+			}
+			cost = math.Sqrt2
+		}
+		fn(next, cost)
+	}
+}
+
+// Closest walkable cell within maxDist cells. False if there is none
+func (g *NavGrid) nearestWalkable(cell NavCell, radius float64, maxDist int) (NavCell, bool) {
+	if g.Walkable(cell, radius) {
+		return cell, true
+	}
+	for dist := 1; dist <= maxDist; dist++ {
+		// Scan ring in fixed order to keep results deterministic
+		for d := -dist; d <= dist; d++ {
+			for _, c := range []NavCell{
+				{cell.Col + d, cell.Row - dist}, {cell.Col + d, cell.Row + dist},
+				{cell.Col - dist, cell.Row + d}, {cell.Col + dist, cell.Row + d},
+			} {
+				if g.Walkable(c, radius) {
+					return c, true
+				}
+			}
+		}
+	}
+	return NavCell{}, false
+}
+
+// True if an agent with the given radius can move in a straight line from src to dst
+func (g *NavGrid) LineOfSight(src, dst cp.Vector, radius float64) bool {
+	dist := src.Distance(dst)
+	steps := int(math.Ceil(dist / (g.cellSize / 2)))
+	for step := 0; step <= steps; step++ {
+		t := 1.0
+		if steps > 0 {
+			t = float64(step) / float64(steps)
+		}
+		if !g.Walkable(g.Cell(src.Lerp(dst, t)), radius) {
+			return false
+		}
+	}
+	return true
+}
+
+// A* query for a single agent. Returns smoothed path of world positions, excluding src.
+// False if dst cannot be reached
+func (g *NavGrid) FindPath(src, dst cp.Vector, radius float64) ([]cp.Vector, bool) {
+	start, ok := g.nearestWalkable(g.Cell(src), radius, navSearchCells)
+	if !ok {
+		return nil, false
+	}
+	goal, ok := g.nearestWalkable(g.Cell(dst), radius, navSearchCells)
+	if !ok {
+		return nil, false
+	}
+	startIdx, goalIdx := g.index(start), g.index(goal)
+	cost := map[int]float64{startIdx: 0}
+	parent := map[int]int{}
+	open := &navQueue{}
+	heap.Push(open, navQueueItem{cell: start, priority: g.heuristic(start, goal)})
+	for open.Len() > 0 {
+		current := heap.Pop(open).(navQueueItem)
+		currentIdx := g.index(current.cell)
+		if currentIdx == goalIdx {
+			break
+		}
+		// Outdated queue entry
+		if current.priority > cost[currentIdx]+g.heuristic(current.cell, goal)+1e-9 {
+			continue
+		}
+		g.neighbours(current.cell, radius, func(next NavCell, stepCost float64) {
+			nextIdx := g.index(next)
+			nextCost := cost[currentIdx] + stepCost
+			if known, ok := cost[nextIdx]; ok && known <= nextCost {
+				return
+			}
+			cost[nextIdx] = nextCost
+			parent[nextIdx] = currentIdx
+			heap.Push(open, navQueueItem{cell: next, priority: nextCost + g.heuristic(next, goal)})
+		})
+	}
+	if _, ok := cost[goalIdx]; !ok {
+		return nil, false
+	}
+	// Walk back from goal
+	cells := []cp.Vector{dst}
+	for idx, ok := parent[goalIdx]; ok && idx != startIdx; idx, ok = parent[idx] {
+		cells = append(cells, g.Center(NavCell{idx % g.cols, idx / g.cols}))
+	}
+	// Reverse
+	for i, j := 0, len(cells)-1; i < j; i, j = i+1, j-1 {
+		cells[i], cells[j] = cells[j], cells[i]
+	}
+	return g.SmoothPath(src, cells, radius), true
+}
+
+// Octile distance in cells
+func (g *NavGrid) heuristic(a, b NavCell) float64 {
+	dx := math.Abs(float64(a.Col - b.Col))
+	dy := math.Abs(float64(a.Row - b.Row))
+	return max(dx, dy) + (math.Sqrt2-1)*min(dx, dy)
+}
+
+// String pulling: Skip all waypoints that can be reached in a straight line
+func (g *NavGrid) SmoothPath(src cp.Vector, path []cp.Vector, radius float64) []cp.Vector {
+	res := []cp.Vector{}
+	current := src
+	for idx := 0; idx < len(path); {
+		furthest := idx
+		for next := len(path) - 1; next > idx; next-- {
+			if g.LineOfSight(current, path[next], radius) {
+				furthest = next
+				break
+			}
+		}
+		res = append(res, path[furthest])
+		current = path[furthest]
+		idx = furthest + 1
+	}
+	return res
+}
+
+// Priority queue of cells. Ties are resolved by insertion order to stay deterministic
+type navQueueItem struct {
+	cell     NavCell
+	priority float64
+	seq      int
+}
+
+type navQueue struct {
+	items  []navQueueItem
+	pushed int
+}
+
+func (q *navQueue) Len() int { return len(q.items) }
+func (q *navQueue) Less(i, j int) bool {
+	if q.items[i].priority != q.items[j].priority {
+		return q.items[i].priority < q.items[j].priority
+	}
+	return q.items[i].seq < q.items[j].seq
+}
+func (q *navQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *navQueue) Push(x any) {
+	item := x.(navQueueItem)
+	item.seq = q.pushed
+	q.pushed++
+	q.items = append(q.items, item)
+}
+func (q *navQueue) Pop() any {
+	item := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return item
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+// Steers an agent towards its destination
+type Navigator interface {
+	// Position to move towards next. False if the destination cannot be reached
+	NextPosition(pos cp.Vector) (cp.Vector, bool)
+}
+
+// Nav grid of a world & the flow fields shared by its agents
+type Navigation struct {
+	grid   *NavGrid
+	fields []*FlowField
+}
+
+func NewNavigation(grid *NavGrid) (*Navigation, error) {
+	if grid == nil {
+		return nil, fmt.Errorf("Cannot create navigation without grid")
+	}
+	return &Navigation{grid: grid}, nil
+}
+
+func (n *Navigation) Grid() *NavGrid { return n.grid }
+
+// Flow field towards target, shared by all agents of similar size.
+// Radius is rounded up to half cells, so a whole wave uses a handful of fields
+func (n *Navigation) FlowField(target GameEntity, radius float64) *FlowField {
+	step := n.grid.cellSize / 2
+	radius = math.Ceil(radius/step) * step
+	for _, field := range n.fields {
+		if field.target == target && field.radius == radius {
+			return field
+		}
+	}
+	field := &FlowField{grid: n.grid, target: target, radius: radius}
+	n.fields = append(n.fields, field)
+	return field
+}
+
+// A* path of a single agent towards target
+func (n *Navigation) PathFollower(target GameEntity, radius float64) *PathFollower {
+	return &PathFollower{grid: n.grid, target: target, radius: radius}
+}
+
+// Recompute flow fields. Call once per tick
+func (n *Navigation) Update() {
+	for _, field := range n.fields {
+		field.Update()
+	}
+}
+
+// Radius of the circle around the bounding box center, that is used to query paths
+func AgentRadius(shape *cp.Shape) float64 {
+	bb := shape.BB()
+	return max(bb.R-bb.L, bb.T-bb.B) / 2
+}
+
+// Follows an A* path. Replans once the target moved to another cell or the grid changed
+type PathFollower struct {
+	grid   *NavGrid
+	target GameEntity
+	radius float64
+
+	path      []cp.Vector
+	reachable bool
+	// State of the last query
+	pathTarget  NavCell
+	gridVersion int
+	planned     bool
+}
+
+func (p *PathFollower) NextPosition(pos cp.Vector) (cp.Vector, bool) {
+	targetPos := p.target.Shape().Body().Position()
+	targetCell := p.grid.Cell(targetPos)
+	if !p.planned || targetCell != p.pathTarget || p.grid.Version() != p.gridVersion {
+		p.path, p.reachable = p.grid.FindPath(pos, targetPos, p.radius)
+		p.pathTarget = targetCell
+		p.gridVersion = p.grid.Version()
+		p.planned = true
+	}
+	if !p.reachable {
+		return cp.Vector{}, false
+	}
+	// Skip waypoints that have been reached
+	for len(p.path) > 1 && pos.Distance(p.path[0]) < p.grid.cellSize/2 {
+		p.path = p.path[1:]
+	}
+	// Last waypoint follows the target within its cell
+	if len(p.path) <= 1 {
+		return targetPos, true
+	}
+	return p.path[0], true
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+// World split by a vertical wall with a gap
+func newNavTestWorld(t *testing.T, gapStart, gapEnd float64) *GameWorld {
+	w, err := NewWorld(640, 640, 1, loot.DefaultResourceRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterWallSegmentToSpace(w.Space(), WallSegment{cp.Vector{X: 320, Y: 0}, cp.Vector{X: 320, Y: gapStart}})
+	RegisterWallSegmentToSpace(w.Space(), WallSegment{cp.Vector{X: 320, Y: gapEnd}, cp.Vector{X: 320, Y: 640}})
+	return w
+}
+
+func TestFindPathAroundWall(t *testing.T) {
+	w := newNavTestWorld(t, 480, 640)
+	nav, err := w.BuildNavigation(DefaultNavCellSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, dst := cp.Vector{X: 160, Y: 160}, cp.Vector{X: 480, Y: 160}
+	path, ok := nav.Grid().FindPath(src, dst, 12)
+	if !ok {
+		t.Fatal("Expected path around wall")
+	}
+	if len(path) > 4 || path[len(path)-1] != dst {
+		t.Errorf("Expected short smoothed path ending at destination, got %v", path)
+	}
+	prev := src
+	for _, pos := range path {
+		if !nav.Grid().LineOfSight(prev, pos, 12) {
+			t.Errorf("Path crosses obstacle between %v and %v", prev, pos)
+		}
+		prev = pos
+	}
+}
+
+func TestNavGridRespectsAgentRadius(t *testing.T) {
+	// ~44px gap
+	w := newNavTestWorld(t, 296, 344)
+	nav, err := w.BuildNavigation(DefaultNavCellSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, dst := cp.Vector{X: 160, Y: 320}, cp.Vector{X: 480, Y: 320}
+	if _, ok := nav.Grid().FindPath(src, dst, 12); !ok {
+		t.Error("Expected small agent to pass through gap")
+	}
+	if _, ok := nav.Grid().FindPath(src, dst, 20); ok {
+		t.Error("Expected large agent not to fit through gap")
+	}
+}
+
+func TestFlowFieldLeadsAroundWall(t *testing.T) {
+	w := newNavTestWorld(t, 480, 640)
+	nav, err := w.BuildNavigation(DefaultNavCellSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := newDamageTestTree(t, w)
+	target.Shape().Body().SetPosition(cp.Vector{X: 480, Y: 160})
+	if err := w.AddEntity(target); err != nil {
+		t.Fatal(err)
+	}
+	// Agents of similar size share a field
+	field := nav.FlowField(target, 12)
+	if nav.FlowField(target, 11) != field {
+		t.Error("Expected flow field to be shared")
+	}
+	nav.Update()
+
+	pos := cp.Vector{X: 160, Y: 160}
+	for range 200 {
+		if pos.Distance(target.Shape().Body().Position()) < 8 {
+			return
+		}
+		next, ok := field.NextPosition(pos)
+		if !ok {
+			t.Fatalf("Expected target to be reachable from %v", pos)
+		}
+		step := pos.Add(next.Sub(pos).Clamp(8))
+		if !nav.Grid().LineOfSight(pos, step, 12) {
+			t.Fatalf("Flow field leads through obstacle from %v to %v", pos, step)
+		}
+		pos = step
+	}
+	t.Errorf("Expected agent to reach target, stopped at %v", pos)
+}
@@ -1,49 +1,71 @@
 package engine
 
 import (
-	"bytes"
 	"fmt"
 	"log"
-	"math/rand"
+	"math/rand/v2"
 
-	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
 	"github.com/jakecoffman/cp"
-	"github.com/lucb31/game-engine-go/bin/assets"
 )
 
 type NpcAggro struct {
 	*NpcEntity
 
+	// Fallback target, e.g. castle
+	home DefenderEntity
+	// Chosen by the behaviour every tick
 	target DefenderEntity
-	// True, once target has entered attach range
+	// True, once target has entered attack range
 	attacking bool
+	// Set once health drops below the flee threshold, until the flee timer is done
+	fleeing bool
+	// Npcs only flee once
+	fled      bool
+	fleeTimer Timeout
 
-	swingTimer   Timeout
-	waypointInfo WaypointInfo
+	// Decision tree. Shared by all npcs of the same behaviour
+	behaviour   AiNode
+	aggroRadius float64
+	fleeHealth  float64
+	// Used by ranged behaviours. Nil for melee only npcs
+	gun *AimedGun
+
+	swingTimer Timeout
+	// Moves straight towards target if nil
+	navigation *Navigation
+	// A* path towards targets other than home
+	chase *PathFollower
 
-	// SFX
-	player *audio.Player
 	// Used to sync swing SE with animation
 	sfxTimeout Timeout
 }
 
 const (
 	npcSwingSfxDelay = 0.8
+	// Perception radius, if not configured
+	defaultAggroRadius = 150.0
 )
 
-func NewNpcAggro(target DefenderEntity, asset *CharacterAsset, opts NpcOpts) (*NpcAggro, error) {
-	if target == nil {
+func NewNpcAggro(home DefenderEntity, asset *CharacterAsset, opts NpcOpts) (*NpcAggro, error) {
+	if home == nil {
 		return nil, fmt.Errorf("Did not receive target")
 	}
 	base, err := NewNpc(asset, opts)
 	if err != nil {
 		return nil, err
 	}
-	npc := &NpcAggro{NpcEntity: base, target: target}
+	npc := &NpcAggro{NpcEntity: base, home: home, target: home, aggroRadius: defaultAggroRadius, fleeHealth: opts.FleeHealth}
 	npc.Shape().Body().SetVelocityUpdateFunc(npc.aggroMovementAI)
-	npc.waypointInfo = opts.WaypointInfo
-	npc.wayPoints = opts.WaypointInfo.waypoints
+	behaviour := opts.Behaviour
+	if behaviour == "" {
+		behaviour = defaultAiBehaviour
+	}
+	if npc.behaviour, err = AiBehaviour(behaviour); err != nil {
+		return nil, err
+	}
+	if opts.AggroRadius > 0 {
+		npc.aggroRadius = opts.AggroRadius
+	}
 
 	if npc.swingTimer, err = NewIngameTimeout(npc); err != nil {
 		return nil, err
@@ -51,183 +73,184 @@ func NewNpcAggro(target DefenderEntity, asset *CharacterAsset, opts NpcOpts) (*N
 	if npc.sfxTimeout, err = NewIngameTimeout(npc); err != nil {
 		return nil, err
 	}
+	if npc.fleeTimer, err = NewIngameTimeout(npc); err != nil {
+		return nil, err
+	}
 	return npc, nil
 }
 
-func (n *NpcAggro) IngameTime() float64 {
+func (n *NpcAggro) Rng() *rand.Rand {
 	u, ok := n.shape.Space().StaticBody.UserData.(SpaceUserData)
 	if !ok {
-		log.Println("Could not read ingame time")
-		return 0.0
+		log.Println("Could not read random generator")
+		return NewRandomGenerator(0)
+	}
+	return u.Rng()
+}
+
+func (n *NpcAggro) Snapshot() EntitySnapshot {
+	return EntitySnapshot{
+		Kind:      EntityKindNpc,
+		Asset:     n.asset.Name(),
+		Position:  n.shape.Body().Position(),
+		Velocity:  n.shape.Body().Velocity(),
+		Stats:     n.StatsSnapshot(),
+		Loot:      NewLootSnapshot(n.loot),
+		Attacking: n.attacking,
+		Fleeing:   n.fleeing,
+		Fled:      n.fled,
+		Facing:    n.facing,
+		Timers:    n.timersSnapshot(),
+		Effects:   n.effects.Snapshot(),
 	}
-	return u.IngameTime()
 }
 
-// Main decision tree for npc AI
+func (n *NpcAggro) timersSnapshot() map[string]TimerSnapshot {
+	res := map[string]TimerSnapshot{"swing": n.swingTimer.Snapshot(), "sfx": n.sfxTimeout.Snapshot(), "flee": n.fleeTimer.Snapshot()}
+	if n.gun != nil {
+		res["reload"] = n.gun.reloadTimeout.Snapshot()
+	}
+	return res
+}
+
+func (n *NpcAggro) Restore(s EntitySnapshot) error {
+	n.shape.Body().SetPosition(s.Position)
+	n.shape.Body().SetVelocityVector(s.Velocity)
+	n.RestoreStats(s.Stats)
+	n.loot = s.Loot.LootTable()
+	n.attacking = s.Attacking
+	n.fleeing = s.Fleeing
+	n.fled = s.Fled
+	n.facing = s.Facing
+	n.swingTimer.Restore(s.Timers["swing"])
+	n.sfxTimeout.Restore(s.Timers["sfx"])
+	n.fleeTimer.Restore(s.Timers["flee"])
+	if n.gun != nil {
+		n.gun.reloadTimeout.Restore(s.Timers["reload"])
+	}
+	return n.effects.Restore(s.Effects)
+}
+
+// Runs the behaviour tree as part of the physics step
 func (n *NpcAggro) aggroMovementAI(body *cp.Body, gravity cp.Vector, damping float64, dt float64) {
-	if n.attacking {
-		// Stop all movement
+	// Stunned npcs neither move nor swing
+	if n.effects.Stunned() {
 		body.SetVelocity(0, 0)
-		// Check if we need to play SFX
-		if n.sfxTimeout.Done() {
-			if err := n.playAtkSE(); err != nil {
-				log.Printf("Could not play npc atk sound effect: %s\n", err.Error())
-			}
-			// Disable timeout until next swing
-			n.sfxTimeout.Stop()
-		}
-		// Wait for next swing timer
-		if !n.swingTimer.Done() {
-			return
-		}
+		return
+	}
+	n.behaviour.Tick(&AiContext{Npc: n, Dt: dt})
+}
 
-		// Apply damage
-		u, ok := n.shape.Space().StaticBody.UserData.(SpaceUserData)
-		if !ok {
-			log.Println("ERROR: Could not apply damage: No acces to damage model via space user data possible")
-			return
-		}
-		_, err := u.damageModel.ApplyDamage(n, n.target, n.IngameTime())
-		if err != nil {
-			log.Println("Error during npc swing damage calc", err.Error())
-			return
-		}
+func (n *NpcAggro) playAtkSE() error {
+	return playSpaceSE(n.shape.Space(), "punch-npc", n.shape.Body().Position())
+}
 
-		// Queue up next swing, animation, sfx
+func (n *NpcAggro) SetNavigation(nav *Navigation) { n.navigation = nav }
+
+// Arms the npc for ranged behaviours
+func (n *NpcAggro) SetGun(gun *AimedGun) {
+	n.gun = gun
+	gun.SetShootingAnimationCallback(func(float64, Orientation) {
 		if err := n.asset.AnimationController().Play("attack"); err != nil {
-			log.Printf("Could not play attack animation: %s\n", err.Error())
+			log.Println("Could not play attack animation", err.Error())
 		}
-		n.sfxTimeout.Set(npcSwingSfxDelay)
-		n.swingTimer.Set(1 / n.AtkSpeed())
-		return
+	})
+}
+
+func (n *NpcAggro) Target() DefenderEntity { return n.target }
+func (n *NpcAggro) Fleeing() bool          { return n.fleeing }
+func (n *NpcAggro) Gun() Gun {
+	if n.gun == nil {
+		return nil
 	}
+	return n.gun
+}
 
-	// Once within range of target, we start attacking
-	if n.target.Shape().BB().Intersects(n.shape.BB()) {
-		body.SetVelocity(0, 0)
-		n.attacking = true
-		// Loop idle animation (in between swings, if animation allows)
-		if err := n.asset.AnimationController().Loop("idle"); err != nil {
-			log.Println("Could not play idle animation: %e", err.Error())
-		}
-		// Play first attack animation
-		if err := n.asset.AnimationController().Play("attack"); err != nil {
-			log.Println("Could not play attack animation: %e", err.Error())
-		}
-		// Set timer to apply swing damage
-		n.swingTimer.Set(1 / n.AtkSpeed())
-		n.sfxTimeout.Set(npcSwingSfxDelay)
-		return
+// Switching targets interrupts the current attack
+func (n *NpcAggro) setTarget(target DefenderEntity) {
+	if target != n.target {
+		n.target = target
+		n.attacking = false
 	}
+}
 
-	// Move towards target
-	dst := n.target.Shape().Body().Position()
-	nextReachableWaypoint, err := n.nextWaypointWithDijkstra(n.shape, dst)
-	if err != nil {
-		// log.Println("Could not find wp to go to", err.Error())
-		// Idle
-		body.SetVelocityVector(cp.Vector{})
-		if err := n.asset.AnimationController().Loop("idle"); err != nil {
-			log.Fatalln("Error looping", err.Error())
-		}
-		return
+// Nearest enemy in sight. Nil if there is none
+func (n *NpcAggro) perceive() DefenderEntity {
+	if n.aggroRadius <= 0 {
+		return nil
 	}
-	n.moveTowards(body, nextReachableWaypoint)
+	return PerceiveEnemy(n.shape.Space(), n.shape.Body().Position(), n.aggroRadius)
 }
 
-func (n *NpcAggro) playAtkSE() error {
-	if n.player == nil {
-		reader := bytes.NewReader(assets.PunchNpcOGG)
-		stream, err := vorbis.DecodeWithoutResampling(reader)
-		if err != nil {
-			return err
-		}
-		n.player, err = audio.CurrentContext().NewPlayer(stream)
-		if err != nil {
-			return err
-		}
-		n.player.SetVolume(SFX_VOLUME)
-	} else if err := n.player.Rewind(); err != nil {
-		return err
+// Next position on the way towards the target. False if the target cannot be reached.
+// Home is approached by the shared flow field, other targets by an A* path
+func (n *NpcAggro) nextPosition() (cp.Vector, bool) {
+	if n.navigation == nil {
+		return n.target.Shape().Body().Position(), true
 	}
-	n.player.Play()
-	return nil
+	pos := n.shape.Body().Position()
+	radius := AgentRadius(n.shape)
+	if n.target == n.home {
+		return n.navigation.FlowField(n.home, radius).NextPosition(pos)
+	}
+	if n.chase == nil || n.chase.target != n.target {
+		n.chase = n.navigation.PathFollower(n.target, radius)
+	}
+	return n.chase.NextPosition(pos)
 }
 
-// Utilize Dijkstra pathfinding algorithm to determine where to go
-// Returns a vector to the next node on the optimal path from src towards dst
-// Node distance is distance between wps & target pos
-func (n *NpcAggro) nextWaypointWithDijkstra(srcShape *cp.Shape, dst cp.Vector) (cp.Vector, error) {
-	// Early exit: If we can see the dst vector, we dont need dijkstra
-	_, visible := calcVisibleDistanceUsingBB(n.shape.Space(), srcShape, dst)
-	if visible {
-		return dst, nil
-	}
-
-	dynamicGraph := n.waypointInfo.graph
-	wayPoints := n.waypointInfo.waypoints
-	DST_NODE_IDX := len(wayPoints)
-	SRC_NODE_IDX := len(wayPoints) + 1
-
-	// Add player node & calculate arcs to all waypoints nodes
-	// FIX: Optimize to calculate only once per tick
-	// FIX: Should also use BB query here (calcVisibleDistanceUsingBB)
-	dynamicGraph.AddEmptyVertex(DST_NODE_IDX)
-	for toIdx, toWp := range wayPoints {
-		dist, visible := calcVisibleDistance(n.shape.Space(), dst, toWp)
-		// Ignore non-visible / path blocked nodes
-		if !visible {
-			continue
+// True if in contact with the target
+func (n *NpcAggro) inMeleeReach() bool {
+	if n.target == nil {
+		return false
+	}
+	bb := n.target.Shape().BB()
+	bb = cp.BB{L: bb.L - aiMeleeReach, B: bb.B - aiMeleeReach, R: bb.R + aiMeleeReach, T: bb.T + aiMeleeReach}
+	return bb.Intersects(n.shape.BB())
+}
+
+// Npcs within the ability radius that are alive but hurt. Includes itself
+func (n *NpcAggro) hurtAllies() []*NpcEntity {
+	res := []*NpcEntity{}
+	pos := n.shape.Body().Position()
+	n.shape.Space().BBQuery(cp.NewBBForCircle(pos, n.abilityRadius), steeringNeighbourFilter, func(shape *cp.Shape, _ interface{}) {
+		ally, ok := shape.Body().UserData.(*NpcEntity)
+		if !ok || ally.Health() <= 0 || ally.Health() >= ally.MaxHealth() {
+			return
 		}
-		// Add bidirectional arc
-		dynamicGraph.AddArc(DST_NODE_IDX, toIdx, dist)
-		dynamicGraph.AddArc(toIdx, DST_NODE_IDX, dist)
-	}
-
-	// Add npc node & calculate BIDIRECTIONAL arcs to all wp nodes
-	// NOTE: Do not need to calculate arcs to target, because that was already covered in early exit
-	// NOTE: Need to consider bounding box dimensions here
-	dynamicGraph.AddEmptyVertex(SRC_NODE_IDX)
-	visibleWaypoints := 0
-	for toIdx, toWp := range wayPoints {
-		// Define bounding box edges. Only if path from all edges is clear, wp will be selected
-		dist, visible := calcVisibleDistanceUsingBB(n.shape.Space(), srcShape, toWp)
-		// Ignore non-visible / path blocked nodes
-		if !visible {
-			continue
+		if shape.PointQuery(pos).Distance <= n.abilityRadius {
+			res = append(res, ally)
 		}
-		// Add bidirectional arc
-		dynamicGraph.AddArc(SRC_NODE_IDX, toIdx, dist)
-		dynamicGraph.AddArc(toIdx, SRC_NODE_IDX, dist)
-		visibleWaypoints++
+	}, nil)
+	return res
+}
+
+// Damage all enemies within the ability radius & die without dropping loot
+func (n *NpcAggro) explode() error {
+	u, ok := n.shape.Space().StaticBody.UserData.(SpaceUserData)
+	if !ok {
+		return fmt.Errorf("No access to damage model via space user data")
 	}
-	// If we cannot see any way points (and we cannot see the player which we checked earlier)
-	// We're going to have a problem. Dijkstra cannot help us here either
-	// Current solution is to perform some random movements, hoping that will unstuck the entity
-	if visibleWaypoints == 0 {
-		log.Println("NPC stuck! Trying to unstuck with random movement")
-		return srcShape.Body().Position().Add(cp.Vector{X: rand.Float64()*10 - 5, Y: rand.Float64()*10 - 5}), nil
+	pos := n.shape.Body().Position()
+	victims := []DefenderEntity{}
+	n.shape.Space().BBQuery(cp.NewBBForCircle(pos, n.abilityRadius), aiEnemyFilter, func(shape *cp.Shape, _ interface{}) {
+		victim, ok := shape.Body().UserData.(DefenderEntity)
+		if !ok {
+			return
+		}
+		if inside, ok := victim.(interface{ Inside() bool }); ok && inside.Inside() {
+			return
+		}
+		if shape.PointQuery(pos).Distance <= n.abilityRadius {
+			victims = append(victims, victim)
+		}
+	}, nil)
+	for _, victim := range victims {
+		if _, err := u.applyDamage(n, victim); err != nil {
+			return err
+		}
 	}
-
-	// Apply dijkstra to get path
-	bestPath, err := dynamicGraph.Shortest(SRC_NODE_IDX, DST_NODE_IDX)
-	if err != nil {
-		// Add some debugging information
-		// str, strErr := dynamicGraph.Export()
-		// if strErr != nil {
-		// 	return cp.Vector{}, strErr
-		// }
-		// _ = str
-		// log.Println("Graph data\n", str)
-		log.Println("Cannot find shortest path. Trying to unstuck with random movement")
-		return srcShape.Body().Position().Add(cp.Vector{X: rand.Float64()*10 - 5, Y: rand.Float64()*10 - 5}), nil
-	}
-	secondNodeInPathIdx := bestPath.Path[1]
-	// First node is always SRC
-	// We know node cannot be dst, because we already checked that in the early exit cond
-	// Safe to assume that its a waypoint. Checking anyways for good measure
-	if secondNodeInPathIdx > len(wayPoints)-1 {
-		return cp.Vector{}, fmt.Errorf("Waypoints out of bounds. Received idx %d, but only know %d waypoints", secondNodeInPathIdx, len(wayPoints))
-	}
-	return wayPoints[secondNodeInPathIdx], nil
+	// Resolved by the world like any other death
+	u.kill(n, n, false)
+	return nil
 }
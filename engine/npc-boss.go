@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// Stage of a boss fight. Entered once health drops below the threshold
+type BossPhase struct {
+	// Fraction of max health at which the phase starts. Ignored for the first phase
+	Health float64
+	// Name of a registered ai behaviour used during this phase
+	Behaviour string
+	// Minions summoned every SummonInterval seconds, starting when the phase is entered. 0 = none
+	Minions        int
+	SummonInterval float64
+	// Applied when entering the phase, e.g. to enrage
+	Modifiers []StatModifier
+}
+
+type BossOpts struct {
+	NpcOpts
+	// Displayed in the hud
+	Name   string
+	Phases []BossPhase
+}
+
+// Spawns minions around the boss, e.g. via the creep provider of the active wave
+type MinionSummoner func(boss *NpcBoss, count int) error
+
+// Aggressive npc that switches behaviours at health thresholds & summons minions
+type NpcBoss struct {
+	*NpcAggro
+
+	name       string
+	phases     []BossPhase
+	behaviours []AiNode
+	phase      int
+
+	summonTimer Timeout
+	// Minions are not summoned if nil
+	summoner MinionSummoner
+}
+
+func NewNpcBoss(home DefenderEntity, asset *CharacterAsset, opts BossOpts) (*NpcBoss, error) {
+	if len(opts.Phases) == 0 {
+		return nil, fmt.Errorf("Cannot init boss without phases")
+	}
+	if !slices.IsSortedFunc(opts.Phases[1:], func(a, b BossPhase) int { return cmp.Compare(b.Health, a.Health) }) {
+		return nil, fmt.Errorf("Boss phases need to be sorted by descending health")
+	}
+	behaviours := make([]AiNode, len(opts.Phases))
+	for idx, phase := range opts.Phases {
+		behaviour, err := AiBehaviour(phase.Behaviour)
+		if err != nil {
+			return nil, err
+		}
+		behaviours[idx] = behaviour
+	}
+	opts.Behaviour = opts.Phases[0].Behaviour
+	base, err := NewNpcAggro(home, asset, opts.NpcOpts)
+	if err != nil {
+		return nil, err
+	}
+	boss := &NpcBoss{NpcAggro: base, name: opts.Name, phases: opts.Phases, behaviours: behaviours}
+	if boss.summonTimer, err = NewIngameTimeout(boss); err != nil {
+		return nil, err
+	}
+	boss.applyPhaseModifiers(0)
+	return boss, nil
+}
+
+func (n *NpcBoss) Name() string                        { return n.name }
+func (n *NpcBoss) Phase() int                          { return n.phase }
+func (n *NpcBoss) SetSummoner(summoner MinionSummoner) { n.summoner = summoner }
+
+func (n *NpcBoss) Snapshot() EntitySnapshot {
+	s := n.NpcAggro.Snapshot()
+	s.Kind = EntityKindBoss
+	s.Phase = n.phase
+	s.Timers["summon"] = n.summonTimer.Snapshot()
+	return s
+}
+
+// Phase modifiers are part of the restored stats
+func (n *NpcBoss) Restore(s EntitySnapshot) error {
+	if s.Phase < 0 || s.Phase >= len(n.phases) {
+		return fmt.Errorf("Invalid boss phase %d", s.Phase)
+	}
+	if err := n.NpcAggro.Restore(s); err != nil {
+		return err
+	}
+	n.phase = s.Phase
+	n.behaviour = n.behaviours[n.phase]
+	n.summonTimer.Restore(s.Timers["summon"])
+	return nil
+}
+
+// Advance phases & summon minions. Needs to run outside of the physics step,
+// since minions are added to the space. The behaviour of the active phase runs during the step
+func (n *NpcBoss) Update() {
+	if n.Health() <= 0 {
+		return
+	}
+	n.updatePhase()
+	n.updateSummons()
+}
+
+// Enter all phases whose threshold has been crossed
+func (n *NpcBoss) updatePhase() {
+	for n.phase+1 < len(n.phases) && n.Health() < n.phases[n.phase+1].Health*n.MaxHealth() {
+		n.phase++
+		n.behaviour = n.behaviours[n.phase]
+		n.attacking = false
+		n.applyPhaseModifiers(n.phase)
+		// Summon right away
+		n.summonTimer.Set(0)
+		log.Printf("Boss %s entered phase %d\n", n.name, n.phase+1)
+	}
+}
+
+func (n *NpcBoss) applyPhaseModifiers(idx int) {
+	source := StatSource{Kind: StatSourcePhase, Name: fmt.Sprintf("%s phase %d", n.name, idx+1)}
+	for _, m := range n.phases[idx].Modifiers {
+		m.Source = source
+		n.AddModifier(m)
+	}
+}
+
+func (n *NpcBoss) updateSummons() {
+	phase := n.phases[n.phase]
+	if n.summoner == nil || phase.Minions <= 0 || phase.SummonInterval <= 0 || !n.summonTimer.Done() {
+		return
+	}
+	if err := n.summoner(n, phase.Minions); err != nil {
+		log.Println("Could not summon minions", err.Error())
+	}
+	n.summonTimer.Set(phase.SummonInterval)
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+func newBossTestBoss(t *testing.T, w *GameWorld, home DefenderEntity, phases []BossPhase) *NpcBoss {
+	asset, err := w.AssetManager.CharacterAsset("npc-orc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := BossOpts{NpcOpts: NpcOpts{BaseHealth: 100, StartingPos: cp.Vector{X: 200, Y: 500}, Size: 64}, Name: "Test boss", Phases: phases}
+	boss, err := NewNpcBoss(home, asset, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return boss
+}
+
+func TestBossPhases(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	home := newAiTestEnemy(t, w, cp.Vector{X: 900, Y: 500}, TowerCollisionFilter())
+	boss := newBossTestBoss(t, w, home, []BossPhase{
+		{Behaviour: "melee"},
+		{Health: 0.5, Behaviour: "ranged", Minions: 2, SummonInterval: 5, Modifiers: []StatModifier{
+			{Stat: StatMovementSpeed, Op: ModifierMultiply, Value: 2},
+		}},
+	})
+	summoned := 0
+	boss.SetSummoner(func(_ *NpcBoss, count int) error {
+		summoned += count
+		return nil
+	})
+	if err := w.AddEntity(boss); err != nil {
+		t.Fatal(err)
+	}
+	speed := boss.MovementSpeed()
+	boss.Update()
+	w.Update()
+	if boss.Phase() != 0 || summoned != 0 {
+		t.Errorf("Expected first phase without minions, got phase %d & %d minions", boss.Phase(), summoned)
+	}
+
+	boss.SetHealth(40)
+	boss.Update()
+	w.Update()
+	if boss.Phase() != 1 {
+		t.Fatalf("Expected boss to enter second phase, got %d", boss.Phase())
+	}
+	if summoned != 2 {
+		t.Errorf("Expected 2 minions on entering the phase, got %d", summoned)
+	}
+	if boss.MovementSpeed() != 2*speed {
+		t.Errorf("Expected phase modifiers to apply, got speed %f", boss.MovementSpeed())
+	}
+	boss.Update()
+	w.Update()
+	if summoned != 2 {
+		t.Errorf("Expected boss to wait for summon interval, got %d minions", summoned)
+	}
+
+	// Phase is persisted
+	restored := newBossTestBoss(t, w, home, boss.phases)
+	if err := w.AddEntity(restored); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Restore(boss.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Phase() != 1 || restored.MovementSpeed() != boss.MovementSpeed() {
+		t.Errorf("Expected restored boss in second phase, got %d", restored.Phase())
+	}
+}
+
+func TestBossPhasesNeedDescendingHealth(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	asset, err := w.AssetManager.CharacterAsset("npc-orc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := newAiTestEnemy(t, w, cp.Vector{X: 900, Y: 500}, TowerCollisionFilter())
+	phases := []BossPhase{{Behaviour: "melee"}, {Health: 0.3, Behaviour: "melee"}, {Health: 0.6, Behaviour: "melee"}}
+	if _, err := NewNpcBoss(home, asset, BossOpts{Phases: phases}); err == nil {
+		t.Error("Expected error for unsorted phases")
+	}
+}
+
+// Spawns orcs & a single phase boss
+type bossTestProvider struct {
+	w    *GameWorld
+	home DefenderEntity
+}
+
+func (p *bossTestProvider) NextNpc(wave Wave) (GameEntity, error) {
+	asset, err := p.w.AssetManager.CharacterAsset("npc-orc")
+	if err != nil {
+		return nil, err
+	}
+	return NewNpcAggro(p.home, asset, NpcOpts{StartingPos: cp.Vector{X: 100, Y: 100}})
+}
+
+func (p *bossTestProvider) NextBoss(wave Wave) (*NpcBoss, error) {
+	asset, err := p.w.AssetManager.CharacterAsset("npc-orc")
+	if err != nil {
+		return nil, err
+	}
+	return NewNpcBoss(p.home, asset, BossOpts{NpcOpts: NpcOpts{StartingPos: cp.Vector{X: 500, Y: 100}}, Name: "Test boss", Phases: []BossPhase{{Behaviour: "melee"}}})
+}
+
+func TestBossWave(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	home := newAiTestEnemy(t, w, cp.Vector{X: 900, Y: 900}, TowerCollisionFilter())
+	cm, err := NewBaseCreepManager(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.SetBossInterval(2)
+	if err := cm.SetProvider(&bossTestProvider{w, home}); err != nil {
+		t.Fatal(err)
+	}
+	// Skip idle time & spawn delay
+	cm.spawnIdleTimeout.Set(0)
+	cm.creepSpawnTimeout.Set(0)
+	if err := cm.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if cm.boss != nil || cm.BossProgress().Max != 0 {
+		t.Fatal("Expected no boss in first wave")
+	}
+
+	if err := cm.NextWave(); err != nil {
+		t.Fatal(err)
+	}
+	cm.creepSpawnTimeout.Set(0)
+	if err := cm.Update(); err != nil {
+		t.Fatal(err)
+	}
+	boss := cm.boss
+	if boss == nil {
+		t.Fatal("Expected boss in second wave")
+	}
+	if progress := cm.BossProgress(); progress.Max == 0 || !strings.HasPrefix(progress.Label, "Test boss") {
+		t.Errorf("Expected boss health bar, got %v", progress)
+	}
+	// Minions count towards the wave
+	alive := cm.creepsAlive
+	if err := cm.summonMinions(boss, 3); err != nil {
+		t.Fatal(err)
+	}
+	if cm.creepsAlive != alive+3 {
+		t.Errorf("Expected 3 more creeps alive, got %d", cm.creepsAlive-alive)
+	}
+
+	if err := boss.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	w.Update()
+	if cm.BossProgress().Max != 0 {
+		t.Error("Expected boss health bar to disappear once the boss is gone")
+	}
+}
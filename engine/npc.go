@@ -2,6 +2,7 @@ package engine
 
 import (
 	"log"
+	"math"
 
 	"github.com/jakecoffman/cp"
 	"github.com/lucb31/game-engine-go/engine/loot"
@@ -12,19 +13,27 @@ type NpcEntity struct {
 
 	// Logic
 	GameEntityStats
-	loot loot.LootTable
+	loot    loot.LootTable
+	effects *StatusEffects
 
 	// Rendering
 	asset       *CharacterAsset
 	orientation Orientation
+	// Direction of the last movement or attack. Shields block projectiles from this side
+	facing cp.Vector
 
 	// Physics
 	shape *cp.Shape
 
 	// Movement AI
+	steering       SteeringOpts
 	wayPoints      []cp.Vector
 	currentWpIndex int
 	loopWaypoints  bool
+
+	// Abilities
+	abilityRadius float64
+	shieldAngle   float64
 }
 
 type NpcOpts struct {
@@ -34,11 +43,36 @@ type NpcOpts struct {
 	BaseHealth        float64
 	BaseMovementSpeed float64
 	GoldValue         int64
-	Waypoints         []cp.Vector
-	// TODO: Deprecate Waypoints
-	WaypointInfo
+	// Takes precedence over gold value
+	LootTable loot.LootTable
+	Waypoints []cp.Vector
+	// Start over after the last waypoint
+	LoopWaypoints bool
+	// Defaults if nil
+	Steering *SteeringOpts
+	// Name of a registered ai behaviour of aggressive npcs. Defaults to melee
+	Behaviour string
+	// Enemies within this distance are attacked instead of the home target
+	AggroRadius float64
+	// Fraction of max health below which npcs flee. 0 = never
+	FleeHealth float64
+	// Range of area abilities, e.g. explosions & heals. Defaults if 0
+	AbilityRadius float64
+	// Projectiles within this angle (degrees) around the facing direction are blocked. 0 = no shield
+	ShieldAngle float64
+	// Edge length of the collision box. Defaults if 0
+	Size float64
 }
 
+const (
+	// Waypoints closer than this count as reached
+	npcWaypointReach = 8.0
+	// Range of area abilities, if not configured
+	defaultAbilityRadius = 60.0
+	// Edge length of the collision box, if not configured
+	defaultNpcSize = 32.0
+)
+
 func NpcCollisionFilter() cp.ShapeFilter {
 	return cp.NewShapeFilter(0, NpcCategory, PlayerCategory|OuterWallsCategory|HarvestableCategory|TowerCategory|ProjectileCategory)
 }
@@ -56,7 +90,11 @@ func NewNpc(asset *CharacterAsset, opts NpcOpts) (*NpcEntity, error) {
 	body.UserData = npc
 
 	// Collision model
-	npc.shape = cp.NewBox(body, 32, 32, 0)
+	size := defaultNpcSize
+	if opts.Size > 0 {
+		size = opts.Size
+	}
+	npc.shape = cp.NewBox(body, size, size, 0)
 	npc.shape.SetElasticity(0)
 	npc.shape.SetFriction(1)
 	npc.shape.SetCollisionType(cp.CollisionType(NpcCollision))
@@ -70,9 +108,13 @@ func NewNpc(asset *CharacterAsset, opts NpcOpts) (*NpcEntity, error) {
 	npc.movementSpeed = 75.0
 	npc.power = 20.0
 	npc.loot = loot.NewEmptyLootTable()
+	if npc.effects, err = NewStatusEffects(npc, npc); err != nil {
+		return nil, err
+	}
 
 	// AI
-	npc.loopWaypoints = false
+	npc.steering = DefaultSteeringOpts()
+	npc.abilityRadius = defaultAbilityRadius
 
 	// Parse opts
 	if opts.BaseArmor > 0 {
@@ -90,28 +132,84 @@ func NewNpc(asset *CharacterAsset, opts NpcOpts) (*NpcEntity, error) {
 	if opts.GoldValue > 0 {
 		npc.loot = loot.NewGoldLootTable(opts.GoldValue)
 	}
+	if opts.LootTable != nil {
+		npc.loot = opts.LootTable
+	}
 	if opts.StartingPos.Length() > 0 {
 		body.SetPosition(opts.StartingPos)
 	}
 	if len(opts.Waypoints) > 0 {
 		npc.wayPoints = opts.Waypoints
 	}
+	npc.loopWaypoints = opts.LoopWaypoints
+	if opts.Steering != nil {
+		npc.steering = *opts.Steering
+	}
+	if opts.AbilityRadius > 0 {
+		npc.abilityRadius = opts.AbilityRadius
+	}
+	npc.shieldAngle = opts.ShieldAngle
 
 	npc.health = npc.maxHealth
 	return npc, nil
 }
 
 func (n *NpcEntity) Draw(t RenderingTarget) error {
-	n.asset.DrawHealthbar(t, n.shape, n.health, n.maxHealth)
+	n.asset.DrawHealthbar(t, n.shape, n.health, n.MaxHealth())
+	n.effects.Draw(t, n.shape)
 	return n.asset.Draw(t, n.shape, n.orientation)
 }
 
-func (n *NpcEntity) Shape() *cp.Shape          { return n.shape }
-func (n *NpcEntity) Body() *cp.Body            { return n.shape.Body() }
-func (n *NpcEntity) IsVulnerable() bool        { return true }
-func (n *NpcEntity) LootTable() loot.LootTable { return n.loot }
+func (n *NpcEntity) Shape() *cp.Shape              { return n.shape }
+func (n *NpcEntity) Body() *cp.Body                { return n.shape.Body() }
+func (n *NpcEntity) IsVulnerable() bool            { return true }
+func (n *NpcEntity) LootTable() loot.LootTable     { return n.loot }
+func (n *NpcEntity) SetLootTable(t loot.LootTable) { n.loot = t }
+func (n *NpcEntity) StatusEffects() *StatusEffects {
+	return n.effects
+}
+func (n *NpcEntity) Asset() *CharacterAsset { return n.asset }
+func (n *NpcEntity) Facing() cp.Vector      { return n.facing }
+
+// Turn towards direction. Keeps facing if direction is zero
+func (n *NpcEntity) face(direction cp.Vector) {
+	if direction.LengthSq() == 0 {
+		return
+	}
+	n.facing = direction.Normalize()
+	n.orientation = updateOrientation(n.orientation, direction)
+}
+
+// Shielded npcs block projectiles coming in from the front
+func (n *NpcEntity) BlocksProjectile(p *Projectile) bool {
+	if n.shieldAngle <= 0 || n.facing.LengthSq() == 0 {
+		return false
+	}
+	incoming := p.Shape().Body().Velocity().Neg()
+	if incoming.LengthSq() == 0 {
+		incoming = p.Shape().Body().Position().Sub(n.shape.Body().Position())
+	}
+	if incoming.LengthSq() == 0 {
+		return false
+	}
+	angle := math.Abs(math.Remainder(incoming.ToAngle()-n.facing.ToAngle(), 2*math.Pi))
+	return angle <= n.shieldAngle*math.Pi/180
+}
+
+func (n *NpcEntity) IngameTime() float64 {
+	u, ok := n.shape.Space().StaticBody.UserData.(SpaceUserData)
+	if !ok {
+		log.Println("Could not read ingame time")
+		return 0.0
+	}
+	return u.IngameTime()
+}
 
 func (n *NpcEntity) defaultMovementAI(body *cp.Body, gravity cp.Vector, damping float64, dt float64) {
+	if n.effects.Stunned() {
+		body.SetVelocityVector(cp.Vector{})
+		return
+	}
 	n.simpleWaypointAlgorithm(body, dt)
 }
 
@@ -127,11 +225,11 @@ func (n *NpcEntity) simpleWaypointAlgorithm(body *cp.Body, dt float64) {
 		return
 	}
 	destination := n.wayPoints[n.currentWpIndex]
-	distance := n.moveTowards(body, destination)
+	distance := n.moveTowards(body, destination, nil, dt)
 
 	// Go to next waypoint if in close proximity to current WP
-	// ~Distance covered within next timestep
-	dx := n.movementSpeed * dt
+	// ~Distance covered within next timestep. Steering may not hit the exact spot
+	dx := max(n.MovementSpeed()*dt, npcWaypointReach)
 	if distance < dx {
 		n.currentWpIndex++
 		if n.currentWpIndex > len(n.wayPoints)-1 {
@@ -147,21 +245,17 @@ func (n *NpcEntity) simpleWaypointAlgorithm(body *cp.Body, dt float64) {
 
 }
 
-// Updates body velocity to move towards destination
+// Updates body velocity to steer towards destination. See steer for goal
 // Returns remaining distance
-func (n *NpcEntity) moveTowards(body *cp.Body, dest cp.Vector) float64 {
-	position := body.Position()
-	diff := dest.Sub(position)
-	diffNormalized := diff.Normalize()
-
-	vel := diffNormalized.Mult(n.movementSpeed)
+func (n *NpcEntity) moveTowards(body *cp.Body, dest cp.Vector, goal *cp.Shape, dt float64) float64 {
+	vel := n.steer(body, dest, goal, dt)
 	body.SetVelocityVector(vel)
 	// Update active animation & orientation
-	n.orientation = updateOrientation(n.orientation, vel)
+	n.face(vel)
 	if err := n.asset.AnimationController().Loop("walk"); err != nil {
 		log.Println("error looping", err.Error())
 	}
-	return diff.Length()
+	return dest.Distance(body.Position())
 }
 
 // DEBUG: Draw connecting lines between npcs & waypoints
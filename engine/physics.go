@@ -3,6 +3,7 @@ package engine
 import (
 	"image/color"
 	"log"
+	"math/rand/v2"
 
 	"github.com/jakecoffman/cp"
 	"github.com/lucb31/game-engine-go/engine/damage"
@@ -26,22 +27,35 @@ const (
 	ItemCategory        uint = 1 << iota
 )
 
-// Pass damage model and in game timer to shapes
+// Pass damage model, in game timer and random generator to shapes
 type SpaceUserData struct {
-	damageModel damage.DamageModel
-	gameTime    *float64
+	damage   DamageApplier
+	gameTime *float64
+	rng      *rand.Rand
+	audio    *AudioManager
+	events   *EventBus
 }
 
 func (s *SpaceUserData) IngameTime() float64 {
 	return *s.gameTime
 }
 
-func NewPhysicsSpace(damageModel damage.DamageModel, gameTime *float64) (*cp.Space, error) {
+func (s *SpaceUserData) Rng() *rand.Rand { return s.rng }
+
+func (s *SpaceUserData) applyDamage(atk damage.Attacker, def damage.Defender) (*damage.DamageRecord, error) {
+	return s.damage.ApplyDamage(atk, def)
+}
+
+func (s *SpaceUserData) kill(def damage.Defender, killer GameEntity, withLoot bool) {
+	s.damage.Kill(def, killer, withLoot)
+}
+
+func NewPhysicsSpace(damageApplier DamageApplier, gameTime *float64, rng *rand.Rand, audio *AudioManager, events *EventBus) (*cp.Space, error) {
 	// Initialize physics
 	space := cp.NewSpace()
 	// Assign references to IGT & damage model to physical space to make
 	// them available within every entity
-	userData := SpaceUserData{damageModel, gameTime}
+	userData := SpaceUserData{damageApplier, gameTime, rng, audio, events}
 	space.StaticBody.UserData = userData
 	// NOTE: As long as we're not utilizing collision solvers, we dont need any iterations
 	// Therefore setting to min value: 1
@@ -51,6 +65,12 @@ func NewPhysicsSpace(damageModel damage.DamageModel, gameTime *float64) (*cp.Spa
 	// TODO: Check if we can replace by static body user data
 	handler.UserData = userData
 	handler.BeginFunc = projectileCollisionHandler
+	// Hostile projectiles fired by npcs
+	for _, defender := range []cp.CollisionType{PlayerCollision, CastleCollision} {
+		hostileHandler := space.NewCollisionHandler(cp.CollisionType(ProjectileCollision), defender)
+		hostileHandler.UserData = userData
+		hostileHandler.BeginFunc = projectileCollisionHandler
+	}
 
 	// Disable all collision with items
 	itemHandler := space.NewWildcardCollisionHandler(ItemCollision)
@@ -62,11 +82,11 @@ func NewPhysicsSpace(damageModel damage.DamageModel, gameTime *float64) (*cp.Spa
 }
 
 func TowerCollisionFilter() cp.ShapeFilter {
-	return cp.NewShapeFilter(0, TowerCategory, PlayerCategory|NpcCategory|OuterWallsCategory|HarvestableCategory|TowerCategory)
+	return cp.NewShapeFilter(0, TowerCategory, PlayerCategory|NpcCategory|OuterWallsCategory|HarvestableCategory|TowerCategory|ProjectileCategory)
 }
 
 func PlayerCollisionFilter() cp.ShapeFilter {
-	return cp.NewShapeFilter(0, PlayerCategory, PlayerCategory|NpcCategory|OuterWallsCategory|TowerCategory|HarvestableCategory)
+	return cp.NewShapeFilter(0, PlayerCategory, PlayerCategory|NpcCategory|OuterWallsCategory|TowerCategory|HarvestableCategory|ProjectileCategory)
 }
 
 func removeProjectile(arb *cp.Arbiter, space *cp.Space, userData interface{}) {
@@ -82,7 +102,6 @@ func removeProjectile(arb *cp.Arbiter, space *cp.Space, userData interface{}) {
 	if ok && collisionPartner.Id() == projectile.gun.Owner().Id() {
 		return
 	}
-	log.Printf("Removing projectile after collision with %v \n", b.UserData)
 	projectile.Destroy()
 }
 
@@ -99,6 +118,16 @@ func projectileCollisionHandler(arb *cp.Arbiter, space *cp.Space, userData inter
 		log.Println("Type assertion for projectile collision failed. Did not receive valid Damage defender", b.UserData)
 		return false
 	}
+	if !projectile.CanHit(b) {
+		return false
+	}
+	// Shields absorb the projectile without taking damage
+	if blocker, ok := b.UserData.(ProjectileBlocker); ok && blocker.BlocksProjectile(projectile) {
+		if err := projectile.Destroy(); err != nil {
+			log.Println("Could not remove blocked projectile", err.Error())
+		}
+		return false
+	}
 
 	// Read damage model from userData
 	handlerData, ok := userData.(SpaceUserData)
@@ -106,28 +135,22 @@ func projectileCollisionHandler(arb *cp.Arbiter, space *cp.Space, userData inter
 		log.Println("Could not read damage model")
 		return false
 	}
-	damageModel := handlerData.damageModel
-	// Calculate & apply damage with COPY of projectile
-	damageResult, err := damageModel.ApplyDamage(projectile, defender, *handlerData.gameTime)
+	// Calculate & apply damage. Kill & loot are resolved by the world
+	rec, err := handlerData.applyDamage(projectile, defender)
 	if err != nil {
 		log.Println("Could not apply damage", err.Error())
 	}
-
-	// Check if we need to distribute loot
-	lootReceiver, isLootReceiver := projectile.gun.Owner().(GameEntityWithInventory)
-	if damageResult != nil && damageResult.Fatal && isLootReceiver {
-		defenderEntity, isGameEntity := defender.(GameEntity)
-		if !isGameEntity {
-			log.Println("ERROR: Expected game entity for defender")
-			return false
-		}
-		if err = lootReceiver.Inventory().AddLoot(defenderEntity.LootTable()); err != nil {
-			log.Println("Error while adding loot: ", err.Error())
+	// Apply on hit effects to survivors
+	if carrier, ok := defender.(StatusEffectCarrier); ok && rec != nil && !rec.Fatal {
+		for _, effect := range projectile.gun.OnHitEffects() {
+			if err := carrier.StatusEffects().Apply(effect, projectile.AttackOwner()); err != nil {
+				log.Println("Could not apply status effect", err.Error())
+			}
 		}
 	}
 
 	// Remove projectile
-	if err := projectile.OnHit(); err != nil {
+	if err := projectile.OnHit(b); err != nil {
 		log.Println("Error during projectile on hit handler: %e", err.Error())
 		return false
 	}
@@ -4,8 +4,6 @@ import (
 	"log"
 	"math"
 
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/jakecoffman/cp"
 )
 
@@ -16,12 +14,15 @@ type PlayerController interface {
 	// True WHILE interaction is ongoing
 	Interacting() bool
 	SetInteracting(bool)
+	// Replace input source, e.g. to record or replay inputs
+	SetInputSource(InputSource)
 	Update()
 }
 
 type KeyboardPlayerController struct {
 	// Dependencies
 	animationController AnimationController
+	input               InputSource
 
 	// General movement
 	movingEastTimer  Timer
@@ -40,7 +41,7 @@ type KeyboardPlayerController struct {
 }
 
 func NewKeyboardPlayerController(ac AnimationController, igt IngameTimeProvider) (*KeyboardPlayerController, error) {
-	c := &KeyboardPlayerController{}
+	c := &KeyboardPlayerController{input: NewKeyboardInputSource()}
 	c.animationController = ac
 	var err error
 	if c.movingEastTimer, err = NewIngameTimer(igt); err != nil {
@@ -76,26 +77,27 @@ const (
 )
 
 func (c *KeyboardPlayerController) Update() {
+	c.input.Update()
 	// Reading movement inputs
-	if ebiten.IsKeyPressed(ebiten.KeyW) {
+	if c.input.Pressed(InputMoveNorth) {
 		c.movingSouthTimer.Stop()
 		c.movingNorthTimer.Start()
 	} else {
 		c.movingNorthTimer.Stop()
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyS) {
+	if c.input.Pressed(InputMoveSouth) {
 		c.movingNorthTimer.Stop()
 		c.movingSouthTimer.Start()
 	} else {
 		c.movingSouthTimer.Stop()
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyD) {
+	if c.input.Pressed(InputMoveEast) {
 		c.movingWestTimer.Stop()
 		c.movingEastTimer.Start()
 	} else {
 		c.movingEastTimer.Stop()
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyA) {
+	if c.input.Pressed(InputMoveWest) {
 		c.movingEastTimer.Stop()
 		c.movingWestTimer.Start()
 	} else {
@@ -103,10 +105,10 @@ func (c *KeyboardPlayerController) Update() {
 	}
 
 	// Reading interaction inputs
-	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+	if c.input.JustPressed(InputInteract) {
 		c.interacting = true
 	}
-	if inpututil.IsKeyJustReleased(ebiten.KeyE) {
+	if c.input.JustReleased(InputInteract) {
 		c.interacting = false
 	}
 }
@@ -139,11 +141,12 @@ func (c *KeyboardPlayerController) Interacting() bool {
 }
 func (c *KeyboardPlayerController) SetInteracting(val bool) { c.interacting = false }
 
-func (c *KeyboardPlayerController) Orientation() Orientation { return c.orientation }
+func (c *KeyboardPlayerController) Orientation() Orientation         { return c.orientation }
+func (c *KeyboardPlayerController) SetInputSource(input InputSource) { c.input = input }
 
 func (c *KeyboardPlayerController) calcVelFromDash(vel cp.Vector) cp.Vector {
 	// Register new dashes
-	if c.dashCooldownTimeout.Done() && inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+	if c.dashCooldownTimeout.Done() && c.input.JustPressed(InputDash) {
 		log.Println("New dash queued")
 		// While moving, dash in direction of movement
 		// While standing still, dash in direction of last horizontal movement
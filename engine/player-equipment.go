@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+func (p *Player) Bag() *loot.Bag        { return p.inventory.Bag() }
+func (p *Player) Equipment() *Equipment { return p.equipment }
+func (p *Player) EquippedItem(slot loot.EquipmentSlot) *loot.ItemInstance {
+	return p.equipment.Slot(slot)
+}
+
+// Equips the bag item. A previously equipped item takes its place in the bag
+func (p *Player) EquipFromBag(bagIdx int) error {
+	instance := p.Bag().Slot(bagIdx)
+	if instance == nil {
+		return fmt.Errorf("Nothing to equip in bag slot %d", bagIdx)
+	}
+	if instance.Item.Slot == "" {
+		return fmt.Errorf("Cannot equip %s", instance)
+	}
+	p.Bag().Take(bagIdx)
+	previous, err := p.equipment.Equip(instance)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		if _, err := p.Bag().Put(bagIdx, previous); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Moves the equipped item into the bag slot. Items fitting the equipment slot are swapped
+func (p *Player) UnequipToBag(slot loot.EquipmentSlot, bagIdx int) error {
+	if p.equipment.Slot(slot) == nil {
+		return fmt.Errorf("Nothing equipped in %s slot", slot)
+	}
+	if bagIdx < 0 || bagIdx >= p.Bag().Size() {
+		return fmt.Errorf("Invalid bag slot %d", bagIdx)
+	}
+	target := p.Bag().Slot(bagIdx)
+	if target != nil {
+		if target.Item.Slot != slot {
+			return fmt.Errorf("Cannot swap %s with %s", p.equipment.Slot(slot), target)
+		}
+		return p.EquipFromBag(bagIdx)
+	}
+	_, err := p.Bag().Put(bagIdx, p.equipment.Unequip(slot))
+	return err
+}
+
+func (p *Player) MoveInBag(from, to int) error { return p.Bag().Move(from, to) }
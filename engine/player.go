@@ -2,9 +2,12 @@ package engine
 
 import (
 	"fmt"
+	"image/color"
 	"log"
 
+	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 	"github.com/jakecoffman/cp"
 	"github.com/lucb31/game-engine-go/engine/loot"
 )
@@ -38,6 +41,11 @@ type Player struct {
 
 	// Eyeframes
 	eyeframesTimeout Timeout
+
+	effects *StatusEffects
+
+	// Kept while entering & leaving buildings
+	equipment *Equipment
 }
 
 const (
@@ -49,6 +57,9 @@ const (
 	maxVisibilityRadius = 100.0
 	// Everything outside of this radius will be fully foggy
 	minVisibilityRadius = 200.0
+	// Stats panel layout
+	playerStatsLineHeight   = 15
+	playerStatsTooltipWidth = 300
 )
 
 func NewPlayer(world *GameWorld, asset *CharacterAsset, projectileAsset *ProjectileAsset) (*Player, error) {
@@ -99,8 +110,18 @@ func NewPlayer(world *GameWorld, asset *CharacterAsset, projectileAsset *Project
 		return nil, err
 	}
 
+	p.effects, err = NewStatusEffects(p, world)
+	if err != nil {
+		return nil, err
+	}
+
 	// Init inventory
-	p.inventory, err = loot.NewInventory()
+	p.inventory, err = loot.NewInventory(world.Resources())
+	if err != nil {
+		return nil, err
+	}
+
+	p.equipment, err = NewEquipment(p)
 	if err != nil {
 		return nil, err
 	}
@@ -125,7 +146,8 @@ func (p *Player) Draw(t RenderingTarget) error {
 	if p.Inside() {
 		return nil
 	}
-	p.asset.DrawHealthbar(t, p.shape, p.health, p.maxHealth)
+	p.asset.DrawHealthbar(t, p.shape, p.health, p.MaxHealth())
+	p.effects.Draw(t, p.shape)
 	// Play death animation loop when dead
 	if p.health <= 0 || p.world.gameOver {
 		err := p.asset.AnimationController().Loop("dead")
@@ -155,17 +177,47 @@ func (p *Player) DrawInteractionHud(t RenderingTarget) error {
 	return nil
 }
 
+type playerStatRow struct {
+	label string
+	value float64
+	// Stat to explain in the tooltip. Empty for stats without modifiers
+	stat Stat
+}
+
 func (p *Player) DrawPlayerStats(t RenderingTarget) error {
-	ebitenutil.DebugPrintAt(t.Screen(), "Player stats", t.Screen().Bounds().Dx()-125, 20)
-	ebitenutil.DebugPrintAt(t.Screen(), fmt.Sprintf("Power %.2f", p.Power()), t.Screen().Bounds().Dx()-125, 35)
-	ebitenutil.DebugPrintAt(t.Screen(), fmt.Sprintf("Health %.2f", p.Health()), t.Screen().Bounds().Dx()-125, 50)
-	ebitenutil.DebugPrintAt(t.Screen(), fmt.Sprintf("Max Health %.2f", p.MaxHealth()), t.Screen().Bounds().Dx()-125, 65)
-	ebitenutil.DebugPrintAt(t.Screen(), fmt.Sprintf("Speed %.2f", p.MovementSpeed()), t.Screen().Bounds().Dx()-125, 80)
-	ebitenutil.DebugPrintAt(t.Screen(), fmt.Sprintf("Armor %.2f", p.Armor()), t.Screen().Bounds().Dx()-125, 95)
-	ebitenutil.DebugPrintAt(t.Screen(), fmt.Sprintf("AtkSpeed %.2f", p.AtkSpeed()), t.Screen().Bounds().Dx()-125, 110)
+	rows := []playerStatRow{
+		{"Power", p.Power(), StatPower},
+		{"Health", p.Health(), ""},
+		{"Max Health", p.MaxHealth(), StatMaxHealth},
+		{"Speed", p.MovementSpeed(), StatMovementSpeed},
+		{"Armor", p.Armor(), StatArmor},
+		{"AtkSpeed", p.AtkSpeed(), StatAtkSpeed},
+	}
+	x := t.Screen().Bounds().Dx() - 125
+	ebitenutil.DebugPrintAt(t.Screen(), "Player stats", x, 20)
+	cursorX, cursorY := ebiten.CursorPosition()
+	for idx, row := range rows {
+		y := 35 + idx*playerStatsLineHeight
+		ebitenutil.DebugPrintAt(t.Screen(), fmt.Sprintf("%s %.2f", row.label, row.value), x, y)
+		// Stat sources tooltip while hovering the row
+		hovered := cursorX >= x && cursorY >= y && cursorY < y+playerStatsLineHeight
+		if hovered && row.stat != "" {
+			p.drawStatSources(t, row.stat, x-playerStatsTooltipWidth, y)
+		}
+	}
 	return nil
 }
 
+// Breakdown of base value & modifiers, left of the stats panel
+func (p *Player) drawStatSources(t RenderingTarget, stat Stat, x, y int) {
+	lines := StatBreakdown(p, stat)
+	height := len(lines)*playerStatsLineHeight + 4
+	vector.DrawFilledRect(t.Screen(), float32(x-4), float32(y-2), playerStatsTooltipWidth, float32(height), color.RGBA{0, 0, 0, 200}, false)
+	for idx, line := range lines {
+		ebitenutil.DebugPrintAt(t.Screen(), line, x, y+idx*playerStatsLineHeight)
+	}
+}
+
 func (p *Player) Destroy() error {
 	// Play dying animation
 	err := p.asset.AnimationController().Play("die")
@@ -185,7 +237,12 @@ func (p *Player) OnPlayerHit(arb *cp.Arbiter, space *cp.Space, userData interfac
 		log.Println("Collsion handler error: Expected npc but did not receive one")
 		return false
 	}
-	_, err := p.world.DamageModel().ApplyDamage(npc, p, p.world.IngameTime())
+	u, ok := space.StaticBody.UserData.(SpaceUserData)
+	if !ok {
+		log.Println("Collision handler error: No access to damage model via space user data")
+		return false
+	}
+	_, err := u.applyDamage(npc, p)
 	if err != nil {
 		log.Println("Error during player npc collision damage calc", err.Error())
 		return false
@@ -229,9 +286,11 @@ func (p *Player) ItemInRange() *ItemEntity {
 }
 
 func (p *Player) ItemPickup(item *ItemEntity) error {
-	if err := p.Inventory().AddLoot(item.loot); err != nil {
+	items := item.loot.Result(p.world.rng)
+	if err := p.Inventory().AddLoot(items); err != nil {
 		return err
 	}
+	Publish(p.world.events, LootCollected{p, items, item.Shape().Body().Position()})
 
 	// Remove item sprite
 	return item.Destroy()
@@ -241,13 +300,18 @@ func (p *Player) SetAxe(axe HarvestingTool) {
 	p.axe = axe
 	p.axe.SetAnimationController(p.asset.AnimationController())
 }
-func (p *Player) Id() GameEntityId          { return p.id }
-func (p *Player) SetId(id GameEntityId)     { p.id = id }
-func (p *Player) Shape() *cp.Shape          { return p.shape }
-func (p *Player) LootTable() loot.LootTable { return loot.NewEmptyLootTable() }
-func (p *Player) Inventory() loot.Inventory { return p.inventory }
-func (p *Player) Gun() Gun                  { return p.gun }
-func (p *Player) Position() cp.Vector       { return p.shape.Body().Position() }
+
+// Swap input source of the player controller, e.g. to record or replay a session
+func (p *Player) SetInputSource(input InputSource) { p.controller.SetInputSource(input) }
+func (p *Player) Id() GameEntityId                 { return p.id }
+func (p *Player) SetId(id GameEntityId)            { p.id = id }
+func (p *Player) Shape() *cp.Shape                 { return p.shape }
+func (p *Player) Body() *cp.Body                   { return p.shape.Body() }
+func (p *Player) LootTable() loot.LootTable        { return loot.NewEmptyLootTable() }
+func (p *Player) Inventory() loot.Inventory        { return p.inventory }
+func (p *Player) Gun() Gun                         { return p.gun }
+func (p *Player) StatusEffects() *StatusEffects    { return p.effects }
+func (p *Player) Position() cp.Vector              { return p.shape.Body().Position() }
 
 // Do nothing. Already have world reference
 func (p *Player) SetEntityRemover(EntityRemover) {}
@@ -256,10 +320,12 @@ func (p *Player) SetEntityRemover(EntityRemover) {}
 func (p *Player) handleInteraction() bool {
 	// Exit building
 	if p.Inside() {
+		building := p.insideBuilding
 		if err := p.Leave(); err != nil {
 			log.Println("Could not exit building", err.Error())
 			return false
 		}
+		Publish(p.world.events, BuildingLeft{building, p})
 		return true
 	}
 
@@ -270,6 +336,7 @@ func (p *Player) handleInteraction() bool {
 			log.Println("Could not enter building", err.Error())
 			return false
 		}
+		Publish(p.world.events, BuildingEntered{buildingInRange, p})
 		return true
 	}
 
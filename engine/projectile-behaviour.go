@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+type ProjectileBehaviourKind string
+
+const (
+	// Continue through Count additional targets
+	BehaviourPierce ProjectileBehaviourKind = "pierce"
+	// Fork into Count projectiles spread across Angle on hit
+	BehaviourFork ProjectileBehaviourKind = "fork"
+	// Redirect to the nearest enemy within Range that has not been hit yet. Count times
+	BehaviourChain ProjectileBehaviourKind = "chain"
+	// Split into Count projectiles spread across Angle once the fire range is exceeded
+	BehaviourSplit ProjectileBehaviourKind = "split"
+	// Steer towards the nearest enemy that has not been hit yet, turning at most TurnRate per second
+	BehaviourHoming ProjectileBehaviourKind = "homing"
+)
+
+// Configured per gun. Every projectile of the gun starts with these behaviours
+type ProjectileBehaviour struct {
+	Kind  ProjectileBehaviourKind
+	Count int
+	// Degrees. 0 = spread evenly around the full circle
+	Angle float64
+	// Chain distance
+	Range float64
+	// Degrees per second
+	TurnRate float64
+}
+
+func (b ProjectileBehaviour) Validate() error {
+	switch b.Kind {
+	case BehaviourPierce, BehaviourFork, BehaviourSplit:
+		if b.Count < 1 {
+			return fmt.Errorf("Projectile behaviour %s needs a count", b.Kind)
+		}
+	case BehaviourChain:
+		if b.Count < 1 || b.Range <= 0 {
+			return fmt.Errorf("Projectile behaviour %s needs count & range", b.Kind)
+		}
+	case BehaviourHoming:
+		if b.TurnRate <= 0 {
+			return fmt.Errorf("Projectile behaviour %s needs a turn rate", b.Kind)
+		}
+	default:
+		return fmt.Errorf("Unknown projectile behaviour %s", b.Kind)
+	}
+	return nil
+}
+
+// Adding a behaviour of a kind that is already present upgrades it:
+// Counts & turn rates add up, angle & range use the larger value
+func AddProjectileBehaviour(behaviours []ProjectileBehaviour, b ProjectileBehaviour) []ProjectileBehaviour {
+	for idx := range behaviours {
+		existing := &behaviours[idx]
+		if existing.Kind == b.Kind {
+			existing.Count += b.Count
+			existing.TurnRate += b.TurnRate
+			existing.Angle = max(existing.Angle, b.Angle)
+			existing.Range = max(existing.Range, b.Range)
+			return behaviours
+		}
+	}
+	return append(behaviours, b)
+}
+
+// Remaining behaviour of a single projectile. Spawned projectiles inherit what is left
+type projectileBehaviours struct {
+	pierce     int
+	fork       int
+	forkAngle  float64
+	chain      int
+	chainRange float64
+	split      int
+	splitAngle float64
+	turnRate   float64
+	// Targets hit by this projectile & all projectiles spawned from it
+	hit map[*cp.Body]bool
+}
+
+func newProjectileBehaviours(behaviours []ProjectileBehaviour) *projectileBehaviours {
+	res := &projectileBehaviours{hit: map[*cp.Body]bool{}}
+	for _, b := range behaviours {
+		switch b.Kind {
+		case BehaviourPierce:
+			res.pierce += b.Count
+		case BehaviourFork:
+			res.fork += b.Count
+			res.forkAngle = b.Angle
+		case BehaviourChain:
+			res.chain += b.Count
+			res.chainRange = b.Range
+		case BehaviourSplit:
+			res.split += b.Count
+			res.splitAngle = b.Angle
+		case BehaviourHoming:
+			res.turnRate += b.TurnRate
+		}
+	}
+	return res
+}
+
+func (b *projectileBehaviours) child() *projectileBehaviours {
+	res := *b
+	return &res
+}
+
+// Runs in order on every hit. The first stage that returns true decides what happens to the projectile.
+// If no stage handles the hit, the projectile is destroyed
+var projectileHitPipeline = []func(p *Projectile) (bool, error){
+	pierceOnHit,
+	forkOnHit,
+	chainOnHit,
+}
+
+// Keep flying in the current direction
+func pierceOnHit(p *Projectile) (bool, error) {
+	if p.behaviours.pierce <= 0 {
+		return false, nil
+	}
+	p.behaviours.pierce--
+	p.flyStraight()
+	return true, nil
+}
+
+// Replace projectile by forks. Forks cannot fork again
+func forkOnHit(p *Projectile) (bool, error) {
+	if p.behaviours.fork <= 0 {
+		return false, nil
+	}
+	behaviours := p.behaviours.child()
+	behaviours.fork = 0
+	if err := p.spawn(p.behaviours.fork, p.behaviours.forkAngle, behaviours); err != nil {
+		return false, err
+	}
+	return true, p.Destroy()
+}
+
+// Redirect to the next target. Destroyed if there is none
+func chainOnHit(p *Projectile) (bool, error) {
+	if p.behaviours.chain <= 0 {
+		return false, nil
+	}
+	next := p.nearestUnhitTarget(p.behaviours.chainRange)
+	if next == nil {
+		return false, nil
+	}
+	p.behaviours.chain--
+	p.target = next
+	p.staticVelocity = cp.Vector{}
+	p.origin = p.shape.Body().Position()
+	return true, nil
+}
+
+// Split into multiple projectiles when expiring. Splits cannot split again
+func (p *Projectile) expire() error {
+	if p.behaviours.split > 0 {
+		behaviours := p.behaviours.child()
+		behaviours.split = 0
+		if err := p.spawn(p.behaviours.split, p.behaviours.splitAngle, behaviours); err != nil {
+			return err
+		}
+	}
+	return p.Destroy()
+}
+
+// Rotate velocity towards the nearest target, limited by turn rate
+func (p *Projectile) steer(dt float64) {
+	if p.behaviours.turnRate <= 0 {
+		return
+	}
+	target := p.nearestUnhitTarget(p.gun.FireRange())
+	if target == nil {
+		return
+	}
+	heading := p.staticVelocity.ToAngle()
+	desired := target.Body().Position().Sub(p.shape.Body().Position()).ToAngle()
+	// Shortest rotation within [-pi, pi]
+	diff := math.Remainder(desired-heading, 2*math.Pi)
+	maxTurn := p.behaviours.turnRate * math.Pi / 180 * dt
+	diff = max(-maxTurn, min(maxTurn, diff))
+	p.staticVelocity = cp.ForAngle(heading + diff).Mult(p.velocity)
+}
+
+// Directions in radians of count projectiles spread across angle (degrees) around heading
+func spreadAngles(heading float64, count int, angle float64) []float64 {
+	res := make([]float64, count)
+	if angle <= 0 || angle >= 360 {
+		for idx := range res {
+			res[idx] = heading + 2*math.Pi*float64(idx)/float64(count)
+		}
+		return res
+	}
+	if count == 1 {
+		res[0] = heading
+		return res
+	}
+	spread := angle * math.Pi / 180
+	for idx := range res {
+		res[idx] = heading - spread/2 + spread*float64(idx)/float64(count-1)
+	}
+	return res
+}
+
+// Spawns new projectiles at the current position. Entities are added after the physics step
+func (p *Projectile) spawn(count int, angle float64, behaviours *projectileBehaviours) error {
+	pos := p.shape.Body().Position()
+	for _, direction := range spreadAngles(p.heading(), count, angle) {
+		proj, err := p.em.Projectiles().Acquire(p.gun, p.asset)
+		if err != nil {
+			return err
+		}
+		// Cap reached
+		if proj == nil {
+			return nil
+		}
+		proj.behaviours = behaviours.child()
+		proj.shape.Body().SetPosition(pos)
+		proj.origin = pos
+		proj.staticVelocity = cp.ForAngle(direction).Mult(proj.velocity)
+		p.shape.Space().AddPostStepCallback(func(*cp.Space, interface{}, interface{}) {
+			if err := p.em.AddEntity(proj); err != nil {
+				log.Println("Could not spawn projectile", err.Error())
+			}
+		}, proj, nil)
+	}
+	return nil
+}
+
+// Nearest target within radius that has not been hit by this projectile or its parents.
+// Candidates are the entities the projectile collides with, e.g. the player & castle for hostile projectiles
+func (p *Projectile) nearestUnhitTarget(radius float64) ProjectileTarget {
+	pos := p.shape.Body().Position()
+	filter := cp.NewShapeFilter(cp.NO_GROUP, cp.ALL_CATEGORIES, p.shape.Filter.Mask&^OuterWallsCategory)
+	var res ProjectileTarget
+	bestDist := math.Inf(1)
+	p.shape.Space().BBQuery(cp.NewBBForCircle(pos, radius), filter, func(shape *cp.Shape, data interface{}) {
+		target, ok := shape.Body().UserData.(ProjectileTarget)
+		if !ok || p.behaviours.hit[shape.Body()] {
+			return
+		}
+		if dist := target.Body().Position().Distance(pos); dist <= radius && dist < bestDist {
+			res, bestDist = target, dist
+		}
+	}, nil)
+	return res
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+func TestProjectileBehaviourUpgradesStack(t *testing.T) {
+	behaviours := AddProjectileBehaviour(nil, ProjectileBehaviour{Kind: BehaviourPierce, Count: 1})
+	behaviours = AddProjectileBehaviour(behaviours, ProjectileBehaviour{Kind: BehaviourChain, Count: 1, Range: 100})
+	behaviours = AddProjectileBehaviour(behaviours, ProjectileBehaviour{Kind: BehaviourPierce, Count: 2})
+	if len(behaviours) != 2 || behaviours[0].Count != 3 {
+		t.Errorf("Expected pierce upgrade to stack, got %v", behaviours)
+	}
+	if err := (ProjectileBehaviour{Kind: BehaviourChain, Count: 1}).Validate(); err == nil {
+		t.Error("Expected chain without range to be invalid")
+	}
+}
+
+func TestSpreadAngles(t *testing.T) {
+	angles := spreadAngles(0, 3, 90)
+	expected := []float64{-math.Pi / 4, 0, math.Pi / 4}
+	for idx := range expected {
+		if math.Abs(angles[idx]-expected[idx]) > 1e-9 {
+			t.Errorf("Expected %v, got %v", expected, angles)
+		}
+	}
+	// Full circle
+	angles = spreadAngles(0, 4, 0)
+	if math.Abs(angles[2]-math.Pi) > 1e-9 {
+		t.Errorf("Expected even spread around the circle, got %v", angles)
+	}
+}
+
+func newBehaviourTestNpcs(t *testing.T, w *GameWorld, positions ...cp.Vector) []*NpcEntity {
+	asset, err := w.AssetManager.CharacterAsset("tree_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := []*NpcEntity{}
+	for _, pos := range positions {
+		npc, err := NewNpc(asset, NpcOpts{BaseHealth: 100, StartingPos: pos})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.AddEntity(npc); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, npc)
+	}
+	return res
+}
+
+func fireBehaviourTestGun(t *testing.T, w *GameWorld, owner GameEntity, behaviours ...ProjectileBehaviour) {
+	proj, err := w.AssetManager.ProjectileAsset("arrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gun, err := NewAutoAimGun(w, owner, proj, BasicGunOpts{FireRange: 400, Damage: 10, Behaviours: behaviours})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gun.Shoot(); err != nil {
+		t.Fatal(err)
+	}
+	for range 120 {
+		w.Update()
+	}
+}
+
+func TestPiercingProjectileHitsEveryTargetOnce(t *testing.T) {
+	w, owner := newDamageTestWorld(t)
+	owner.Shape().Body().SetPosition(cp.Vector{X: 100, Y: 100})
+	if err := w.AddEntity(owner); err != nil {
+		t.Fatal(err)
+	}
+	npcs := newBehaviourTestNpcs(t, w, cp.Vector{X: 200, Y: 100}, cp.Vector{X: 260, Y: 100}, cp.Vector{X: 320, Y: 100})
+	fireBehaviourTestGun(t, w, owner, ProjectileBehaviour{Kind: BehaviourPierce, Count: 5})
+	for idx, npc := range npcs {
+		// Single hit of ~10 damage. Damage rolls vary slightly
+		if npc.Health() >= 100 || npc.Health() < 85 {
+			t.Errorf("Expected npc %d to be hit exactly once, got health %f", idx, npc.Health())
+		}
+	}
+}
+
+func TestChainingProjectileSkipsHitTargets(t *testing.T) {
+	w, owner := newDamageTestWorld(t)
+	owner.Shape().Body().SetPosition(cp.Vector{X: 100, Y: 100})
+	if err := w.AddEntity(owner); err != nil {
+		t.Fatal(err)
+	}
+	npcs := newBehaviourTestNpcs(t, w, cp.Vector{X: 200, Y: 100}, cp.Vector{X: 200, Y: 200})
+	fireBehaviourTestGun(t, w, owner, ProjectileBehaviour{Kind: BehaviourChain, Count: 3, Range: 150})
+	for idx, npc := range npcs {
+		// Single hit of ~10 damage. Damage rolls vary slightly
+		if npc.Health() >= 100 || npc.Health() < 85 {
+			t.Errorf("Expected npc %d to be hit exactly once, got health %f", idx, npc.Health())
+		}
+	}
+}
+
+func TestHostileProjectileTargetsPlayer(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	player, err := w.InitPlayer(w.AssetManager)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player.Shape().Body().SetPosition(cp.Vector{X: 300, Y: 100})
+	npcs := newBehaviourTestNpcs(t, w, cp.Vector{X: 100, Y: 100}, cp.Vector{X: 150, Y: 100})
+	asset, err := w.AssetManager.ProjectileAsset("arrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gun, err := NewAimedGun(w, npcs[0], asset, BasicGunOpts{FireRange: 400, Damage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proj, err := w.Projectiles().Acquire(gun, asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddEntity(proj); err != nil {
+		t.Fatal(err)
+	}
+	// Other npc is closer, but cannot be hit by hostile projectiles
+	if target := proj.nearestUnhitTarget(400); target != player {
+		t.Errorf("Expected hostile projectile to target the player, got %v", target)
+	}
+}
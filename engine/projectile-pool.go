@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"log"
+	"slices"
+)
+
+// What happens if a gun fires while the projectile cap is reached
+type ProjectileCapPolicy string
+
+const (
+	// Destroy the oldest projectile to make room for the new one
+	ProjectileCapDropOldest ProjectileCapPolicy = "drop-oldest"
+	// Do not fire
+	ProjectileCapRefuse ProjectileCapPolicy = "refuse"
+)
+
+const defaultMaxProjectiles = 2000
+
+// Recycles projectiles incl. their physics body & shape.
+// Projectiles return to the pool once removed from the world
+type ProjectilePool struct {
+	em GameEntityManager
+	// Max nr of projectiles alive at the same time. 0 = unlimited
+	MaxProjectiles int
+	Policy         ProjectileCapPolicy
+
+	free []*Projectile
+	// Ordered by spawn time. May contain stale entries of destroyed projectiles
+	active  []pooledProjectile
+	live    int
+	liveGun map[Gun]int
+}
+
+// Projectiles are reused. The generation tells if the entry still refers to the same shot
+type pooledProjectile struct {
+	projectile *Projectile
+	generation int
+}
+
+func (e pooledProjectile) alive() bool {
+	return !e.projectile.destroyed && e.projectile.generation == e.generation
+}
+
+func NewProjectilePool(em GameEntityManager) (*ProjectilePool, error) {
+	return &ProjectilePool{
+		em:             em,
+		MaxProjectiles: defaultMaxProjectiles,
+		Policy:         ProjectileCapDropOldest,
+		liveGun:        map[Gun]int{},
+	}, nil
+}
+
+// Returns a fresh projectile fired from the gun. Needs to be added to the world by the caller.
+// Nil if a cap is reached & the policy refuses to fire
+func (pool *ProjectilePool) Acquire(gun Gun, asset *ProjectileAsset) (*Projectile, error) {
+	if max := gun.MaxProjectiles(); max > 0 && pool.liveGun[gun] >= max {
+		if !pool.dropOldest(gun) {
+			return nil, nil
+		}
+	}
+	if pool.MaxProjectiles > 0 && pool.live >= pool.MaxProjectiles {
+		if !pool.dropOldest(nil) {
+			return nil, nil
+		}
+	}
+
+	var p *Projectile
+	if len(pool.free) > 0 {
+		p = pool.free[len(pool.free)-1]
+		pool.free = pool.free[:len(pool.free)-1]
+		if err := p.reset(gun, asset); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if p, err = NewProjectile(pool.em, gun, asset); err != nil {
+			return nil, err
+		}
+		p.pool = pool
+	}
+	p.generation++
+	pool.active = append(pool.active, pooledProjectile{p, p.generation})
+	pool.live++
+	pool.liveGun[gun]++
+	return p, nil
+}
+
+// Nr of projectiles that have not been destroyed
+func (pool *ProjectilePool) Live() int { return pool.live }
+
+// Nr of projectiles waiting for reuse
+func (pool *ProjectilePool) Free() int { return len(pool.free) }
+
+// Destroy oldest projectile of the gun. Any gun if nil. False if the policy refuses to fire
+func (pool *ProjectilePool) dropOldest(gun Gun) bool {
+	if pool.Policy != ProjectileCapDropOldest {
+		return false
+	}
+	for _, entry := range pool.active {
+		// Projectiles spawned after the current step are not in the world yet
+		if !entry.alive() || entry.projectile.Remover == nil {
+			continue
+		}
+		if gun == nil || entry.projectile.gun == gun {
+			// Destroy calls back into onDestroyed
+			if err := entry.projectile.Destroy(); err != nil {
+				log.Println("Could not drop projectile", err.Error())
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Called once a projectile is scheduled for removal
+func (pool *ProjectilePool) onDestroyed(p *Projectile) {
+	pool.live--
+	pool.liveGun[p.gun]--
+	if pool.liveGun[p.gun] <= 0 {
+		delete(pool.liveGun, p.gun)
+	}
+	// Drop stale entries once they make up half of the queue
+	if len(pool.active) > 2*pool.live+16 {
+		pool.active = slices.DeleteFunc(pool.active, func(e pooledProjectile) bool { return !e.alive() })
+	}
+}
+
+// Called once a projectile has been removed from the world
+func (pool *ProjectilePool) release(p *Projectile) {
+	pool.free = append(pool.free, p)
+}
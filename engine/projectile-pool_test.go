@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+func newPoolTestGun(tb testing.TB, w *GameWorld, owner GameEntity, maxProjectiles int) *SimpleGun {
+	asset, err := w.AssetManager.ProjectileAsset("arrow")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	orientation := North
+	gun, err := NewSimpleGun(w, owner, asset, &orientation, BasicGunOpts{FireRange: 10000, MaxProjectiles: maxProjectiles})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return gun
+}
+
+// Spawn a projectile flying in the given direction
+func firePoolTestProjectile(tb testing.TB, w *GameWorld, gun Gun, angle float64) *Projectile {
+	p, err := w.Projectiles().Acquire(gun, gun.(*SimpleGun).projectileAsset)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if p == nil {
+		return nil
+	}
+	p.staticVelocity = cp.ForAngle(angle).Mult(p.velocity)
+	if err := w.AddEntity(p); err != nil {
+		tb.Fatal(err)
+	}
+	return p
+}
+
+func TestProjectilePoolRecycles(t *testing.T) {
+	w, owner := newDamageTestWorld(t)
+	gun := newPoolTestGun(t, w, owner, 0)
+	first := firePoolTestProjectile(t, w, gun, 0)
+	if err := first.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	w.Update()
+	if w.Projectiles().Free() != 1 || w.Projectiles().Live() != 0 {
+		t.Fatalf("Expected projectile to return to pool, got %d free %d live", w.Projectiles().Free(), w.Projectiles().Live())
+	}
+	second := firePoolTestProjectile(t, w, gun, 0)
+	if first != second || second.destroyed {
+		t.Error("Expected projectile to be reused")
+	}
+}
+
+func TestProjectileCapPolicies(t *testing.T) {
+	w, owner := newDamageTestWorld(t)
+	gun := newPoolTestGun(t, w, owner, 2)
+	first := firePoolTestProjectile(t, w, gun, 0)
+	firePoolTestProjectile(t, w, gun, 0)
+
+	// Per gun cap: Drop oldest
+	firePoolTestProjectile(t, w, gun, 0)
+	if !first.destroyed || w.Projectiles().Live() != 2 {
+		t.Errorf("Expected oldest projectile to be dropped, got %d live", w.Projectiles().Live())
+	}
+	// Global cap: Refuse
+	w.Projectiles().Policy = ProjectileCapRefuse
+	w.Projectiles().MaxProjectiles = 3
+	other := newPoolTestGun(t, w, owner, 0)
+	if firePoolTestProjectile(t, w, other, 0) == nil {
+		t.Error("Expected projectile below global cap")
+	}
+	if firePoolTestProjectile(t, w, other, 0) != nil {
+		t.Error("Expected global cap to refuse projectile")
+	}
+	if firePoolTestProjectile(t, w, gun, 0) != nil {
+		t.Error("Expected gun cap to refuse projectile")
+	}
+}
+
+// Step time & allocations with thousands of projectiles in flight.
+// Compare pooled shots with allocating a new projectile per shot
+func BenchmarkProjectiles(b *testing.B) {
+	for _, pooled := range []bool{true, false} {
+		name := "pooled"
+		if !pooled {
+			name = "unpooled"
+		}
+		b.Run(name, func(b *testing.B) {
+			w, owner := newDamageTestWorld(b)
+			owner.Shape().Body().SetPosition(cp.Vector{X: 500, Y: 500})
+			w.Projectiles().MaxProjectiles = 0
+			gun := newPoolTestGun(b, w, owner, 0)
+			gun.fireRange = 300
+			fire := func(idx int) {
+				angle := 2 * math.Pi * float64(idx%360) / 360
+				if pooled {
+					firePoolTestProjectile(b, w, gun, angle)
+					return
+				}
+				p, err := NewProjectile(w, gun, gun.projectileAsset)
+				if err != nil {
+					b.Fatal(err)
+				}
+				p.staticVelocity = cp.ForAngle(angle).Mult(p.velocity)
+				if err := w.AddEntity(p); err != nil {
+					b.Fatal(err)
+				}
+			}
+			// 50 shots per step & 45 steps until the end of their range keep ~2000 projectiles in flight
+			step := func(i int) {
+				for idx := range 50 {
+					fire(i*50 + idx)
+				}
+				w.Update()
+			}
+			// Warm up: Reach steady state & fill the pool
+			for i := range 60 {
+				step(i)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				step(i)
+			}
+			b.ReportMetric(float64(len(w.objects)), "projectiles")
+		})
+	}
+}
@@ -15,6 +15,23 @@ func ProjectileCollisionFilter() cp.ShapeFilter {
 	return cp.NewShapeFilter(cp.NO_GROUP, ProjectileCategory, NpcCategory|OuterWallsCategory)
 }
 
+// Projectiles fired by npcs hit players & buildings instead
+func HostileProjectileCollisionFilter() cp.ShapeFilter {
+	return cp.NewShapeFilter(cp.NO_GROUP, ProjectileCategory, PlayerCategory|TowerCategory|OuterWallsCategory)
+}
+
+func projectileCollisionFilterFor(owner GameEntity) cp.ShapeFilter {
+	if owner.Shape().Filter.Categories&NpcCategory != 0 {
+		return HostileProjectileCollisionFilter()
+	}
+	return ProjectileCollisionFilter()
+}
+
+// Entities that absorb projectiles without taking damage, e.g. shielded npcs
+type ProjectileBlocker interface {
+	BlocksProjectile(p *Projectile) bool
+}
+
 type ProjectileTarget interface {
 	// Needs physical body
 	Body() *cp.Body
@@ -33,15 +50,42 @@ type Projectile struct {
 	staticVelocity cp.Vector
 
 	// Logic
+	em GameEntityManager
 	// Gun this projectile was fired from
-	gun      Gun
-	target   ProjectileTarget
-	piercing bool
+	gun        Gun
+	target     ProjectileTarget
+	behaviours *projectileBehaviours
+	// Set once destroyed. Further hits within the same step are ignored
+	destroyed bool
+	// Pool the projectile returns to after removal. Incremented on every reuse
+	pool       *ProjectilePool
+	generation int
 
 	// Rendering
 	asset *ProjectileAsset
 }
 
+// Kills are attributed to the owner of the gun
+func (p *Projectile) AttackOwner() GameEntity { return p.gun.Owner() }
+func (p *Projectile) DamageType() damage.DamageType {
+	if typed, ok := p.gun.(damage.TypedAttacker); ok {
+		return typed.DamageType()
+	}
+	return damage.Physical
+}
+func (p *Projectile) CritChance() float64 {
+	if crit, ok := p.gun.(damage.CritAttacker); ok {
+		return crit.CritChance()
+	}
+	return 0
+}
+func (p *Projectile) CritMultiplier() float64 {
+	if crit, ok := p.gun.(damage.CritAttacker); ok {
+		return crit.CritMultiplier()
+	}
+	return damage.DefaultCritMultiplier
+}
+
 type ProjectileAsset struct {
 	Image          *ebiten.Image
 	animationSpeed float64
@@ -69,7 +113,7 @@ func (a *ProjectileAsset) Draw(t RenderingTarget, position cp.Vector, angleInRad
 	return nil
 }
 
-func NewProjectile(gun Gun, asset *ProjectileAsset) (*Projectile, error) {
+func NewProjectile(em GameEntityManager, gun Gun, asset *ProjectileAsset) (*Projectile, error) {
 	if asset.Image == nil {
 		return nil, fmt.Errorf("Failed to instantiate projectile. No asset provided")
 	}
@@ -77,25 +121,74 @@ func NewProjectile(gun Gun, asset *ProjectileAsset) (*Projectile, error) {
 	if err != nil {
 		return nil, err
 	}
-	p := &Projectile{BaseEntityImpl: base, asset: asset}
+	p := &Projectile{BaseEntityImpl: base, em: em}
 	body := cp.NewKinematicBody()
-	body.SetPosition(gun.Owner().Shape().Body().Position())
 	body.SetVelocityUpdateFunc(p.calculateVelocity)
 	body.UserData = p
 
 	p.shape = cp.NewBox(body, 16, 16, 0)
 	p.shape.SetSensor(true)
 	p.shape.SetCollisionType(cp.CollisionType(ProjectileCollision))
-	p.shape.SetFilter(ProjectileCollisionFilter())
-	p.velocity = defaultProjectileSpeed
+	return p, p.reset(gun, asset)
+}
+
+// Prepare a new or recycled projectile to be fired from the gun
+func (p *Projectile) reset(gun Gun, asset *ProjectileAsset) error {
+	if asset.Image == nil {
+		return fmt.Errorf("Failed to reset projectile. No asset provided")
+	}
+	p.BaseEntityImpl.Remover = nil
+	p.asset = asset
 	p.gun = gun
+	p.target = nil
+	p.destroyed = false
+	p.velocity = defaultProjectileSpeed
+	p.direction = cp.Vector{}
+	p.staticVelocity = cp.Vector{}
+	p.behaviours = newProjectileBehaviours(gun.ProjectileBehaviours())
+	p.shape.SetFilter(projectileCollisionFilterFor(gun.Owner()))
+	body := p.shape.Body()
+	body.SetPosition(gun.Owner().Shape().Body().Position())
+	body.SetVelocity(0, 0)
 	p.origin = body.Position()
-	return p, nil
+	return nil
 }
 
 func (p *Projectile) Draw(t RenderingTarget) error {
-	angle := p.Shape().Body().Position().Sub(p.direction).Neg().ToAngle()
-	return p.asset.Draw(t, p.shape.Body().Position(), angle)
+	return p.asset.Draw(t, p.shape.Body().Position(), p.heading())
+}
+
+// Angle of the current flight direction
+func (p *Projectile) heading() float64 {
+	if vel := p.shape.Body().Velocity(); vel.LengthSq() > 0 {
+		return vel.ToAngle()
+	}
+	if p.target != nil {
+		return p.target.Body().Position().Sub(p.shape.Body().Position()).ToAngle()
+	}
+	return p.direction.Sub(p.shape.Body().Position()).ToAngle()
+}
+
+// Continue with current momentum instead of following the target
+func (p *Projectile) flyStraight() {
+	p.staticVelocity = cp.ForAngle(p.heading()).Mult(p.velocity)
+	p.target = nil
+}
+
+func (p *Projectile) Destroy() error {
+	if p.destroyed {
+		return nil
+	}
+	p.destroyed = true
+	if p.pool != nil {
+		p.pool.onDestroyed(p)
+	}
+	return p.BaseEntityImpl.Destroy()
+}
+
+// Projectiles never hit the same target twice. Includes targets hit by parent projectiles
+func (p *Projectile) CanHit(target *cp.Body) bool {
+	return !p.destroyed && !p.behaviours.hit[target]
 }
 
 func (p *Projectile) Shape() *cp.Shape                  { return p.shape }
@@ -103,47 +196,59 @@ func (p *Projectile) Power() float64                    { return p.gun.Power() }
 func (p *Projectile) AtkSpeed() float64                 { return 1.0 }
 func (p *Projectile) LootTable() loot.LootTable         { return loot.NewEmptyLootTable() }
 func (p *Projectile) SetTarget(target ProjectileTarget) { p.target = target }
-func (p *Projectile) SetPiercing(piercing bool)         { p.piercing = piercing }
-
-// Callback after projectile has hit an object. Used to implement projectile behaviour after hit
-// Default: Remove projectile
-// Piercing: Continue with current momentum
-// Forking: TODO
-func (p *Projectile) OnHit() error {
-	if p.piercing {
-		if p.target == nil {
-			return fmt.Errorf("Whoops. No target. This should not happen")
+
+// Callback after projectile has hit a target. Runs the on hit pipeline
+// of the gun behaviours. Default: Remove projectile
+func (p *Projectile) OnHit(target *cp.Body) error {
+	p.behaviours.hit[target] = true
+	for _, stage := range projectileHitPipeline {
+		handled, err := stage(p)
+		if err != nil {
+			return err
+		}
+		if handled {
+			return nil
 		}
-		p.staticVelocity = p.Shape().Body().Velocity()
-		p.target = nil
-		return nil
 	}
-
 	return p.Destroy()
 }
 
 func (p *Projectile) calculateVelocity(body *cp.Body, gravity cp.Vector, damping float64, dt float64) {
+	if p.destroyed {
+		return
+	}
 	if p.target != nil {
-		// Remove guided projectile if target no longer exists
+		// Remove guided projectile if target no longer exists. Homing projectiles look for a new one
 		targetStillExists := p.shape.Space().ContainsBody(p.target.Body())
-		if !targetStillExists {
-			log.Println("Removing projectile: Target no longer exists")
-			p.Destroy()
+		switch {
+		case targetStillExists:
+			p.direction = p.target.Body().Position()
+		case p.behaviours.turnRate > 0:
+			p.flyStraight()
+		default:
+			if err := p.expire(); err != nil {
+				log.Println("Could not expire projectile", err.Error())
+			}
 			return
 		}
-
-		p.direction = p.target.Body().Position()
+	}
+	// Homing needs a free flight direction to steer
+	if p.target == nil && p.behaviours.turnRate > 0 && p.staticVelocity.LengthSq() == 0 {
+		p.flyStraight()
 	}
 
 	// Remove projectile if fire range exceeded
 	distanceFromOrigin := p.shape.Body().Position().Distance(p.origin)
 	if math.IsNaN(distanceFromOrigin) || distanceFromOrigin >= p.gun.FireRange() {
-		p.Destroy()
+		if err := p.expire(); err != nil {
+			log.Println("Could not expire projectile", err.Error())
+		}
 		return
 	}
 
 	// Move projectile by static velocity
 	if p.staticVelocity.LengthSq() > 0.0 {
+		p.steer(dt)
 		body.SetVelocityVector(p.staticVelocity)
 		return
 	}
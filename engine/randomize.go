@@ -1,11 +1,17 @@
 package engine
 
 import (
-	"math/rand"
+	"math/rand/v2"
 )
 
+// Seedable random number generator. Game logic should always draw from an injected
+// generator instead of the global math/rand source to keep simulations reproducible
+func NewRandomGenerator(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
 // Returns a list of indices with size "sampleSize" and the given probability distribution
-func SampleWithRelativeProbabilities(probabilities []int, sampleSize int) []int {
+func SampleWithRelativeProbabilities(rng *rand.Rand, probabilities []int, sampleSize int) []int {
 	// Calculate total sum of probabilities (required for normalization)
 	sum := 0
 	for _, prob := range probabilities {
@@ -25,7 +31,7 @@ func SampleWithRelativeProbabilities(probabilities []int, sampleSize int) []int
 	// Sample
 	res := make([]int, sampleSize)
 	for i := range sampleSize {
-		randNumber := rand.Float32()
+		randNumber := rng.Float32()
 		bucket := 0
 		for randNumber > cdf[bucket] {
 			bucket++
@@ -12,7 +12,7 @@ func TestProbabilitySample(t *testing.T) {
 	sampleSize := 10000
 	pdf := []int{1, 1, 5, 10}
 
-	res := engine.SampleWithRelativeProbabilities(pdf, sampleSize)
+	res := engine.SampleWithRelativeProbabilities(engine.NewRandomGenerator(1), pdf, sampleSize)
 	if len(res) != sampleSize {
 		t.Fatalf("Expected %d items, but only received array of size %d", sampleSize, len(res))
 	}
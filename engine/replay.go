@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+const ReplayVersion = 1
+
+// Everything required to reproduce a session: Seed, settings & player inputs of every tick
+type Replay struct {
+	Version int
+	// Name of the game, e.g. 'survival'
+	Game         string
+	Seed         uint64
+	ScreenWidth  int
+	ScreenHeight int
+	// One frame per tick. Encoded as base64
+	Frames []InputFrame
+	// Changes to game speed, ordered by tick
+	SpeedChanges []ReplaySpeedChange
+	// Ui actions not covered by input frames, e.g. shop purchases. Ordered by tick
+	Events []ReplayEvent `json:",omitempty"`
+}
+
+type ReplaySpeedChange struct {
+	Tick  int
+	Speed float64
+}
+
+// Ui action. Played back before simulating the tick it was recorded at
+type ReplayEvent struct {
+	Tick   int
+	Action string
+	// Action specific arguments, e.g. item id or slot indices
+	Id   string `json:",omitempty"`
+	Args []int  `json:",omitempty"`
+}
+
+func NewReplay(game string, seed uint64, screenWidth, screenHeight int) *Replay {
+	return &Replay{Version: ReplayVersion, Game: game, Seed: seed, ScreenWidth: screenWidth, ScreenHeight: screenHeight}
+}
+
+// Returns game speed that was active at the given tick
+func (r *Replay) SpeedAt(tick int) float64 {
+	speed := 1.0
+	for _, change := range r.SpeedChanges {
+		if change.Tick > tick {
+			break
+		}
+		speed = change.Speed
+	}
+	return speed
+}
+
+func (r *Replay) RecordSpeedChange(tick int, speed float64) {
+	// Multiple changes within the same tick: Only last one is relevant
+	if len(r.SpeedChanges) > 0 && r.SpeedChanges[len(r.SpeedChanges)-1].Tick == tick {
+		r.SpeedChanges[len(r.SpeedChanges)-1].Speed = speed
+		return
+	}
+	r.SpeedChanges = append(r.SpeedChanges, ReplaySpeedChange{tick, speed})
+}
+
+func (r *Replay) RecordEvent(event ReplayEvent) {
+	r.Events = append(r.Events, event)
+}
+
+// Returns events recorded at the given tick in recording order
+func (r *Replay) EventsAt(tick int) []ReplayEvent {
+	start, _ := slices.BinarySearchFunc(r.Events, tick, func(e ReplayEvent, t int) int { return cmp.Compare(e.Tick, t) })
+	end := start
+	for end < len(r.Events) && r.Events[end].Tick == tick {
+		end++
+	}
+	return r.Events[start:end]
+}
+
+func (r *Replay) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+func (r *Replay) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.Write(f)
+}
+
+func ReadReplay(reader io.Reader) (*Replay, error) {
+	r := &Replay{}
+	if err := json.NewDecoder(reader).Decode(r); err != nil {
+		return nil, fmt.Errorf("Could not parse replay: %s", err.Error())
+	}
+	if r.Version != ReplayVersion {
+		return nil, fmt.Errorf("Unsupported replay version %d. Expected %d", r.Version, ReplayVersion)
+	}
+	return r, nil
+}
+
+func LoadReplay(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadReplay(f)
+}
+
+// Wraps an input source & appends every frame to the replay
+type InputRecorder struct {
+	InputSource
+	replay *Replay
+}
+
+func NewInputRecorder(source InputSource, replay *Replay) (*InputRecorder, error) {
+	if source == nil || replay == nil {
+		return nil, fmt.Errorf("Cannot init recorder without source & replay")
+	}
+	return &InputRecorder{InputSource: source, replay: replay}, nil
+}
+
+func (r *InputRecorder) Update() {
+	r.InputSource.Update()
+	r.replay.Frames = append(r.replay.Frames, r.InputSource.Frame())
+}
+
+func (r *InputRecorder) Replay() *Replay { return r.replay }
+
+// Feeds recorded frames back. Once all frames have been played, no actions will be pressed
+type ReplayInputSource struct {
+	*BaseInputSource
+	frames []InputFrame
+	next   int
+}
+
+func NewReplayInputSource(replay *Replay) (*ReplayInputSource, error) {
+	if replay == nil {
+		return nil, fmt.Errorf("Cannot init replay input without replay")
+	}
+	return &ReplayInputSource{BaseInputSource: &BaseInputSource{}, frames: replay.Frames}, nil
+}
+
+func (s *ReplayInputSource) Update() {
+	frame := InputFrame(0)
+	if s.next < len(s.frames) {
+		frame = s.frames[s.next]
+	}
+	s.next++
+	s.push(frame)
+}
+
+func (s *ReplayInputSource) Done() bool { return s.next >= len(s.frames) }
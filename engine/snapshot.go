@@ -0,0 +1,423 @@
+package engine
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/damage"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+type EntityKind string
+
+const (
+	EntityKindTree EntityKind = "tree"
+	EntityKindItem EntityKind = "item"
+	EntityKindNpc  EntityKind = "npc"
+	EntityKindBoss EntityKind = "boss"
+)
+
+// Serializable state of a game world. Does not contain game specific entities like castles,
+// those need to be persisted by the game itself
+type WorldSnapshot struct {
+	Seed uint64
+	// Binary state of the random generator
+	Rng        []byte
+	Tick       int
+	IngameTime float64
+	GameSpeed  float64
+	// Ground tiles of a procedurally generated hex map
+	HexMap [][]MapTile `json:",omitempty"`
+	// Fog alpha per tile
+	Fog      [][]uint8 `json:",omitempty"`
+	Player   PlayerSnapshot
+	Entities []EntitySnapshot
+}
+
+type PlayerSnapshot struct {
+	Position  cp.Vector
+	Stats     StatsSnapshot
+	Inventory InventorySnapshot
+	Eyeframes TimerSnapshot
+	Effects   []StatusEffectSnapshot `json:",omitempty"`
+	// Nil for empty bag slots
+	Bag       []*ItemSnapshot                     `json:",omitempty"`
+	Equipment map[loot.EquipmentSlot]ItemSnapshot `json:",omitempty"`
+}
+
+type StatsSnapshot struct {
+	Armor          float64
+	AtkSpeed       float64
+	Health         float64
+	MaxHealth      float64
+	MovementSpeed  float64
+	Power          float64
+	CritChance     float64                       `json:",omitempty"`
+	CritMultiplier float64                       `json:",omitempty"`
+	Resistances    map[damage.DamageType]float64 `json:",omitempty"`
+	// Base values above are stored without modifiers
+	Modifiers []StatModifier `json:",omitempty"`
+}
+
+type ResourceSnapshot struct {
+	Balance int64
+	Revenue int64
+}
+
+// Balance & revenue per resource
+type InventorySnapshot map[loot.ResourceId]ResourceSnapshot
+
+type ItemSnapshot struct {
+	Id      loot.ItemInstanceId
+	Item    string
+	Count   int64
+	Affixes []loot.ItemAffix `json:",omitempty"`
+}
+
+func NewItemSnapshot(instance *loot.ItemInstance) ItemSnapshot {
+	return ItemSnapshot{instance.Id, instance.Item.Id, instance.Count, slices.Clone(instance.Affixes)}
+}
+
+func (s ItemSnapshot) Restore(db *loot.ItemDB) (*loot.ItemInstance, error) {
+	return db.RestoreInstance(s.Id, s.Item, s.Count, slices.Clone(s.Affixes))
+}
+
+func NewBagSnapshot(bag *loot.Bag) []*ItemSnapshot {
+	res := make([]*ItemSnapshot, bag.Size())
+	empty := true
+	for idx := range res {
+		if instance := bag.Slot(idx); instance != nil {
+			snapshot := NewItemSnapshot(instance)
+			res[idx] = &snapshot
+			empty = false
+		}
+	}
+	if empty {
+		return nil
+	}
+	return res
+}
+
+// Slots missing in the snapshot are emptied
+func RestoreBag(bag *loot.Bag, slots []*ItemSnapshot, db *loot.ItemDB) error {
+	for idx := range bag.Size() {
+		var instance *loot.ItemInstance
+		if idx < len(slots) && slots[idx] != nil {
+			var err error
+			if instance, err = slots[idx].Restore(db); err != nil {
+				return err
+			}
+		}
+		if _, err := bag.Put(idx, instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewEquipmentSnapshot(e *Equipment) map[loot.EquipmentSlot]ItemSnapshot {
+	res := map[loot.EquipmentSlot]ItemSnapshot{}
+	for slot, instance := range e.Equipped() {
+		res[slot] = NewItemSnapshot(instance)
+	}
+	return res
+}
+
+// Resources contained in a loot table
+type LootSnapshot map[loot.ResourceId]int64
+
+type EntitySnapshot struct {
+	Kind EntityKind
+	// Name of the character asset
+	Asset     string
+	Position  cp.Vector
+	Velocity  cp.Vector
+	Stats     StatsSnapshot
+	Loot      LootSnapshot
+	Attacking bool `json:",omitempty"`
+	Fleeing   bool `json:",omitempty"`
+	Fled      bool `json:",omitempty"`
+	// Direction shields are facing
+	Facing  cp.Vector
+	Timers  map[string]TimerSnapshot `json:",omitempty"`
+	Effects []StatusEffectSnapshot   `json:",omitempty"`
+	// Index of the active boss phase
+	Phase int `json:",omitempty"`
+	// Id of a weighted loot table. Replaces Loot on restore
+	LootTable string `json:",omitempty"`
+	// Contents of weighted loot tables without id, e.g. built in code. Replaces Loot on restore
+	LootEntries *loot.LootEntryDefinition `json:",omitempty"`
+	// Item instances of dropped loot
+	Items []ItemSnapshot `json:",omitempty"`
+}
+
+// Entities that can be persisted in a world snapshot.
+// Entities without snapshot support (e.g. projectiles) are dropped when saving
+type SnapshotEntity interface {
+	GameEntity
+	Snapshot() EntitySnapshot
+	Restore(EntitySnapshot) error
+}
+
+// Creates an empty entity for the given snapshot. State is applied via SnapshotEntity.Restore
+type EntityFactory func(EntitySnapshot) (SnapshotEntity, error)
+
+func (s *GameEntityStats) StatsSnapshot() StatsSnapshot {
+	return StatsSnapshot{
+		Armor:          s.armor,
+		AtkSpeed:       s.atkSpeed,
+		Health:         s.health,
+		MaxHealth:      s.maxHealth,
+		MovementSpeed:  s.movementSpeed,
+		Power:          s.power,
+		CritChance:     s.critChance,
+		CritMultiplier: s.critMultiplier,
+		Resistances:    maps.Clone(s.resistances),
+		Modifiers:      slices.Clone(s.modifiers),
+	}
+}
+
+func (s *GameEntityStats) RestoreStats(stats StatsSnapshot) {
+	s.armor = stats.Armor
+	s.atkSpeed = stats.AtkSpeed
+	s.health = stats.Health
+	s.maxHealth = stats.MaxHealth
+	s.movementSpeed = stats.MovementSpeed
+	s.power = stats.Power
+	s.critChance = stats.CritChance
+	// Saves without crit stats
+	if stats.CritMultiplier > 0 {
+		s.critMultiplier = stats.CritMultiplier
+	}
+	s.resistances = maps.Clone(stats.Resistances)
+	s.modifiers = slices.Clone(stats.Modifiers)
+	s.nextModifierId = 0
+	for _, m := range s.modifiers {
+		s.nextModifierId = max(s.nextModifierId, m.Id)
+	}
+}
+
+func NewInventorySnapshot(inv loot.Inventory) InventorySnapshot {
+	res := InventorySnapshot{}
+	for _, kind := range inv.Registry().Kinds() {
+		manager := inv.Resource(kind.Id)
+		res[kind.Id] = ResourceSnapshot{manager.Balance(), manager.Revenue()}
+	}
+	return res
+}
+
+// Resources missing in the snapshot are reset
+func (s InventorySnapshot) Restore(inv loot.Inventory) {
+	for _, kind := range inv.Registry().Kinds() {
+		inv.Resource(kind.Id).Restore(s[kind.Id].Balance, s[kind.Id].Revenue)
+	}
+}
+
+// NOTE: Evaluates the loot table. Only works for deterministic tables.
+// Weighted tables are persisted via EntitySnapshot.LootTable & LootEntries instead
+func NewLootSnapshot(table loot.LootTable) LootSnapshot {
+	res := LootSnapshot{}
+	if _, ok := table.(*loot.WeightedLootTable); ok {
+		return res
+	}
+	for _, item := range table.Result(nil) {
+		if v, ok := item.(*loot.ResourceItem); ok {
+			res[v.Resource] += v.Value()
+		}
+	}
+	return res
+}
+
+func (s LootSnapshot) LootTable() loot.LootTable {
+	table := loot.NewResourcesLootTable()
+	for id, amount := range s {
+		table.Add(id, amount)
+	}
+	return table
+}
+
+// Creates trees & items. Other kinds need to be handled by the game
+func NewDefaultEntityFactory(am AssetManager) EntityFactory {
+	return func(s EntitySnapshot) (SnapshotEntity, error) {
+		switch s.Kind {
+		case EntityKindTree:
+			asset, err := am.CharacterAsset(s.Asset)
+			if err != nil {
+				return nil, err
+			}
+			return NewTree(asset)
+		case EntityKindItem:
+			item, err := NewItemEntity(s.Position)
+			if err != nil {
+				return nil, err
+			}
+			if s.Asset != "" {
+				asset, err := am.CharacterAsset(s.Asset)
+				if err != nil {
+					return nil, err
+				}
+				if err := item.SetAsset(asset); err != nil {
+					return nil, err
+				}
+			}
+			return item, nil
+		}
+		return nil, fmt.Errorf("Unknown entity kind %s", s.Kind)
+	}
+}
+
+func (w *GameWorld) Snapshot() (*WorldSnapshot, error) {
+	rng, err := w.pcg.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	s := &WorldSnapshot{
+		Seed:       w.seed,
+		Rng:        rng,
+		Tick:       w.tick,
+		IngameTime: *w.gameTime,
+		GameSpeed:  w.GameSpeed,
+	}
+	if hexMap, ok := w.WorldMap.(*HexWorldMap); ok {
+		s.HexMap = hexMap.GroundTiles()
+	}
+	if fog, ok := w.FogOfWar.(*DiscoveryLayer); ok {
+		s.Fog = fog.Discovered()
+	}
+	if w.player != nil {
+		s.Player = PlayerSnapshot{
+			Position:  w.player.Position(),
+			Stats:     w.player.StatsSnapshot(),
+			Inventory: NewInventorySnapshot(w.player.Inventory()),
+			Eyeframes: w.player.eyeframesTimeout.Snapshot(),
+			Effects:   w.player.effects.Snapshot(),
+			Bag:       NewBagSnapshot(w.player.Bag()),
+			Equipment: NewEquipmentSnapshot(w.player.equipment),
+		}
+	}
+	// Sort by id to keep restore order stable
+	ids := []GameEntityId{}
+	for id := range w.objects {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		if entity, ok := w.objects[id].(SnapshotEntity); ok {
+			entitySnapshot := entity.Snapshot()
+			if table, ok := entity.LootTable().(*loot.WeightedLootTable); ok {
+				if err := snapshotWeightedLootTable(&entitySnapshot, table); err != nil {
+					return nil, err
+				}
+			}
+			if item, ok := entity.(*ItemEntity); ok {
+				entitySnapshot.Items = NewDropSnapshot(item)
+			}
+			s.Entities = append(s.Entities, entitySnapshot)
+		}
+	}
+	return s, nil
+}
+
+// Apply snapshot to a freshly initialized world. Entities are recreated via factory
+// and added to the world. Returns restored entities in snapshot order
+func (w *GameWorld) Restore(s *WorldSnapshot, factory EntityFactory) ([]SnapshotEntity, error) {
+	if s.Seed != w.seed {
+		return nil, fmt.Errorf("Snapshot seed %d does not match world seed %d", s.Seed, w.seed)
+	}
+	if err := w.pcg.UnmarshalBinary(s.Rng); err != nil {
+		return nil, fmt.Errorf("Could not restore random generator: %s", err.Error())
+	}
+	w.tick = s.Tick
+	*w.gameTime = s.IngameTime
+	w.GameSpeed = s.GameSpeed
+	if hexMap, ok := w.WorldMap.(*HexWorldMap); ok && s.HexMap != nil {
+		if err := hexMap.RestoreGroundTiles(s.HexMap); err != nil {
+			return nil, err
+		}
+	}
+	if fog, ok := w.FogOfWar.(*DiscoveryLayer); ok && s.Fog != nil {
+		if err := fog.RestoreDiscovered(s.Fog); err != nil {
+			return nil, err
+		}
+	}
+	if w.player != nil {
+		w.player.Shape().Body().SetPosition(s.Player.Position)
+		w.player.RestoreStats(s.Player.Stats)
+		s.Player.Inventory.Restore(w.player.Inventory())
+		w.player.eyeframesTimeout.Restore(s.Player.Eyeframes)
+		if err := w.player.effects.Restore(s.Player.Effects); err != nil {
+			return nil, err
+		}
+		if err := RestoreBag(w.player.Bag(), s.Player.Bag, w.items); err != nil {
+			return nil, err
+		}
+		equipped := map[loot.EquipmentSlot]*loot.ItemInstance{}
+		for slot, item := range s.Player.Equipment {
+			instance, err := item.Restore(w.items)
+			if err != nil {
+				return nil, err
+			}
+			equipped[slot] = instance
+		}
+		w.player.equipment.Restore(equipped)
+	}
+	res := []SnapshotEntity{}
+	for _, entitySnapshot := range s.Entities {
+		entity, err := factory(entitySnapshot)
+		if err != nil {
+			return nil, fmt.Errorf("Could not restore %s entity: %s", entitySnapshot.Kind, err.Error())
+		}
+		if err := entity.Restore(entitySnapshot); err != nil {
+			return nil, err
+		}
+		if err := w.restoreLootTable(entity, entitySnapshot); err != nil {
+			return nil, err
+		}
+		if err := w.restoreItemDrop(entity, entitySnapshot); err != nil {
+			return nil, err
+		}
+		if err := w.AddEntity(entity); err != nil {
+			return nil, err
+		}
+		res = append(res, entity)
+	}
+	return res, nil
+}
+
+// Named tables are referenced by id, others are persisted with their contents
+func snapshotWeightedLootTable(s *EntitySnapshot, table *loot.WeightedLootTable) error {
+	if table.Id != "" {
+		s.LootTable = table.Id
+		return nil
+	}
+	def, err := loot.NewLootEntryDefinition(table)
+	if err != nil {
+		return err
+	}
+	s.LootEntries = &def
+	return nil
+}
+
+func (w *GameWorld) restoreLootTable(entity SnapshotEntity, s EntitySnapshot) error {
+	if s.LootTable == "" && s.LootEntries == nil {
+		return nil
+	}
+	setter, ok := entity.(LootTableSetter)
+	if !ok {
+		return fmt.Errorf("Cannot restore loot table of %s entity without loot table setter", s.Kind)
+	}
+	var table *loot.WeightedLootTable
+	var err error
+	if s.LootTable != "" {
+		table, err = w.lootTables.Table(s.LootTable)
+	} else {
+		table, err = w.lootTables.NewTable(*s.LootEntries)
+	}
+	if err != nil {
+		return err
+	}
+	setter.SetLootTable(table)
+	return nil
+}
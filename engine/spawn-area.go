@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/jakecoffman/cp"
+)
+
+// Max attempts to sample a position within a non-rectangular region
+const spawnAreaMaxSamples = 32
+
+// Set of closed map shapes that positions can be sampled from
+// Regions are weighted by their area. Weight can be adjusted with the 'weight' property
+type SpawnArea struct {
+	regions []*MapObject
+	// Cumulative weights
+	weights     []float64
+	totalWeight float64
+}
+
+func NewSpawnArea(regions []*MapObject) (*SpawnArea, error) {
+	a := &SpawnArea{}
+	for _, region := range regions {
+		if !region.Closed() {
+			return nil, fmt.Errorf("Spawn region %d is not a closed shape", region.Id)
+		}
+		weight := region.Area() * region.Properties.Float("weight", 1.0)
+		if weight <= 0 {
+			continue
+		}
+		a.totalWeight += weight
+		a.regions = append(a.regions, region)
+		a.weights = append(a.weights, a.totalWeight)
+	}
+	if len(a.regions) == 0 {
+		return nil, fmt.Errorf("Spawn area needs at least one region")
+	}
+	return a, nil
+}
+
+func (a *SpawnArea) Contains(pos cp.Vector) bool {
+	for _, region := range a.regions {
+		if region.Contains(pos) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a random position within one of the regions
+func (a *SpawnArea) RandomPosition(rng *rand.Rand) (cp.Vector, error) {
+	// Select region
+	roll := rng.Float64() * a.totalWeight
+	region := a.regions[len(a.regions)-1]
+	for idx, weight := range a.weights {
+		if roll < weight {
+			region = a.regions[idx]
+			break
+		}
+	}
+	// Rejection sampling within bounding box
+	bb := region.BB()
+	for range spawnAreaMaxSamples {
+		pos := cp.Vector{X: bb.L + rng.Float64()*(bb.R-bb.L), Y: bb.B + rng.Float64()*(bb.T-bb.B)}
+		if region.Contains(pos) {
+			return pos, nil
+		}
+	}
+	return cp.Vector{}, fmt.Errorf("Could not sample position in spawn region %d", region.Id)
+}
@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Stat string
+
+const (
+	StatArmor          Stat = "armor"
+	StatAtkSpeed       Stat = "atk-speed"
+	StatMaxHealth      Stat = "max-health"
+	StatMovementSpeed  Stat = "movement-speed"
+	StatPower          Stat = "power"
+	StatCritChance     Stat = "crit-chance"
+	StatCritMultiplier Stat = "crit-multiplier"
+)
+
+// Unknown stats are ignored by stat calculation. Used to validate definitions
+func (s Stat) Valid() bool {
+	switch s {
+	case StatArmor, StatAtkSpeed, StatMaxHealth, StatMovementSpeed, StatPower, StatCritChance, StatCritMultiplier:
+		return true
+	}
+	return false
+}
+
+type StatModifierOp string
+
+const (
+	// Added to the base value
+	ModifierAdd StatModifierOp = "add"
+	// Multiplies base value & additive modifiers. 1.1 = +10%
+	ModifierMultiply StatModifierOp = "multiply"
+	// Replaces the final value. Latest override wins
+	ModifierOverride StatModifierOp = "override"
+)
+
+func (op StatModifierOp) Valid() bool {
+	switch op {
+	case ModifierAdd, ModifierMultiply, ModifierOverride:
+		return true
+	}
+	return false
+}
+
+type StatSourceKind string
+
+const (
+	StatSourceItem   StatSourceKind = "item"
+	StatSourceBuff   StatSourceKind = "buff"
+	StatSourceDebuff StatSourceKind = "debuff"
+	StatSourceWave   StatSourceKind = "wave"
+	StatSourcePhase  StatSourceKind = "phase"
+)
+
+// What caused a stat modification. Used to remove & display modifiers
+type StatSource struct {
+	Kind StatSourceKind
+	Name string
+}
+
+func (s StatSource) String() string { return fmt.Sprintf("%s: %s", s.Kind, s.Name) }
+
+// Handle to remove a single modifier. 0 is never assigned
+type StatModifierId int
+
+type StatModifier struct {
+	Id     StatModifierId
+	Stat   Stat
+	Op     StatModifierOp
+	Value  float64
+	Source StatSource
+}
+
+func (m StatModifier) String() string {
+	switch m.Op {
+	case ModifierMultiply:
+		return fmt.Sprintf("x%.2f (%s)", m.Value, m.Source)
+	case ModifierOverride:
+		return fmt.Sprintf("=%.2f (%s)", m.Value, m.Source)
+	}
+	return fmt.Sprintf("%+.2f (%s)", m.Value, m.Source)
+}
+
+// Stats are calculated from base values & a stack of modifiers
+type StatModifierStack interface {
+	// Returns handle to remove the modifier again
+	AddModifier(StatModifier) StatModifierId
+	RemoveModifier(StatModifierId) bool
+	// Returns number of removed modifiers
+	RemoveModifiersFrom(StatSource) int
+	// Modifiers of the stat in order of application
+	Modifiers(Stat) []StatModifier
+	// Value without any modifiers
+	BaseStat(Stat) float64
+}
+
+func (s *GameEntityStats) AddModifier(m StatModifier) StatModifierId {
+	s.nextModifierId++
+	m.Id = s.nextModifierId
+	s.modifiers = append(s.modifiers, m)
+	s.clampHealth()
+	return m.Id
+}
+
+func (s *GameEntityStats) RemoveModifier(id StatModifierId) bool {
+	count := len(s.modifiers)
+	s.modifiers = slices.DeleteFunc(s.modifiers, func(m StatModifier) bool { return m.Id == id })
+	s.clampHealth()
+	return len(s.modifiers) < count
+}
+
+func (s *GameEntityStats) RemoveModifiersFrom(source StatSource) int {
+	count := len(s.modifiers)
+	s.modifiers = slices.DeleteFunc(s.modifiers, func(m StatModifier) bool { return m.Source == source })
+	s.clampHealth()
+	return count - len(s.modifiers)
+}
+
+func (s *GameEntityStats) Modifiers(stat Stat) []StatModifier {
+	res := []StatModifier{}
+	for _, m := range s.modifiers {
+		if m.Stat == stat {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+func (s *GameEntityStats) BaseStat(stat Stat) float64 {
+	if base := s.base(stat); base != nil {
+		return *base
+	}
+	return 0
+}
+
+// (base + additive) * multiplicative, unless overridden
+func (s *GameEntityStats) final(stat Stat, base float64) float64 {
+	value, factor := base, 1.0
+	overridden, override := false, 0.0
+	for _, m := range s.modifiers {
+		if m.Stat != stat {
+			continue
+		}
+		switch m.Op {
+		case ModifierAdd:
+			value += m.Value
+		case ModifierMultiply:
+			factor *= m.Value
+		case ModifierOverride:
+			overridden, override = true, m.Value
+		}
+	}
+	if overridden {
+		return override
+	}
+	return value * factor
+}
+
+func (s *GameEntityStats) base(stat Stat) *float64 {
+	switch stat {
+	case StatArmor:
+		return &s.armor
+	case StatAtkSpeed:
+		return &s.atkSpeed
+	case StatMaxHealth:
+		return &s.maxHealth
+	case StatMovementSpeed:
+		return &s.movementSpeed
+	case StatPower:
+		return &s.power
+	case StatCritChance:
+		return &s.critChance
+	case StatCritMultiplier:
+		return &s.critMultiplier
+	}
+	return nil
+}
+
+// Losing max health modifiers must not leave entities above max health
+func (s *GameEntityStats) clampHealth() {
+	s.health = min(s.health, s.MaxHealth())
+}
+
+// Lines explaining how the final value of the stat is calculated
+func StatBreakdown(stack StatModifierStack, stat Stat) []string {
+	res := []string{fmt.Sprintf("Base %.2f", stack.BaseStat(stat))}
+	for _, m := range stack.Modifiers(stat) {
+		res = append(res, m.String())
+	}
+	return res
+}
@@ -0,0 +1,58 @@
+package engine
+
+import "testing"
+
+func TestStatModifierStack(t *testing.T) {
+	stats := DefaultGameEntityStats()
+	item := StatSource{Kind: StatSourceItem, Name: "Sword"}
+	buff := StatSource{Kind: StatSourceBuff, Name: "Rage"}
+	stats.AddModifier(StatModifier{Stat: StatPower, Op: ModifierAdd, Value: 10, Source: item})
+	rage := stats.AddModifier(StatModifier{Stat: StatPower, Op: ModifierMultiply, Value: 1.5, Source: buff})
+	// (30 + 10) * 1.5
+	if stats.Power() != 60 {
+		t.Fatalf("Expected power 60, got %f", stats.Power())
+	}
+	if stats.BaseStat(StatPower) != 30 {
+		t.Fatalf("Expected base power 30, got %f", stats.BaseStat(StatPower))
+	}
+
+	override := stats.AddModifier(StatModifier{Stat: StatPower, Op: ModifierOverride, Value: 1, Source: buff})
+	if stats.Power() != 1 {
+		t.Fatalf("Expected overridden power 1, got %f", stats.Power())
+	}
+	stats.RemoveModifier(override)
+	stats.RemoveModifier(rage)
+	if stats.Power() != 40 {
+		t.Fatalf("Expected power 40 after removing buffs, got %f", stats.Power())
+	}
+	if removed := stats.RemoveModifiersFrom(item); removed != 1 || stats.Power() != 30 {
+		t.Fatalf("Expected item modifier removed, got %d removed & power %f", removed, stats.Power())
+	}
+}
+
+func TestStatModifierMaxHealthClampsHealth(t *testing.T) {
+	stats := DefaultGameEntityStats()
+	id := stats.AddModifier(StatModifier{Stat: StatMaxHealth, Op: ModifierAdd, Value: 50})
+	stats.SetHealth(150)
+	if stats.Health() != 150 {
+		t.Fatalf("Expected health 150, got %f", stats.Health())
+	}
+	stats.RemoveModifier(id)
+	if stats.Health() != 100 {
+		t.Fatalf("Expected health clamped to 100, got %f", stats.Health())
+	}
+}
+
+func TestStatModifiersSnapshotRoundTrip(t *testing.T) {
+	stats := DefaultGameEntityStats()
+	stats.AddModifier(StatModifier{Stat: StatArmor, Op: ModifierAdd, Value: 10})
+	restored := DefaultGameEntityStats()
+	restored.RestoreStats(stats.StatsSnapshot())
+	if restored.Armor() != 10 || restored.BaseStat(StatArmor) != 0 {
+		t.Fatalf("Expected base armor 0 & final armor 10, got %f & %f", restored.BaseStat(StatArmor), restored.Armor())
+	}
+	// New modifiers must not reuse restored ids
+	if id := restored.AddModifier(StatModifier{Stat: StatArmor, Op: ModifierAdd, Value: 1}); id != 2 {
+		t.Fatalf("Expected next modifier id 2, got %d", id)
+	}
+}
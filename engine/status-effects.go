@@ -0,0 +1,288 @@
+package engine
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/damage"
+)
+
+type StatusEffectKind string
+
+const (
+	StatusSlow       StatusEffectKind = "slow"
+	StatusBurn       StatusEffectKind = "burn"
+	StatusPoison     StatusEffectKind = "poison"
+	StatusStun       StatusEffectKind = "stun"
+	StatusArmorShred StatusEffectKind = "armor-shred"
+)
+
+const (
+	// Seconds between two damage over time ticks
+	statusEffectTickInterval = 0.5
+	// Entities should never be slowed to a halt. Use stun for that
+	maxSlow = 0.9
+	// Size of the icons drawn above the health bar
+	statusIconSize   = 6.0
+	statusIconMargin = 2.0
+)
+
+// Timed effect that can be applied to any entity with status effects
+type StatusEffect struct {
+	Kind StatusEffectKind
+	// Seconds
+	Duration float64
+	// Strength of a single stack
+	// - Slow: Fraction of movement speed removed
+	// - Burn & poison: Damage per second
+	// - Armor shred: Armor removed
+	// - Stun: Unused
+	Magnitude float64
+	// Reapplying adds a stack until MaxStacks is reached. Every application refreshes the duration
+	MaxStacks int
+}
+
+// Entities affected by status effects
+type StatusEffectCarrier interface {
+	StatusEffects() *StatusEffects
+}
+
+// Persisted state of an active status effect. Kill attribution is not persisted
+type StatusEffectSnapshot struct {
+	StatusEffect
+	Stacks   int
+	Modifier StatModifierId `json:",omitempty"`
+	Timeout  TimerSnapshot
+	Tick     TimerSnapshot
+}
+
+type activeStatusEffect struct {
+	StatusEffect
+	stacks int
+	// Entity that applied the effect. Damage over time kills are attributed to it
+	source  GameEntity
+	timeout Timeout
+	// Time until next damage over time tick
+	tick Timeout
+	// Stat modifier currently applied to the target. Removed on expiry
+	modifier StatModifierId
+}
+
+// Stacks, refreshes & expires timed effects on an entity
+type StatusEffects struct {
+	target GameEntityStatReadWriter
+	time   IngameTimeProvider
+	// Ordered by first application to keep damage ticks deterministic
+	active []*activeStatusEffect
+}
+
+func NewStatusEffects(target GameEntityStatReadWriter, time IngameTimeProvider) (*StatusEffects, error) {
+	if target == nil || time == nil {
+		return nil, fmt.Errorf("Cannot init status effects without target & time provider")
+	}
+	return &StatusEffects{target: target, time: time}, nil
+}
+
+// Add effect or stack & refresh it, if already active
+func (s *StatusEffects) Apply(effect StatusEffect, source GameEntity) error {
+	if effect.Duration <= 0 {
+		return fmt.Errorf("Invalid duration for %s effect: %f", effect.Kind, effect.Duration)
+	}
+	active := s.find(effect.Kind)
+	if active == nil {
+		timeout, err := NewIngameTimeout(s.time)
+		if err != nil {
+			return err
+		}
+		tick, err := NewIngameTimeout(s.time)
+		if err != nil {
+			return err
+		}
+		active = &activeStatusEffect{StatusEffect: effect, timeout: timeout, tick: tick}
+		s.active = append(s.active, active)
+		tick.Set(statusEffectTickInterval)
+	}
+	// Strongest application wins
+	active.Magnitude = math.Max(active.Magnitude, effect.Magnitude)
+	active.MaxStacks = effect.MaxStacks
+	if active.stacks < max(1, active.MaxStacks) {
+		active.stacks++
+	}
+	active.source = source
+	active.timeout.Set(effect.Duration)
+	s.applyStats(active)
+	return nil
+}
+
+// Tick damage over time & remove expired effects
+func (s *StatusEffects) Update(applier DamageApplier) {
+	remaining := s.active[:0]
+	for _, active := range s.active {
+		if err := s.tickDamage(active, applier); err != nil {
+			log.Printf("Could not apply %s damage: %s\n", active.Kind, err.Error())
+		}
+		if active.timeout.Done() {
+			s.revertStats(active)
+			continue
+		}
+		remaining = append(remaining, active)
+	}
+	clear(s.active[len(remaining):])
+	s.active = remaining
+}
+
+// Remove all effects & revert their stat changes
+func (s *StatusEffects) Clear() {
+	for _, active := range s.active {
+		s.revertStats(active)
+	}
+	s.active = nil
+}
+
+func (s *StatusEffects) Has(kind StatusEffectKind) bool { return s.find(kind) != nil }
+func (s *StatusEffects) Stunned() bool                  { return s.Has(StatusStun) }
+func (s *StatusEffects) Stacks(kind StatusEffectKind) int {
+	if active := s.find(kind); active != nil {
+		return active.stacks
+	}
+	return 0
+}
+
+// Draws one icon per active effect above the health bar of the shape
+func (s *StatusEffects) Draw(t RenderingTarget, shape *cp.Shape) {
+	bb := shape.BB()
+	// Healthbar takes 16px above the character BB
+	bottom := bb.B - 16.0 - statusIconMargin
+	for idx, active := range s.active {
+		left := bb.L + float64(idx)*(statusIconSize+statusIconMargin)
+		topLeft := cp.Vector{X: left, Y: bottom - statusIconSize}
+		botRight := cp.Vector{X: left + statusIconSize, Y: bottom}
+		t.FillRect(topLeft, botRight, statusEffectColor(active.Kind), false)
+		// Thicker outline for stacked effects
+		t.StrokeRect(topLeft, botRight, float32(min(active.stacks, 3)), color.Black, false)
+	}
+}
+
+func (s *StatusEffects) Snapshot() []StatusEffectSnapshot {
+	res := []StatusEffectSnapshot{}
+	for _, active := range s.active {
+		res = append(res, StatusEffectSnapshot{
+			StatusEffect: active.StatusEffect,
+			Stacks:       active.stacks,
+			Modifier:     active.modifier,
+			Timeout:      active.timeout.Snapshot(),
+			Tick:         active.tick.Snapshot(),
+		})
+	}
+	return res
+}
+
+// Stat modifiers are expected to be part of the restored stats already
+func (s *StatusEffects) Restore(snapshots []StatusEffectSnapshot) error {
+	s.active = nil
+	for _, snapshot := range snapshots {
+		timeout, err := NewIngameTimeout(s.time)
+		if err != nil {
+			return err
+		}
+		tick, err := NewIngameTimeout(s.time)
+		if err != nil {
+			return err
+		}
+		timeout.Restore(snapshot.Timeout)
+		tick.Restore(snapshot.Tick)
+		s.active = append(s.active, &activeStatusEffect{
+			StatusEffect: snapshot.StatusEffect,
+			stacks:       snapshot.Stacks,
+			modifier:     snapshot.Modifier,
+			timeout:      timeout,
+			tick:         tick,
+		})
+	}
+	return nil
+}
+
+func (s *StatusEffects) find(kind StatusEffectKind) *activeStatusEffect {
+	for _, active := range s.active {
+		if active.Kind == kind {
+			return active
+		}
+	}
+	return nil
+}
+
+// (Re-)apply stat modifier for the current number of stacks
+func (s *StatusEffects) applyStats(active *activeStatusEffect) {
+	s.revertStats(active)
+	strength := active.Magnitude * float64(active.stacks)
+	source := StatSource{Kind: StatSourceDebuff, Name: string(active.Kind)}
+	switch active.Kind {
+	case StatusSlow:
+		active.modifier = s.target.AddModifier(StatModifier{Stat: StatMovementSpeed, Op: ModifierMultiply, Value: 1 - math.Min(strength, maxSlow), Source: source})
+	case StatusArmorShred:
+		active.modifier = s.target.AddModifier(StatModifier{Stat: StatArmor, Op: ModifierAdd, Value: -strength, Source: source})
+	}
+}
+
+func (s *StatusEffects) revertStats(active *activeStatusEffect) {
+	if active.modifier != 0 {
+		s.target.RemoveModifier(active.modifier)
+		active.modifier = 0
+	}
+}
+
+// Damage over time goes through the damage model, so resistances apply
+func (s *StatusEffects) tickDamage(active *activeStatusEffect, applier DamageApplier) error {
+	damageType, ok := statusEffectDamageTypes[active.Kind]
+	if !ok || !active.tick.Done() {
+		return nil
+	}
+	active.tick.Set(statusEffectTickInterval)
+	def, ok := s.target.(damage.Defender)
+	if !ok || applier == nil {
+		return nil
+	}
+	atk := &statusEffectAttacker{
+		power:      active.Magnitude * float64(active.stacks) * statusEffectTickInterval,
+		damageType: damageType,
+		owner:      active.source,
+	}
+	_, err := applier.ApplyDamage(atk, def)
+	return err
+}
+
+var statusEffectDamageTypes = map[StatusEffectKind]damage.DamageType{
+	StatusBurn:   damage.Fire,
+	StatusPoison: damage.Poison,
+}
+
+func statusEffectColor(kind StatusEffectKind) color.Color {
+	switch kind {
+	case StatusSlow:
+		return color.RGBA{80, 160, 255, 255}
+	case StatusBurn:
+		return color.RGBA{255, 120, 0, 255}
+	case StatusPoison:
+		return color.RGBA{60, 200, 60, 255}
+	case StatusStun:
+		return color.RGBA{255, 230, 0, 255}
+	case StatusArmorShred:
+		return color.RGBA{150, 150, 150, 255}
+	}
+	return color.White
+}
+
+// Single damage over time tick
+type statusEffectAttacker struct {
+	power      float64
+	damageType damage.DamageType
+	owner      GameEntity
+}
+
+func (a *statusEffectAttacker) Power() float64                { return a.power }
+func (a *statusEffectAttacker) AtkSpeed() float64             { return 1 / statusEffectTickInterval }
+func (a *statusEffectAttacker) DamageType() damage.DamageType { return a.damageType }
+func (a *statusEffectAttacker) AttackOwner() GameEntity       { return a.owner }
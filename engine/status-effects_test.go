@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/damage"
+)
+
+func newStatusTestNpc(t *testing.T, w *GameWorld) *NpcEntity {
+	asset, err := w.AssetManager.CharacterAsset("tree_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	npc, err := NewNpc(asset, NpcOpts{BaseMovementSpeed: 100, StartingPos: cp.Vector{X: 100, Y: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddEntity(npc); err != nil {
+		t.Fatal(err)
+	}
+	return npc
+}
+
+func TestStatusEffectSlowStacksAndReverts(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	npc := newStatusTestNpc(t, w)
+	slow := StatusEffect{Kind: StatusSlow, Duration: 1, Magnitude: 0.2, MaxStacks: 2}
+	for range 3 {
+		if err := npc.StatusEffects().Apply(slow, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stacks := npc.StatusEffects().Stacks(StatusSlow); stacks != 2 {
+		t.Fatalf("Expected 2 stacks, got %d", stacks)
+	}
+	if npc.MovementSpeed() != 60 {
+		t.Fatalf("Expected slowed speed 60, got %f", npc.MovementSpeed())
+	}
+
+	*w.gameTime += 1.1
+	w.updateStatusEffects()
+	if npc.StatusEffects().Has(StatusSlow) {
+		t.Fatal("Expected slow to expire")
+	}
+	if npc.MovementSpeed() != 100 {
+		t.Fatalf("Expected speed 100 after expiry, got %f", npc.MovementSpeed())
+	}
+}
+
+func TestStatusEffectDamageOverTimeAttributedToSource(t *testing.T) {
+	w, player := newDamageTestWorld(t)
+	w.damageModel.(*damage.BasicDamageModel).SetVariance(0)
+	if err := w.AddEntity(player); err != nil {
+		t.Fatal(err)
+	}
+	npc := newStatusTestNpc(t, w)
+	var applied *DamageApplied
+	Subscribe(w.Events(), func(e DamageApplied) { applied = &e })
+
+	burn := StatusEffect{Kind: StatusBurn, Duration: 2, Magnitude: 10, MaxStacks: 1}
+	if err := npc.StatusEffects().Apply(burn, player); err != nil {
+		t.Fatal(err)
+	}
+	*w.gameTime += 0.6
+	w.updateStatusEffects()
+
+	if applied == nil {
+		t.Fatal("Expected damage over time tick")
+	}
+	if applied.Record.Type != damage.Fire || applied.Record.Damage != 5 {
+		t.Fatalf("Expected 5 fire damage, got %s", applied.Record.String())
+	}
+	if applied.Record.AttackerId != int(player.Id()) {
+		t.Fatalf("Expected tick attributed to %d, got %d", player.Id(), applied.Record.AttackerId)
+	}
+}
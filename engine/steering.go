@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"github.com/jakecoffman/cp"
+)
+
+// Weights & ranges of the forces that make up npc movement
+type SteeringOpts struct {
+	// Weight of moving towards the destination
+	Seek float64
+	// Weight of keeping distance to other npcs
+	Separation float64
+	// Weight of keeping distance to walls, towers & trees
+	Avoidance float64
+	// Braking behind npcs in front. 0 disables queueing, 1 stops completely
+	Queue float64
+	// Gap kept between the bounding boxes of npcs
+	SeparationRadius float64
+	// Obstacles closer than this start to push
+	AvoidanceRadius float64
+	// Slow down within this distance of the goal
+	ArriveRadius float64
+	// Max velocity change per second, relative to movement speed. Dampens jitter in crowds
+	Responsiveness float64
+}
+
+func DefaultSteeringOpts() SteeringOpts {
+	return SteeringOpts{
+		Seek:             1,
+		Separation:       1.5,
+		Avoidance:        1,
+		Queue:            0.8,
+		SeparationRadius: 6,
+		AvoidanceRadius:  12,
+		ArriveRadius:     24,
+		Responsiveness:   8,
+	}
+}
+
+var (
+	// Npcs do not collide with each other. Separation is the only thing keeping them apart
+	steeringNeighbourFilter = cp.NewShapeFilter(cp.NO_GROUP, cp.ALL_CATEGORIES, NpcCategory)
+	// Includes trees, which npcs could walk through
+	steeringObstacleFilter = cp.NewShapeFilter(cp.NO_GROUP, cp.ALL_CATEGORIES, OuterWallsCategory|TowerCategory|HarvestableCategory)
+)
+
+// Arriving npcs keep moving at least at this fraction of their speed, so they reach the goal
+const minArriveSpeed = 0.25
+
+// Velocity towards dst combining seek, separation, avoidance & queueing.
+// Goal is the shape at the end of the way, e.g. the attack target. It is not avoided, but approached slowly.
+// Nil while following waypoints
+func (n *NpcEntity) steer(body *cp.Body, dst cp.Vector, goal *cp.Shape, dt float64) cp.Vector {
+	opts := n.steering
+	pos := body.Position()
+	speed := n.MovementSpeed()
+	radius := AgentRadius(n.shape)
+	heading := cp.Vector{}
+	if diff := dst.Sub(pos); diff.LengthSq() > 0 {
+		heading = diff.Normalize()
+	}
+
+	// Seek & arrive
+	seekSpeed := speed
+	if goal != nil && opts.ArriveRadius > 0 {
+		gap := goal.PointQuery(pos).Distance - radius
+		seekSpeed *= cp.Clamp(gap/opts.ArriveRadius, minArriveSpeed, 1)
+	}
+	force := heading.Mult(seekSpeed * opts.Seek)
+
+	space := n.shape.Space()
+	if space != nil {
+		separation, brake := n.separation(space, pos, radius, heading)
+		force = force.Add(separation.Mult(speed * opts.Separation))
+		force = force.Add(n.avoidance(space, pos, radius, goal).Mult(speed * opts.Avoidance))
+		force = force.Mult(1 - cp.Clamp01(opts.Queue)*brake)
+	}
+	desired := force.Clamp(speed)
+
+	// Limit acceleration
+	if opts.Responsiveness <= 0 {
+		return desired
+	}
+	current := body.Velocity()
+	return current.Add(desired.Sub(current).Clamp(speed * opts.Responsiveness * dt))
+}
+
+// Push away from nearby npcs. Brake is the strength of the closest npc in front, in [0,1]
+func (n *NpcEntity) separation(space *cp.Space, pos cp.Vector, radius float64, heading cp.Vector) (cp.Vector, float64) {
+	res := cp.Vector{}
+	brake := 0.0
+	reach := 2*radius + n.steering.SeparationRadius
+	space.BBQuery(cp.NewBBForCircle(pos, reach), steeringNeighbourFilter, func(shape *cp.Shape, _ interface{}) {
+		if shape == n.shape {
+			return
+		}
+		limit := radius + AgentRadius(shape) + n.steering.SeparationRadius
+		offset := pos.Sub(shape.Body().Position())
+		dist := offset.Length()
+		if dist >= limit {
+			return
+		}
+		// Stacked on the same spot: Split up by id to stay deterministic
+		if dist < 1e-6 {
+			offset = cp.ForAngle(float64(n.Id()))
+			dist = 1
+		}
+		strength := 1 - dist/limit
+		res = res.Add(offset.Mult(strength / dist))
+		// Neighbour is in front
+		if offset.Dot(heading) < 0 {
+			brake = max(brake, strength)
+		}
+	}, nil)
+	return res, brake
+}
+
+// Push away from obstacles & slide along them
+func (n *NpcEntity) avoidance(space *cp.Space, pos cp.Vector, radius float64, goal *cp.Shape) cp.Vector {
+	res := cp.Vector{}
+	avoidRadius := n.steering.AvoidanceRadius
+	if avoidRadius <= 0 {
+		return res
+	}
+	space.BBQuery(cp.NewBBForCircle(pos, radius+avoidRadius), steeringObstacleFilter, func(shape *cp.Shape, _ interface{}) {
+		if shape == goal {
+			return
+		}
+		info := shape.PointQuery(pos)
+		clearance := info.Distance - radius
+		if clearance >= avoidRadius {
+			return
+		}
+		strength := 1 - max(clearance, 0)/avoidRadius
+		// Gradient points away from the obstacle. Sliding towards the closer end keeps npcs from stalling in front of it
+		tangent := info.Gradient.Perp()
+		if tangent.Dot(pos.Sub(shape.BB().Center())) < 0 {
+			tangent = tangent.Neg()
+		}
+		res = res.Add(info.Gradient.Add(tangent.Mult(0.5)).Mult(strength))
+	}, nil)
+	return res
+}
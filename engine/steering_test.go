@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+func newSteeringTestTree(tb testing.TB, w *GameWorld, pos cp.Vector) *TreeEntity {
+	tree := newDamageTestTree(tb, w)
+	tree.Shape().Body().SetPosition(pos)
+	if err := w.AddEntity(tree); err != nil {
+		tb.Fatal(err)
+	}
+	return tree
+}
+
+func newSteeringTestNpc(tb testing.TB, w *GameWorld, target DefenderEntity, pos cp.Vector, steering *SteeringOpts) *NpcAggro {
+	asset, err := w.AssetManager.CharacterAsset("npc-orc")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	npc, err := NewNpcAggro(target, asset, NpcOpts{BaseHealth: 100, StartingPos: pos, Steering: steering})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := w.AddEntity(npc); err != nil {
+		tb.Fatal(err)
+	}
+	return npc
+}
+
+// Smallest distance between any two npcs
+func minNpcDistance(npcs []*NpcAggro) float64 {
+	res := cp.INFINITY
+	for i := range npcs {
+		for j := i + 1; j < len(npcs); j++ {
+			res = min(res, npcs[i].Body().Position().Distance(npcs[j].Body().Position()))
+		}
+	}
+	return res
+}
+
+func TestSeparationSpreadsStackedNpcs(t *testing.T) {
+	for _, separate := range []bool{true, false} {
+		w, _ := newDamageTestWorld(t)
+		target := newSteeringTestTree(t, w, cp.Vector{X: 800, Y: 500})
+		steering := DefaultSteeringOpts()
+		if !separate {
+			steering.Separation = 0
+		}
+		npcs := []*NpcAggro{}
+		for range 10 {
+			npcs = append(npcs, newSteeringTestNpc(t, w, target, cp.Vector{X: 200, Y: 500}, &steering))
+		}
+		for range 120 {
+			w.Update()
+		}
+		dist := minNpcDistance(npcs)
+		if separate && dist < 8 {
+			t.Errorf("Expected npcs to spread out, got min distance %f", dist)
+		}
+		if !separate && dist > 1 {
+			t.Errorf("Expected npcs without separation to stay stacked, got min distance %f", dist)
+		}
+	}
+}
+
+func TestAvoidanceSteersAroundTrees(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	target := newSteeringTestTree(t, w, cp.Vector{X: 600, Y: 500})
+	obstacle := newSteeringTestTree(t, w, cp.Vector{X: 400, Y: 500})
+	npc := newSteeringTestNpc(t, w, target, cp.Vector{X: 200, Y: 500}, nil)
+	for range 600 {
+		w.Update()
+		if dist := obstacle.Shape().PointQuery(npc.Body().Position()).Distance; dist < 0 {
+			t.Fatalf("Expected npc to walk around tree, got inside at %v", npc.Body().Position())
+		}
+		if npc.attacking {
+			return
+		}
+	}
+	t.Errorf("Expected npc to reach target, stopped at %v", npc.Body().Position())
+}
+
+// Step time of a wave converging on a single target
+func BenchmarkCrowd(b *testing.B) {
+	w, err := NewWorld(2000, 2000, 1, loot.DefaultResourceRegistry())
+	if err != nil {
+		b.Fatal(err)
+	}
+	nav, err := w.BuildNavigation(DefaultNavCellSize)
+	if err != nil {
+		b.Fatal(err)
+	}
+	target := newSteeringTestTree(b, w, cp.Vector{X: 1000, Y: 1000})
+	for range 600 {
+		pos := cp.Vector{X: 100 + w.Rng().Float64()*1800, Y: 100 + w.Rng().Float64()*1800}
+		npc := newSteeringTestNpc(b, w, target, pos, nil)
+		npc.SetNavigation(nav)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		w.Update()
+	}
+}
@@ -8,6 +8,11 @@ import (
 )
 
 type Tileset struct {
+	// Shared between all copies of the tileset, so images can be swapped on hot reload
+	*tileImages
+}
+
+type tileImages struct {
 	images []*ebiten.Image
 }
 
@@ -34,12 +39,18 @@ func NewTileset(tilesetImage *ebiten.Image, tileSizeX, tileSizeY int, scale floa
 			tileIdx++
 		}
 	}
-	return &Tileset{images: images}, nil
+	return &Tileset{&tileImages{images}}, nil
 }
 
 func (t *Tileset) GetTile(tileIdx int) (*ebiten.Image, error) {
+	if t.tileImages == nil {
+		return nil, fmt.Errorf("Tileset not initialized")
+	}
 	if tileIdx < 0 || tileIdx > len(t.images)-1 {
 		return nil, fmt.Errorf("Tileset out of bounds! Unknown index %d / %d", tileIdx, len(t.images)-1)
 	}
 	return t.images[tileIdx], nil
 }
+
+// Replace images of this & all copies of the tileset
+func (t *Tileset) swap(other Tileset) { t.tileImages.images = other.images }
@@ -16,6 +16,14 @@ type Timeout interface {
 	Set(seconds float64)
 	Done() bool
 	Timer
+	Snapshot() TimerSnapshot
+	Restore(TimerSnapshot)
+}
+
+// Persisted state of a timeout. Start time is absolute ingame time
+type TimerSnapshot struct {
+	StartedAt float64
+	Timeout   float64
 }
 
 type BaseTimeout struct {
@@ -51,6 +59,15 @@ func (t *BaseTimeout) Done() bool {
 	return false
 }
 
+func (t *BaseTimeout) Snapshot() TimerSnapshot {
+	return TimerSnapshot{StartedAt: t.startedAt, Timeout: t.timeout}
+}
+
+func (t *BaseTimeout) Restore(s TimerSnapshot) {
+	t.startedAt = s.StartedAt
+	t.timeout = s.Timeout
+}
+
 type TimeFunc func() float64
 
 type BaseTimer struct {
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/damage"
 	"github.com/lucb31/game-engine-go/engine/loot"
 )
 
@@ -48,9 +49,9 @@ func NewTree(asset *CharacterAsset) (*TreeEntity, error) {
 	if err != nil {
 		return nil, err
 	}
-	loot := loot.NewResourcesLootTable()
-	loot.AddWood(5)
-	t.loot = loot
+	table := loot.NewResourcesLootTable()
+	table.Add(loot.ResourceWood, 5)
+	t.loot = table
 	return t, nil
 }
 
@@ -59,9 +60,9 @@ func NewBush(asset *CharacterAsset) (*TreeEntity, error) {
 	if err != nil {
 		return nil, err
 	}
-	loot := loot.NewResourcesLootTable()
-	loot.AddWood(2)
-	t.loot = loot
+	table := loot.NewResourcesLootTable()
+	table.Add(loot.ResourceWood, 2)
+	t.loot = table
 	t.health = 50.0
 	t.maxHealth = 50.0
 	return t, nil
@@ -72,13 +73,34 @@ func (p *TreeEntity) Draw(t RenderingTarget) error {
 	return nil
 }
 
-func (p *TreeEntity) Id() GameEntityId          { return p.id }
-func (p *TreeEntity) SetId(id GameEntityId)     { p.id = id }
-func (p *TreeEntity) Shape() *cp.Shape          { return p.shape }
-func (p *TreeEntity) LootTable() loot.LootTable { return p.loot }
-func (p *TreeEntity) Position() cp.Vector       { return p.Shape().Body().Position() }
-func (p *TreeEntity) Health() float64           { return p.health }
-func (p *TreeEntity) SetHealth(v float64)       { p.health = v }
-func (p *TreeEntity) Armor() float64            { return 0 }
-func (p *TreeEntity) IsVulnerable() bool        { return true }
-func (p *TreeEntity) SetPosition(pos cp.Vector) { p.Shape().Body().SetPosition(pos) }
+func (p *TreeEntity) Id() GameEntityId                     { return p.id }
+func (p *TreeEntity) SetId(id GameEntityId)                { p.id = id }
+func (p *TreeEntity) Shape() *cp.Shape                     { return p.shape }
+func (p *TreeEntity) LootTable() loot.LootTable            { return p.loot }
+func (p *TreeEntity) SetLootTable(t loot.LootTable)        { p.loot = t }
+func (p *TreeEntity) Position() cp.Vector                  { return p.Shape().Body().Position() }
+func (p *TreeEntity) DropsLoot() bool                      { return true }
+func (p *TreeEntity) Resistance(damage.DamageType) float64 { return 0 }
+func (p *TreeEntity) Health() float64                      { return p.health }
+func (p *TreeEntity) SetHealth(v float64)                  { p.health = v }
+func (p *TreeEntity) Armor() float64                       { return 0 }
+func (p *TreeEntity) IsVulnerable() bool                   { return true }
+func (p *TreeEntity) SetPosition(pos cp.Vector)            { p.Shape().Body().SetPosition(pos) }
+
+func (p *TreeEntity) Snapshot() EntitySnapshot {
+	return EntitySnapshot{
+		Kind:     EntityKindTree,
+		Asset:    p.asset.Name(),
+		Position: p.Position(),
+		Stats:    StatsSnapshot{Health: p.health, MaxHealth: p.maxHealth},
+		Loot:     NewLootSnapshot(p.loot),
+	}
+}
+
+func (p *TreeEntity) Restore(s EntitySnapshot) error {
+	p.SetPosition(s.Position)
+	p.health = s.Stats.Health
+	p.maxHealth = s.Stats.MaxHealth
+	p.loot = s.Loot.LootTable()
+	return nil
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/damage"
+)
+
+// Applies damage. Death is resolved by the world at the end of the step
+type DamageApplier interface {
+	// Returns nil record, if defender has already died this step
+	ApplyDamage(atk damage.Attacker, def damage.Defender) (*damage.DamageRecord, error)
+	// Kills without a damage roll, e.g. when self destructing. Loot is only distributed if requested
+	Kill(def damage.Defender, killer GameEntity, withLoot bool)
+}
+
+// Attackers acting on behalf of another entity, e.g. projectiles fired from a gun.
+// Kills are attributed to the owner
+type OwnedAttacker interface {
+	AttackOwner() GameEntity
+}
+
+// Entities that drop their loot on the ground, instead of adding it to the killers inventory
+type LootDropper interface {
+	DropsLoot() bool
+}
+
+// Runs when the entity dies, instead of simply destroying it.
+// Use to play dying animations or trigger game over
+type DeathHook interface {
+	OnDeath(killer GameEntity) error
+}
+
+type pendingDeath struct {
+	defender damage.Defender
+	killer   GameEntity
+	record   damage.DamageRecord
+	noLoot   bool
+}
+
+// Calculate damage via damage model, apply health change & queue death
+func (w *GameWorld) ApplyDamage(atk damage.Attacker, def damage.Defender) (*damage.DamageRecord, error) {
+	// Dead entities stay in the space until the end of the step. Do not kill twice
+	if def.Health() <= 0 {
+		return nil, nil
+	}
+	hit, err := w.damageModel.CalculateDamage(atk, def)
+	if err != nil {
+		return nil, err
+	}
+	newHealth := def.Health() - hit.Damage
+	def.SetHealth(newHealth)
+
+	killer := attackingEntity(atk)
+	attackerId := -1
+	if killer != nil {
+		attackerId = int(killer.Id())
+	}
+	rec := damage.DamageRecord{
+		GameTime:   w.IngameTime(),
+		Damage:     hit.Damage,
+		Pos:        def.Shape().Body().Position(),
+		Fatal:      newHealth <= 0.0,
+		Type:       hit.Type,
+		Crit:       hit.Crit,
+		Mitigated:  hit.Mitigated,
+		AttackerId: attackerId,
+	}
+	if err := w.damageModel.DamageLog().Add(rec); err != nil {
+		log.Println("Could not log damage record. Continuing anyways...", rec, err.Error())
+	}
+	Publish(w.events, DamageApplied{atk, def, rec})
+
+	if rec.Fatal {
+		w.pendingDeaths = append(w.pendingDeaths, pendingDeath{def, killer, rec, false})
+	}
+	return &rec, nil
+}
+
+func (w *GameWorld) Kill(def damage.Defender, killer GameEntity, withLoot bool) {
+	if def.Health() <= 0 {
+		return
+	}
+	def.SetHealth(0)
+	attackerId := -1
+	if killer != nil {
+		attackerId = int(killer.Id())
+	}
+	rec := damage.DamageRecord{
+		GameTime:   w.IngameTime(),
+		Pos:        def.Shape().Body().Position(),
+		Fatal:      true,
+		AttackerId: attackerId,
+	}
+	w.pendingDeaths = append(w.pendingDeaths, pendingDeath{def, killer, rec, !withLoot})
+}
+
+// Resolve the entity responsible for the attack
+func attackingEntity(atk damage.Attacker) GameEntity {
+	if owned, ok := atk.(OwnedAttacker); ok {
+		return owned.AttackOwner()
+	}
+	if entity, ok := atk.(GameEntity); ok {
+		return entity
+	}
+	return nil
+}
+
+// Run death hooks & distribute loot for all entities that died during this step
+func (w *GameWorld) resolveDeaths() {
+	deaths := w.pendingDeaths
+	w.pendingDeaths = nil
+	for _, death := range deaths {
+		if err := w.resolveDeath(death); err != nil {
+			log.Println("Error while resolving death: ", err.Error())
+		}
+	}
+}
+
+func (w *GameWorld) resolveDeath(death pendingDeath) error {
+	entity, ok := death.defender.(GameEntity)
+	if !ok {
+		return fmt.Errorf("Expected game entity for defender")
+	}
+	if hook, ok := entity.(DeathHook); ok {
+		if err := hook.OnDeath(death.killer); err != nil {
+			return err
+		}
+	} else if err := entity.Destroy(); err != nil {
+		return err
+	}
+	Publish(w.events, EntityKilled{entity, death.killer, death.record})
+	if death.noLoot {
+		return nil
+	}
+	return w.distributeLoot(entity, death.killer)
+}
+
+// Loot goes to the killers inventory, if there is one. Otherwise it's dropped
+func (w *GameWorld) distributeLoot(entity GameEntity, killer GameEntity) error {
+	table := entity.LootTable()
+	pos := entity.Shape().Body().Position()
+	dropper, drops := entity.(LootDropper)
+	receiver, isReceiver := killer.(GameEntityWithInventory)
+	if (!drops || !dropper.DropsLoot()) && isReceiver && receiver.Inventory() != nil {
+		items := table.Result(w.rng)
+		if err := receiver.Inventory().AddLoot(items); err != nil {
+			return err
+		}
+		Publish(w.events, LootCollected{receiver, items, pos})
+		return nil
+	}
+	// Drop loot at slightly randomized position
+	pos = pos.Add(cp.Vector{X: w.rng.Float64()*20 - 10, Y: w.rng.Float64()*20 - 10})
+	return w.DropLoot(table, pos)
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+// Attacker acting on behalf of another entity, like a projectile
+type ownedTestAttacker struct{ owner GameEntity }
+
+func (a ownedTestAttacker) Power() float64          { return 1000 }
+func (a ownedTestAttacker) AtkSpeed() float64       { return 1 }
+func (a ownedTestAttacker) AttackOwner() GameEntity { return a.owner }
+
+// Tree that hands loot to the killer instead of dropping it
+type pickedTree struct{ *TreeEntity }
+
+func (t pickedTree) DropsLoot() bool { return false }
+
+// Killer with an inventory
+type lootCollector struct {
+	*TreeEntity
+	inventory loot.Inventory
+}
+
+func (c lootCollector) Inventory() loot.Inventory { return c.inventory }
+
+func newDamageTestWorld(t testing.TB) (*GameWorld, lootCollector) {
+	w, err := NewWorld(1000, 1000, 1, loot.DefaultResourceRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inventory, err := loot.NewInventory(w.Resources())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, lootCollector{newDamageTestTree(t, w), inventory}
+}
+
+func newDamageTestTree(t testing.TB, w *GameWorld) *TreeEntity {
+	asset, err := w.AssetManager.CharacterAsset("tree_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := NewTree(asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestKillAttributedToAttackOwner(t *testing.T) {
+	w, player := newDamageTestWorld(t)
+	tree := pickedTree{newDamageTestTree(t, w)}
+	if err := w.AddEntity(tree); err != nil {
+		t.Fatal(err)
+	}
+	var killer GameEntity
+	Subscribe(w.Events(), func(e EntityKilled) { killer = e.Killer })
+
+	rec, err := w.ApplyDamage(ownedTestAttacker{player}, tree)
+	if err != nil || !rec.Fatal {
+		t.Fatalf("Expected fatal damage, got %v %v", rec, err)
+	}
+	// Dead entities cannot be killed twice within the same step
+	if rec, _ := w.ApplyDamage(ownedTestAttacker{player}, tree); rec != nil {
+		t.Error("Expected no damage on dead entity")
+	}
+	w.Update()
+
+	if killer != GameEntity(player) {
+		t.Errorf("Expected kill to be attributed to player, got %v", killer)
+	}
+	if wood := player.Inventory().Resource(loot.ResourceWood).Balance(); wood != 5 {
+		t.Errorf("Expected loot in killer inventory, got %d wood", wood)
+	}
+	if len(w.objects) != 0 {
+		t.Errorf("Expected tree to be removed, got %d objects", len(w.objects))
+	}
+}
+
+func TestKillDropsLoot(t *testing.T) {
+	w, player := newDamageTestWorld(t)
+	tree := newDamageTestTree(t, w)
+	if err := w.AddEntity(tree); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.ApplyDamage(ownedTestAttacker{player}, tree); err != nil {
+		t.Fatal(err)
+	}
+	w.Update()
+
+	if wood := player.Inventory().Resource(loot.ResourceWood).Balance(); wood != 0 {
+		t.Errorf("Expected loot to be dropped, but got %d wood in inventory", wood)
+	}
+	items := 0
+	for _, obj := range w.objects {
+		if _, ok := obj.(*ItemEntity); ok {
+			items++
+		}
+	}
+	if items != 1 {
+		t.Errorf("Expected 1 dropped item, got %d", items)
+	}
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"log"
+	"math"
+	"slices"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+// Dropping items is done by evaluating the input loot table &
+// spawning one item per result. Drops are scattered around the drop position
+func (w *GameWorld) DropLoot(lootTable loot.LootTable, pos cp.Vector) error {
+	for _, lootableItem := range lootTable.Result(w.rng) {
+		itemEntity, err := NewItemEntity(pos)
+		if err != nil {
+			return err
+		}
+		itemEntity.SetLootTable(loot.NewGuaranteedLootTable(lootableItem))
+		appearance := w.applyDropAppearance(itemEntity)
+		if appearance.Sprite != "" {
+			asset, err := w.AssetManager.CharacterAsset(appearance.Sprite)
+			if err != nil {
+				return err
+			}
+			if err := itemEntity.SetAsset(asset); err != nil {
+				return err
+			}
+			if appearance.SpawnAnimation != "" {
+				if err := asset.AnimationController().Play(appearance.SpawnAnimation); err != nil {
+					return err
+				}
+			}
+		}
+		itemEntity.SetLifetime(w.IngameTime(), w.LootDrops.Lifetime)
+		angle := w.rng.Float64() * 2 * math.Pi
+		impulse := cp.ForAngle(angle).Mult(w.rng.Float64() * w.LootDrops.ScatterImpulse)
+		itemEntity.Shape().Body().ApplyImpulseAtLocalPoint(impulse, cp.Vector{})
+		if err := w.AddEntity(itemEntity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rarity & auto pickup are derived from the first item of the drop
+func (w *GameWorld) applyDropAppearance(item *ItemEntity) loot.DropAppearance {
+	contents := item.LootTable().Result(nil)
+	if len(contents) == 0 {
+		return loot.DropAppearance{}
+	}
+	appearance := w.resources.DropAppearance(contents[0])
+	autoPickup := false
+	if resource, ok := contents[0].(*loot.ResourceItem); ok {
+		kind, _ := w.resources.Kind(resource.Resource)
+		autoPickup = kind.AutoPickup
+	}
+	item.SetAppearance(appearance, autoPickup)
+	return appearance
+}
+
+// Despawn expired drops, slow down scattered ones & pull drops towards the player.
+// Sorted by id to keep pickups deterministic
+func (w *GameWorld) updateLootDrops(dt float64) {
+	ids := []GameEntityId{}
+	for id, obj := range w.objects {
+		if _, ok := obj.(*ItemEntity); ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	opts := w.LootDrops
+	for _, id := range ids {
+		item := w.objects[id].(*ItemEntity)
+		if item.Expired(w.IngameTime()) {
+			if err := item.Destroy(); err != nil {
+				log.Println("Could not despawn item", err.Error())
+			}
+			continue
+		}
+		body := item.Shape().Body()
+		if w.player == nil || w.player.Inside() {
+			body.SetVelocityVector(body.Velocity().Mult(math.Pow(opts.ScatterDamping, dt)))
+			continue
+		}
+		offset := w.player.Position().Sub(body.Position())
+		dist := offset.Length()
+		switch {
+		case dist <= playerPickupRange:
+			body.SetVelocityVector(cp.Vector{})
+			if item.AutoPickup() {
+				if err := w.player.ItemPickup(item); err != nil {
+					log.Println("Could not auto pickup item", err.Error())
+				}
+			}
+		case dist < opts.MagnetRadius:
+			body.SetVelocityVector(offset.Normalize().Mult(opts.MagnetSpeed))
+		default:
+			body.SetVelocityVector(body.Velocity().Mult(math.Pow(opts.ScatterDamping, dt)))
+		}
+	}
+}
+
+// Dropped item instances are persisted in addition to resources
+func NewDropSnapshot(item *ItemEntity) []ItemSnapshot {
+	res := []ItemSnapshot{}
+	for _, content := range item.LootTable().Result(nil) {
+		if instance, ok := content.(*loot.ItemInstance); ok {
+			res = append(res, NewItemSnapshot(instance))
+		}
+	}
+	return res
+}
+
+func (w *GameWorld) restoreItemDrop(entity SnapshotEntity, s EntitySnapshot) error {
+	item, ok := entity.(*ItemEntity)
+	if !ok {
+		return nil
+	}
+	contents := s.Loot.LootTable().Result(nil)
+	for _, itemSnapshot := range s.Items {
+		instance, err := itemSnapshot.Restore(w.items)
+		if err != nil {
+			return err
+		}
+		contents = append(contents, instance)
+	}
+	item.SetLootTable(loot.NewGuaranteedLootTable(contents...))
+	w.applyDropAppearance(item)
+	return nil
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+func droppedItems(w *GameWorld) []*ItemEntity {
+	res := []*ItemEntity{}
+	for _, obj := range w.objects {
+		if item, ok := obj.(*ItemEntity); ok {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func TestDropsScatterAndDespawn(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	w.LootDrops.Lifetime = 1
+	table := loot.NewGuaranteedLootTable(loot.NewResourceItem(loot.ResourceWood, 1), loot.NewResourceItem(loot.ResourceGold, 1))
+	if err := w.DropLoot(table, cp.Vector{X: 500, Y: 500}); err != nil {
+		t.Fatal(err)
+	}
+	items := droppedItems(w)
+	if len(items) != 2 {
+		t.Fatalf("Expected one drop per item, got %d", len(items))
+	}
+	for _, item := range items {
+		if item.Shape().Body().Velocity().Length() == 0 {
+			t.Error("Expected drop to be scattered")
+		}
+		if !item.AutoPickup() {
+			t.Error("Expected resource drop to be picked up automatically")
+		}
+	}
+	for range 30 {
+		w.Update()
+	}
+	if len(droppedItems(w)) != 2 {
+		t.Error("Expected drops to exist before lifetime ends")
+	}
+	for range 40 {
+		w.Update()
+	}
+	if len(droppedItems(w)) != 0 {
+		t.Errorf("Expected drops to despawn, got %d", len(droppedItems(w)))
+	}
+}
+
+func TestDroppedItemSnapshot(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	instance, err := w.Items().NewInstance("short-sword", 1, w.Rng())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.DropLoot(loot.NewGuaranteedLootTable(instance), cp.Vector{X: 500, Y: 500}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := w.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, _ := newDamageTestWorld(t)
+	if _, err := restored.Restore(s, NewDefaultEntityFactory(restored.AssetManager)); err != nil {
+		t.Fatal(err)
+	}
+	items := droppedItems(restored)
+	if len(items) != 1 {
+		t.Fatalf("Expected restored drop, got %d", len(items))
+	}
+	contents := items[0].LootTable().Result(nil)
+	if len(contents) != 1 {
+		t.Fatalf("Expected 1 item in drop, got %d", len(contents))
+	}
+	if got, ok := contents[0].(*loot.ItemInstance); !ok || got.Id != instance.Id || got.Item.Id != "short-sword" {
+		t.Errorf("Expected restored short sword #%d, got %v", instance.Id, contents[0])
+	}
+	if items[0].AutoPickup() {
+		t.Error("Expected items to require manual pickup")
+	}
+}
+
+func TestCodeLootTableSnapshot(t *testing.T) {
+	w, _ := newDamageTestWorld(t)
+	tree := newDamageTestTree(t, w)
+	gold := &loot.ResourceRoll{LootEntry: loot.NewLootEntry(0, true, false), Resource: loot.ResourceGold, Min: 3, Max: 3}
+	tree.SetLootTable(loot.NewWeightedLootTable(1, gold))
+	if err := w.AddEntity(tree); err != nil {
+		t.Fatal(err)
+	}
+	s, err := w.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, _ := newDamageTestWorld(t)
+	entities, err := restored.Restore(s, NewDefaultEntityFactory(restored.AssetManager))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 1 {
+		t.Fatalf("Expected restored tree, got %d entities", len(entities))
+	}
+	contents := entities[0].LootTable().Result(restored.Rng())
+	if len(contents) != 1 {
+		t.Fatalf("Expected 1 drop, got %d", len(contents))
+	}
+	if got, ok := contents[0].(*loot.ResourceItem); !ok || got.Resource != loot.ResourceGold || got.Amount != 3 {
+		t.Errorf("Expected 3 gold, got %v", contents[0])
+	}
+}
@@ -1,8 +1,10 @@
 package engine
 
 import (
+	"fmt"
 	"image/color"
 	"math"
+	"slices"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/jakecoffman/cp"
@@ -247,6 +249,25 @@ func (l *DiscoveryLayer) Draw(camera Camera) {
 	camera.Screen().DrawImage(image, op)
 }
 
+// Copy of fog alpha values
+func (l *DiscoveryLayer) Discovered() [][]uint8 {
+	res := make([][]uint8, len(l.discovered))
+	for row := range l.discovered {
+		res[row] = slices.Clone(l.discovered[row])
+	}
+	return res
+}
+
+func (l *DiscoveryLayer) RestoreDiscovered(discovered [][]uint8) error {
+	if len(discovered) != len(l.discovered) || len(discovered[0]) != len(l.discovered[0]) {
+		return fmt.Errorf("Cannot restore fog of war: Dimensions do not match")
+	}
+	for row := range discovered {
+		l.discovered[row] = slices.Clone(discovered[row])
+	}
+	return nil
+}
+
 func (l *DiscoveryLayer) VectorVisible(vec cp.Vector) bool {
 	row, col := WorldPosToGridPos(vec)
 	return l.discovered[row][col] < fogMaxAlpha
@@ -3,7 +3,8 @@ package engine
 import (
 	"fmt"
 	"math"
-	"math/rand"
+	"math/rand/v2"
+	"slices"
 
 	"github.com/jakecoffman/cp"
 )
@@ -19,6 +20,11 @@ type HexWorldMap struct {
 	// TODO: Datatype
 	// Pool of segments to randomly choose from
 	segmentPool []HexSegmentPattern
+	// Asset path per segment. Empty if segment was added from memory
+	segmentFiles []string
+	readFile     AssetFileReader
+	// Where segments have been copied to. Required to apply hot reloaded segments
+	placements []hexPlacement
 
 	// Center pos of initial hex
 	center cp.Vector
@@ -26,15 +32,21 @@ type HexWorldMap struct {
 	radius float64
 	// Inner radius of all hexes
 	inradius float64
+	rng      *rand.Rand
 }
 
-func NewProcHexWorldMap(width, height int64, center cp.Vector) (*HexWorldMap, error) {
+type hexPlacement struct {
+	segment int
+	center  cp.Vector
+}
+
+func NewProcHexWorldMap(width, height int64, center cp.Vector, rng *rand.Rand) (*HexWorldMap, error) {
 	// Init base
 	base, err := NewMultiLayerWorldMap(width, height)
 	if err != nil {
 		return nil, err
 	}
-	m := &HexWorldMap{MultiLayerWorldMap: base}
+	m := &HexWorldMap{MultiLayerWorldMap: base, rng: rng}
 	m.center = center
 	return m, nil
 }
@@ -71,10 +83,64 @@ func (m *HexWorldMap) initPropLayer(tileset *Tileset) error {
 // Adds hexagon map segment csv data to the pool of available segments
 // that the procedure will randomly choose from
 func (m *HexWorldMap) AddHexSegment(mapCsv []byte) error {
+	csvMapData, err := m.parseHexSegment(mapCsv)
+	if err != nil {
+		return err
+	}
+	// Add to pool
+	m.segmentPool = append(m.segmentPool, csvMapData)
+	m.segmentFiles = append(m.segmentFiles, "")
+	return nil
+}
+
+// Adds hexagon map segment from an asset file. Segment will be hot reloaded in dev mode
+func (m *HexWorldMap) AddHexSegmentFile(path string, readFile AssetFileReader) error {
+	data, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	if err := m.AddHexSegment(data); err != nil {
+		return fmt.Errorf("Could not add hex segment %s: %s", path, err.Error())
+	}
+	m.segmentFiles[len(m.segmentFiles)-1] = path
+	m.readFile = readFile
+	return nil
+}
+
+// Replace segment loaded from path & redraw all hexes using it
+func (m *HexWorldMap) Reload(path string) error {
+	idx := slices.Index(m.segmentFiles, path)
+	if idx < 0 || m.readFile == nil {
+		return nil
+	}
+	data, err := m.readFile(path)
+	if err != nil {
+		return err
+	}
+	csvMapData, err := m.parseHexSegment(data)
+	if err != nil {
+		return err
+	}
+	m.segmentPool[idx] = csvMapData
+	for _, placement := range m.placements {
+		if placement.segment != idx {
+			continue
+		}
+		if err := m.groundLayer.CopyMapDataToCenterPosition(csvMapData, placement.center); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *HexWorldMap) parseHexSegment(mapCsv []byte) (HexSegmentPattern, error) {
 	// Read map data from provided path
 	csvMapData, err := ReadCsvFromBinary(mapCsv)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(csvMapData) == 0 {
+		return nil, fmt.Errorf("Hex segment is empty")
 	}
 
 	// Determine hex radius from map data
@@ -85,12 +151,9 @@ func (m *HexWorldMap) AddHexSegment(mapCsv []byte) error {
 	if m.radius == 0 {
 		m.SetRadius(radius)
 	} else if m.radius != radius {
-		return fmt.Errorf("Hex segment radius does not match.")
+		return nil, fmt.Errorf("Hex segment radius does not match.")
 	}
-
-	// Add to pool
-	m.segmentPool = append(m.segmentPool, csvMapData)
-	return nil
+	return csvMapData, nil
 }
 
 func (m *HexWorldMap) SetRadius(radius float64) {
@@ -100,6 +163,29 @@ func (m *HexWorldMap) SetRadius(radius float64) {
 }
 func (m *HexWorldMap) Radius() float64 { return m.radius }
 
+// Copy of the generated ground layer
+func (m *HexWorldMap) GroundTiles() [][]MapTile {
+	res := make([][]MapTile, len(m.groundLayer.tileData))
+	for row := range m.groundLayer.tileData {
+		res[row] = slices.Clone(m.groundLayer.tileData[row])
+	}
+	return res
+}
+
+// Overwrite ground layer with previously generated tiles instead of generating
+func (m *HexWorldMap) RestoreGroundTiles(tiles [][]MapTile) error {
+	if m.groundLayer == nil {
+		return fmt.Errorf("Cannot restore hex map: Base layers not initialized")
+	}
+	if len(tiles) != len(m.groundLayer.tileData) || len(tiles[0]) != len(m.groundLayer.tileData[0]) {
+		return fmt.Errorf("Cannot restore hex map: Dimensions do not match")
+	}
+	for row := range tiles {
+		m.groundLayer.tileData[row] = slices.Clone(tiles[row])
+	}
+	return nil
+}
+
 func (m *HexWorldMap) Generate() error {
 	// FIX: Currently first hex segment is always used as start
 	startingHex := m.segmentPool[0]
@@ -110,14 +196,14 @@ func (m *HexWorldMap) Generate() error {
 	if err := layer.CopyMapDataToCenterPosition(startingHex, m.center); err != nil {
 		return err
 	}
+	m.placements = append(m.placements, hexPlacement{0, m.center})
 
 	// Draw first ring of hexes
 	return m.NewHexRing(m.center, m.segmentPool[0], layer)
 }
 
-func (m *HexWorldMap) getRandomSegment() HexSegmentPattern {
-	idx := rand.Intn(len(m.segmentPool))
-	return m.segmentPool[idx]
+func (m *HexWorldMap) getRandomSegment() int {
+	return m.rng.IntN(len(m.segmentPool))
 }
 
 func (m *HexWorldMap) NewHexRing(center cp.Vector, csvMapData [][]MapTile, l *BaseMapLayer) error {
@@ -130,12 +216,15 @@ func (m *HexWorldMap) NewHexRing(center cp.Vector, csvMapData [][]MapTile, l *Ba
 		hexCenter := center.Add(cp.Vector{math.Cos(angle) * m.inradius * 2, math.Sin(angle) * m.inradius * 2})
 
 		// Randomize which hexagon to pick, ideally flip and / or rotate
-		mapData := m.getRandomSegment()
+		segment := m.getRandomSegment()
 
 		// Copy to layer
-		if err := l.CopyMapDataToCenterPosition(mapData, hexCenter); err != nil {
+		if err := l.CopyMapDataToCenterPosition(m.segmentPool[segment], hexCenter); err != nil {
 			return err
 		}
+		if l == m.groundLayer {
+			m.placements = append(m.placements, hexPlacement{segment, hexCenter})
+		}
 	}
 	return nil
 }
@@ -3,7 +3,7 @@ package engine
 import (
 	"fmt"
 	"math"
-	"math/rand"
+	"math/rand/v2"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/jakecoffman/cp"
@@ -15,13 +15,14 @@ type SkyboxLayer struct {
 	// duplicate with base layer
 	tileset  Tileset
 	tileData [][]MapTile
+	rng      *rand.Rand
 }
 
 const parallaxSpeed = -0.2
 
 // Dimension should equal camera viewport
-func NewSkyboxLayer(width, height int64, tileset *Tileset) (*SkyboxLayer, error) {
-	layer := &SkyboxLayer{}
+func NewSkyboxLayer(width, height int64, tileset *Tileset, rng *rand.Rand) (*SkyboxLayer, error) {
+	layer := &SkyboxLayer{rng: rng}
 	if tileset == nil {
 		return nil, fmt.Errorf("Missing tileset")
 	}
@@ -43,7 +44,7 @@ func (l *SkyboxLayer) Seed() {
 	for row := range rows {
 		tileData[row] = make([]MapTile, cols)
 		for col := range cols {
-			tileData[row][col] = randomStarTile()
+			tileData[row][col] = randomStarTile(l.rng)
 		}
 	}
 	l.tileData = tileData
@@ -110,13 +111,13 @@ func calcFloatingOffset(v float64) float64 {
 	return offset
 }
 
-func randomStarTile() MapTile {
-	if rand.Intn(30) < 29 {
+func randomStarTile(rng *rand.Rand) MapTile {
+	if rng.IntN(30) < 29 {
 		return MapTile(467)
 	}
 	// Random star from 4x8 tileset starting at idx 436 with rowsize 29
-	col := rand.Intn(8)
-	row := rand.Intn(4)
+	col := rng.IntN(8)
+	row := rng.IntN(4)
 	tileIdx := col + row*29 + 436
 	return MapTile(tileIdx)
 }
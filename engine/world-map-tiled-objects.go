@@ -0,0 +1,360 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jakecoffman/cp"
+)
+
+type MapObjectKind int
+
+const (
+	MapObjectRect MapObjectKind = iota
+	MapObjectEllipse
+	MapObjectPolygon
+	MapObjectPolyline
+	MapObjectPoint
+)
+
+// Class of tiled objects that are registered as static colliders
+const MapObjectClassCollider = "collider"
+
+// Number of vertices used to approximate ellipses
+const mapObjectEllipseSegments = 16
+
+type MapProperties map[string]string
+
+func (p MapProperties) String(name, fallback string) string {
+	if val, ok := p[name]; ok {
+		return val
+	}
+	return fallback
+}
+
+func (p MapProperties) Float(name string, fallback float64) float64 {
+	val, err := strconv.ParseFloat(p[name], 64)
+	if err != nil {
+		return fallback
+	}
+	return val
+}
+
+func (p MapProperties) Bool(name string, fallback bool) bool {
+	val, err := strconv.ParseBool(p[name])
+	if err != nil {
+		return fallback
+	}
+	return val
+}
+
+func newMapProperties(properties []TiledProperty) MapProperties {
+	res := MapProperties{}
+	for _, prop := range properties {
+		res[prop.Name] = tiledPropertyValue(properties, prop.Name)
+	}
+	return res
+}
+
+// Shape of a tiled object layer in world coordinates
+type MapObject struct {
+	Id    int
+	Name  string
+	Class string
+	Kind  MapObjectKind
+	// Top left for rects & ellipses, origin for polygons & polylines
+	Position cp.Vector
+	// Outline in world coordinates. Closed for all kinds except polylines. Empty for points
+	Points     []cp.Vector
+	Properties MapProperties
+	bb         cp.BB
+}
+
+func newMapObject(obj TiledObject) (*MapObject, error) {
+	o := &MapObject{
+		Id:         obj.Id,
+		Name:       obj.Name,
+		Class:      obj.Class,
+		Position:   cp.Vector{X: obj.X, Y: obj.Y},
+		Properties: newMapProperties(obj.Properties),
+	}
+	if o.Class == "" {
+		o.Class = obj.Type
+	}
+	// Determine outline relative to object position
+	var local []cp.Vector
+	var err error
+	switch {
+	case obj.Point != nil:
+		o.Kind = MapObjectPoint
+	case obj.Polygon != nil:
+		o.Kind = MapObjectPolygon
+		local, err = parseTiledPoints(obj.Polygon.Points)
+	case obj.Polyline != nil:
+		o.Kind = MapObjectPolyline
+		local, err = parseTiledPoints(obj.Polyline.Points)
+	case obj.Ellipse != nil:
+		o.Kind = MapObjectEllipse
+		radius := cp.Vector{X: obj.Width / 2, Y: obj.Height / 2}
+		for idx := range mapObjectEllipseSegments {
+			angle := 2 * math.Pi * float64(idx) / mapObjectEllipseSegments
+			local = append(local, cp.Vector{X: radius.X + radius.X*math.Cos(angle), Y: radius.Y + radius.Y*math.Sin(angle)})
+		}
+	default:
+		o.Kind = MapObjectRect
+		local = []cp.Vector{{X: 0, Y: 0}, {X: obj.Width, Y: 0}, {X: obj.Width, Y: obj.Height}, {X: 0, Y: obj.Height}}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Object %d: %s", obj.Id, err.Error())
+	}
+	if o.Kind == MapObjectPolygon && len(local) < 3 {
+		return nil, fmt.Errorf("Object %d: Polygon needs at least 3 points", obj.Id)
+	}
+	if o.Kind == MapObjectPolyline && len(local) < 2 {
+		return nil, fmt.Errorf("Object %d: Polyline needs at least 2 points", obj.Id)
+	}
+
+	// Objects are rotated clockwise around their position
+	rotation := cp.ForAngle(obj.Rotation * math.Pi / 180)
+	o.bb = cp.NewBBForCircle(o.Position, 0)
+	for _, p := range local {
+		worldPos := o.Position.Add(p.Rotate(rotation))
+		o.Points = append(o.Points, worldPos)
+		o.bb = o.bb.Expand(worldPos)
+	}
+	return o, nil
+}
+
+func parseTiledPoints(raw string) ([]cp.Vector, error) {
+	res := []cp.Vector{}
+	for _, pair := range strings.Fields(raw) {
+		coords := strings.Split(pair, ",")
+		if len(coords) != 2 {
+			return nil, fmt.Errorf("Invalid point %s", pair)
+		}
+		x, err := strconv.ParseFloat(coords[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		y, err := strconv.ParseFloat(coords[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cp.Vector{X: x, Y: y})
+	}
+	return res, nil
+}
+
+func (o *MapObject) BB() cp.BB { return o.bb }
+
+// Closed shapes enclose an area. Points & polylines do not
+func (o *MapObject) Closed() bool { return o.Kind != MapObjectPoint && o.Kind != MapObjectPolyline }
+
+// Returns point position or center of bounding box
+func (o *MapObject) Center() cp.Vector {
+	if o.Kind == MapObjectPoint {
+		return o.Position
+	}
+	return o.bb.Center()
+}
+
+// Even-odd rule. Works for concave polygons as well
+func (o *MapObject) Contains(p cp.Vector) bool {
+	if !o.Closed() || !o.bb.ContainsVect(p) {
+		return false
+	}
+	inside := false
+	for i, j := 0, len(o.Points)-1; i < len(o.Points); j, i = i, i+1 {
+		a, b := o.Points[i], o.Points[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Area of closed shapes. Returns 0 for points & polylines
+func (o *MapObject) Area() float64 {
+	if !o.Closed() {
+		return 0
+	}
+	return math.Abs(polygonSignedArea(o.Points))
+}
+
+// Add static collider shapes for this object to the space
+// Closed shapes are triangulated, polylines are added as segments
+func (o *MapObject) RegisterToSpace(space *cp.Space) error {
+	shapes := []*cp.Shape{}
+	switch o.Kind {
+	case MapObjectPoint:
+		return fmt.Errorf("Object %d: Points cannot be used as collider", o.Id)
+	case MapObjectPolyline:
+		for idx := 1; idx < len(o.Points); idx++ {
+			shapes = append(shapes, cp.NewSegment(space.StaticBody, o.Points[idx-1], o.Points[idx], 2))
+		}
+	default:
+		triangles, err := triangulatePolygon(o.Points)
+		if err != nil {
+			return fmt.Errorf("Object %d: %s", o.Id, err.Error())
+		}
+		for _, tri := range triangles {
+			shapes = append(shapes, cp.NewPolyShape(space.StaticBody, 3, tri, cp.NewTransformIdentity(), 0))
+		}
+	}
+	for _, shape := range shapes {
+		space.AddShape(shape)
+		shape.SetElasticity(1)
+		shape.SetFriction(1)
+		shape.SetFilter(boundingBoxFilter)
+	}
+	return nil
+}
+
+type MapObjectLayer struct {
+	Name       string
+	Visible    bool
+	Objects    []*MapObject
+	Properties MapProperties
+}
+
+func newMapObjectLayer(layer TiledLayer) (*MapObjectLayer, error) {
+	l := &MapObjectLayer{Properties: newMapProperties(layer.Properties)}
+	for _, obj := range layer.Objects {
+		mapObject, err := newMapObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		l.Objects = append(l.Objects, mapObject)
+	}
+	return l, nil
+}
+
+// Returns point positions & centers of all other objects. Can be used as waypoints
+func (l *MapObjectLayer) Positions() []cp.Vector {
+	res := make([]cp.Vector, len(l.Objects))
+	for idx, obj := range l.Objects {
+		res[idx] = obj.Center()
+	}
+	return res
+}
+
+// Register all objects as static colliders
+func (l *MapObjectLayer) RegisterToSpace(space *cp.Space) error {
+	for _, obj := range l.Objects {
+		if err := obj.RegisterToSpace(space); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// All objects across all object layers in document order
+func (r *TiledMapResult) Objects() []*MapObject {
+	// Iterate in document order, map iteration would not be deterministic
+	res := []*MapObject{}
+	var collect func(layers []TiledLayer, prefix string)
+	collect = func(layers []TiledLayer, prefix string) {
+		for _, layer := range layers {
+			switch layer.Kind() {
+			case TiledGroupLayer:
+				collect(layer.Layers, prefix+layer.Name+"/")
+			case TiledObjectLayer:
+				res = append(res, r.ObjectLayers[prefix+layer.Name].Objects...)
+			}
+		}
+	}
+	collect(r.Map.Layers, "")
+	return res
+}
+
+// Returns the first object with the given name across all object layers
+// Used for markers, e.g. the castle or player start position
+func (r *TiledMapResult) Marker(name string) (*MapObject, error) {
+	for _, obj := range r.Objects() {
+		if obj.Name == name {
+			return obj, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown marker %s", name)
+}
+
+// Register all objects of class "collider" as static colliders
+// Needs to happen before building navigation, so the nav grid sees them
+func (r *TiledMapResult) RegisterColliders(space *cp.Space) error {
+	for _, obj := range r.Objects() {
+		if obj.Class != MapObjectClassCollider {
+			continue
+		}
+		if err := obj.RegisterToSpace(space); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Positive for counter-clockwise winding in a y-up coordinate system
+func polygonSignedArea(points []cp.Vector) float64 {
+	area := 0.0
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		area += points[j].Cross(points[i])
+	}
+	return area / 2
+}
+
+// Ear clipping triangulation. cp only supports convex polygons, so concave
+// tiled polygons need to be split up
+func triangulatePolygon(points []cp.Vector) ([][]cp.Vector, error) {
+	if len(points) < 3 {
+		return nil, fmt.Errorf("Polygon needs at least 3 points")
+	}
+	// Ensure consistent winding
+	remaining := make([]cp.Vector, len(points))
+	copy(remaining, points)
+	if polygonSignedArea(remaining) < 0 {
+		for i, j := 0, len(remaining)-1; i < j; i, j = i+1, j-1 {
+			remaining[i], remaining[j] = remaining[j], remaining[i]
+		}
+	}
+
+	res := [][]cp.Vector{}
+	for len(remaining) > 3 {
+		earFound := false
+		for idx := range remaining {
+			prev := remaining[(idx+len(remaining)-1)%len(remaining)]
+			cur := remaining[idx]
+			next := remaining[(idx+1)%len(remaining)]
+			// Skip reflex vertices
+			if cur.Sub(prev).Cross(next.Sub(cur)) <= 0 {
+				continue
+			}
+			// Ear must not contain any other vertex
+			containsOther := false
+			for _, p := range remaining {
+				if p == prev || p == cur || p == next {
+					continue
+				}
+				if triangleContains(prev, cur, next, p) {
+					containsOther = true
+					break
+				}
+			}
+			if containsOther {
+				continue
+			}
+			res = append(res, []cp.Vector{prev, cur, next})
+			remaining = append(remaining[:idx:idx], remaining[idx+1:]...)
+			earFound = true
+			break
+		}
+		if !earFound {
+			return nil, fmt.Errorf("Could not triangulate self-intersecting polygon")
+		}
+	}
+	return append(res, remaining), nil
+}
+
+func triangleContains(a, b, c, p cp.Vector) bool {
+	return b.Sub(a).Cross(p.Sub(a)) >= 0 && c.Sub(b).Cross(p.Sub(b)) >= 0 && a.Sub(c).Cross(p.Sub(c)) >= 0
+}
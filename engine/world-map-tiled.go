@@ -0,0 +1,532 @@
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ///////////////
+// TMX / TSX format
+// Reference: https://doc.mapeditor.org/en/stable/reference/tmx-map-format/
+// ///////////////
+
+type TiledMap struct {
+	Orientation string          `xml:"orientation,attr"`
+	Width       int             `xml:"width,attr"`
+	Height      int             `xml:"height,attr"`
+	TileWidth   int             `xml:"tilewidth,attr"`
+	TileHeight  int             `xml:"tileheight,attr"`
+	Infinite    int             `xml:"infinite,attr"`
+	Tilesets    []TiledTileset  `xml:"tileset"`
+	Properties  []TiledProperty `xml:"properties>property"`
+	// Tile layers, object layers & groups in document order
+	Layers []TiledLayer `xml:",any"`
+}
+
+type TiledTileset struct {
+	FirstGid   int        `xml:"firstgid,attr"`
+	Source     string     `xml:"source,attr"`
+	Name       string     `xml:"name,attr"`
+	TileWidth  int        `xml:"tilewidth,attr"`
+	TileHeight int        `xml:"tileheight,attr"`
+	TileCount  int        `xml:"tilecount,attr"`
+	Columns    int        `xml:"columns,attr"`
+	Spacing    int        `xml:"spacing,attr"`
+	Margin     int        `xml:"margin,attr"`
+	Image      TiledImage `xml:"image"`
+}
+
+type TiledImage struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+type TiledProperty struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+	// Multi-line string values are stored as element content
+	Text string `xml:",chardata"`
+}
+
+type TiledLayerKind string
+
+const (
+	TiledTileLayer   TiledLayerKind = "layer"
+	TiledObjectLayer TiledLayerKind = "objectgroup"
+	TiledGroupLayer  TiledLayerKind = "group"
+)
+
+type TiledLayer struct {
+	XMLName    xml.Name
+	Id         int             `xml:"id,attr"`
+	Name       string          `xml:"name,attr"`
+	Width      int             `xml:"width,attr"`
+	Height     int             `xml:"height,attr"`
+	Visible    string          `xml:"visible,attr"`
+	Opacity    string          `xml:"opacity,attr"`
+	Data       TiledLayerData  `xml:"data"`
+	Objects    []TiledObject   `xml:"object"`
+	Properties []TiledProperty `xml:"properties>property"`
+	// Child layers of a group in document order
+	Layers []TiledLayer `xml:",any"`
+}
+
+type TiledObject struct {
+	Id   int    `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+	// Tiled 1.9 renamed type to class
+	Class      string          `xml:"class,attr"`
+	X          float64         `xml:"x,attr"`
+	Y          float64         `xml:"y,attr"`
+	Width      float64         `xml:"width,attr"`
+	Height     float64         `xml:"height,attr"`
+	Rotation   float64         `xml:"rotation,attr"`
+	Visible    string          `xml:"visible,attr"`
+	Point      *struct{}       `xml:"point"`
+	Ellipse    *struct{}       `xml:"ellipse"`
+	Polygon    *TiledPoints    `xml:"polygon"`
+	Polyline   *TiledPoints    `xml:"polyline"`
+	Properties []TiledProperty `xml:"properties>property"`
+}
+
+// Space separated list of x,y pairs relative to the object position
+type TiledPoints struct {
+	Points string `xml:"points,attr"`
+}
+
+type TiledLayerData struct {
+	Encoding    string `xml:"encoding,attr"`
+	Compression string `xml:"compression,attr"`
+	Raw         string `xml:",chardata"`
+}
+
+const (
+	// Upper bits of a gid are used for flipping flags
+	tiledGidFlagsMask = 0xF0000000
+)
+
+func (l *TiledLayer) Kind() TiledLayerKind { return TiledLayerKind(l.XMLName.Local) }
+func (l *TiledLayer) IsVisible() bool      { return l.Visible != "0" }
+
+func (l *TiledLayer) OpacityValue() float64 {
+	if l.Opacity == "" {
+		return 1.0
+	}
+	opacity, err := strconv.ParseFloat(l.Opacity, 64)
+	if err != nil {
+		return 1.0
+	}
+	return opacity
+}
+
+// Returns value of the property with the given name or empty string if unknown
+func (l *TiledLayer) Property(name string) string {
+	return tiledPropertyValue(l.Properties, name)
+}
+
+func tiledPropertyValue(properties []TiledProperty, name string) string {
+	for _, prop := range properties {
+		if prop.Name != name {
+			continue
+		}
+		if prop.Value == "" {
+			return prop.Text
+		}
+		return prop.Value
+	}
+	return ""
+}
+
+// Decode layer data into gids. Flipping flags will be dropped
+func (d *TiledLayerData) Gids(expected int) ([]uint32, error) {
+	var gids []uint32
+	switch d.Encoding {
+	case "csv":
+		for _, field := range strings.Split(d.Raw, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			gid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			gids = append(gids, uint32(gid))
+		}
+	case "base64":
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(d.Raw))
+		if err != nil {
+			return nil, err
+		}
+		var reader io.Reader = bytes.NewReader(raw)
+		switch d.Compression {
+		case "":
+		case "zlib":
+			if reader, err = zlib.NewReader(reader); err != nil {
+				return nil, err
+			}
+		case "gzip":
+			if reader, err = gzip.NewReader(reader); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("Unsupported compression %s", d.Compression)
+		}
+		gids = make([]uint32, expected)
+		if err := binary.Read(reader, binary.LittleEndian, gids); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported encoding '%s'", d.Encoding)
+	}
+	if len(gids) != expected {
+		return nil, fmt.Errorf("Expected %d tiles, but received %d", expected, len(gids))
+	}
+	for idx := range gids {
+		gids[idx] &^= tiledGidFlagsMask
+	}
+	return gids, nil
+}
+
+func ParseTiledMap(data []byte) (*TiledMap, error) {
+	m := &TiledMap{}
+	if err := xml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("Could not parse tmx: %s", err.Error())
+	}
+	return m, nil
+}
+
+func ParseTiledTileset(data []byte) (*TiledTileset, error) {
+	ts := &TiledTileset{}
+	if err := xml.Unmarshal(data, ts); err != nil {
+		return nil, fmt.Errorf("Could not parse tsx: %s", err.Error())
+	}
+	return ts, nil
+}
+
+// ///////////////
+// Map layer
+// ///////////////
+
+type tiledTilesetRange struct {
+	firstGid MapTile
+	name     string
+	tileset  *Tileset
+}
+
+// Map layer loaded from a tiled map. Tile data contains GIDs, which can
+// reference tiles of multiple tilesets
+type TiledMapLayer struct {
+	*BaseMapLayer
+	name     string
+	visible  bool
+	opacity  float64
+	tilesets []tiledTilesetRange
+}
+
+func (l *TiledMapLayer) Draw(camera Camera) error {
+	if !l.visible || l.opacity <= 0 {
+		return nil
+	}
+	topLeft, bottomRight := camera.Viewport()
+	// We dont want to iterate over out of bounds rows and cols
+	startingRow := max(int(topLeft.Y/mapTileSize), 0)
+	// + 1 to ensure that last row is included
+	endRow := min(int(bottomRight.Y/mapTileSize)+1, len(l.tileData))
+	startingCol := max(int(topLeft.X/mapTileSize), 0)
+	// + 1 to ensure that last col is included
+	endCol := min(int(bottomRight.X/mapTileSize)+1, len(l.tileData[0]))
+
+	for row := startingRow; row < endRow; row++ {
+		for col := startingCol; col < endCol; col++ {
+			gid := l.tileData[row][col]
+			// Ignore empty cells
+			if gid == EmptyTile {
+				continue
+			}
+			subIm, err := l.tile(gid)
+			if err != nil {
+				return fmt.Errorf("Unable to draw layer %s: %s", l.name, err.Error())
+			}
+			op := ebiten.DrawImageOptions{}
+			x, y := GridPosToTopLeftWorldPos(col, row)
+			op.GeoM.Translate(x, y)
+			op.ColorScale.ScaleAlpha(float32(l.opacity))
+			camera.DrawImage(subIm, &op)
+		}
+	}
+	return nil
+}
+
+// Resolve gid to tileset & local tile index
+func (l *TiledMapLayer) tile(gid MapTile) (*ebiten.Image, error) {
+	for idx := len(l.tilesets) - 1; idx >= 0; idx-- {
+		ts := l.tilesets[idx]
+		if ts.firstGid > gid {
+			continue
+		}
+		if ts.tileset == nil {
+			return nil, fmt.Errorf("Tileset %s of gid %d not resolved", ts.name, gid)
+		}
+		return ts.tileset.GetTile(int(gid - ts.firstGid))
+	}
+	return nil, fmt.Errorf("No tileset for gid %d", gid)
+}
+
+func (l *TiledMapLayer) Name() string             { return l.name }
+func (l *TiledMapLayer) Visible() bool            { return l.visible }
+func (l *TiledMapLayer) Opacity() float64         { return l.opacity }
+func (l *TiledMapLayer) SetVisible(visible bool)  { l.visible = visible }
+func (l *TiledMapLayer) SetOpacity(value float64) { l.opacity = value }
+func (l *TiledMapLayer) TileData() [][]MapTile    { return l.tileData }
+
+// ///////////////
+// Loader
+// ///////////////
+
+// Returns file contents for the given path. Paths of external tilesets and images
+// are relative to the referencing file
+type TiledFileReader func(path string) ([]byte, error)
+
+// File reader for files that have been bundled into the binary
+func NewTiledFileMap(files map[string][]byte) TiledFileReader {
+	return func(filePath string) ([]byte, error) {
+		data, ok := files[path.Clean(filePath)]
+		if !ok {
+			return nil, fmt.Errorf("Unknown file %s", filePath)
+		}
+		return data, nil
+	}
+}
+
+type TiledMapResult struct {
+	WorldMap *MultiLayerWorldMap
+	// Resolved tilesets by name
+	Tilesets map[string]*Tileset
+	// Tile layers by full path including group names, e.g. "Logic/walls"
+	Layers map[string]*TiledMapLayer
+	// Object layers by full path including group names, e.g. "Logic/SpawnAreas"
+	ObjectLayers map[string]*MapObjectLayer
+	// Raw map data. Required to access object layers & custom properties
+	Map *TiledMap
+
+	// Required to reload the map in dev mode
+	loader *TiledMapLoader
+	path   string
+}
+
+type TiledMapLoader struct {
+	readFile TiledFileReader
+	// Pre-resolved tilesets by tileset name. Used for tilesets that are not
+	// available as tsx file, e.g. because the map references a tsx outside of the assets
+	tilesets map[string]*Tileset
+}
+
+func NewTiledMapLoader(readFile TiledFileReader) (*TiledMapLoader, error) {
+	if readFile == nil {
+		return nil, fmt.Errorf("Cannot init tiled map loader without file reader")
+	}
+	return &TiledMapLoader{readFile: readFile, tilesets: map[string]*Tileset{}}, nil
+}
+
+// Name is the tileset name OR the file name of an external tileset without extension
+func (l *TiledMapLoader) SetTileset(name string, tileset *Tileset) { l.tilesets[name] = tileset }
+
+// Load a .tmx map and all its external tilesets
+func (l *TiledMapLoader) Load(tmxPath string) (*TiledMapResult, error) {
+	data, err := l.readFile(tmxPath)
+	if err != nil {
+		return nil, err
+	}
+	tiledMap, err := ParseTiledMap(data)
+	if err != nil {
+		return nil, err
+	}
+	if tiledMap.Orientation != "orthogonal" {
+		return nil, fmt.Errorf("Unsupported map orientation %s", tiledMap.Orientation)
+	}
+	if tiledMap.Infinite != 0 {
+		return nil, fmt.Errorf("Infinite maps are not supported")
+	}
+	if tiledMap.TileWidth != mapTileSize || tiledMap.TileHeight != mapTileSize {
+		return nil, fmt.Errorf("Unsupported tile size %dx%d. Expected %dx%d", tiledMap.TileWidth, tiledMap.TileHeight, mapTileSize, mapTileSize)
+	}
+
+	res := &TiledMapResult{
+		Tilesets:     map[string]*Tileset{},
+		Layers:       map[string]*TiledMapLayer{},
+		ObjectLayers: map[string]*MapObjectLayer{},
+		Map:          tiledMap,
+		loader:       l,
+		path:         tmxPath,
+	}
+	// Resolve tilesets
+	ranges := []tiledTilesetRange{}
+	for _, ref := range tiledMap.Tilesets {
+		name, tileset, err := l.resolveTileset(path.Dir(tmxPath), ref)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, tiledTilesetRange{MapTile(ref.FirstGid), name, tileset})
+		if tileset != nil {
+			res.Tilesets[name] = tileset
+		}
+	}
+
+	// Init world map
+	width := int64(tiledMap.Width * tiledMap.TileWidth)
+	height := int64(tiledMap.Height * tiledMap.TileHeight)
+	if res.WorldMap, err = NewMultiLayerWorldMap(width, height); err != nil {
+		return nil, err
+	}
+	if err := l.addLayers(res, ranges, tiledMap.Layers, "", true, 1.0); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Re-read the map & copy tile data into the existing layers. Object layers & physics
+// shapes derived from them are NOT updated, since they have already been turned into entities
+func (r *TiledMapResult) Reload(changedPath string) error {
+	if path.Clean(changedPath) != path.Clean(r.path) {
+		return nil
+	}
+	res, err := r.loader.Load(r.path)
+	if err != nil {
+		return err
+	}
+	// Validate first, so that a broken map is not applied partially
+	for name, layer := range r.Layers {
+		next, ok := res.Layers[name]
+		if !ok {
+			return fmt.Errorf("Layer %s has been removed. Restart required", name)
+		}
+		if len(next.tileData) != len(layer.tileData) || len(next.tileData[0]) != len(layer.tileData[0]) {
+			return fmt.Errorf("Dimensions of layer %s changed. Restart required", name)
+		}
+	}
+	for name, layer := range r.Layers {
+		next := res.Layers[name]
+		layer.tileData = next.tileData
+		layer.tilesets = next.tilesets
+		layer.visible = next.visible
+		layer.opacity = next.opacity
+	}
+	return nil
+}
+
+// Recursively add tile layers. Visibility & opacity of groups apply to all their children
+func (l *TiledMapLoader) addLayers(res *TiledMapResult, ranges []tiledTilesetRange, layers []TiledLayer, prefix string, visible bool, opacity float64) error {
+	for _, layer := range layers {
+		name := prefix + layer.Name
+		switch layer.Kind() {
+		case TiledGroupLayer:
+			if err := l.addLayers(res, ranges, layer.Layers, name+"/", visible && layer.IsVisible(), opacity*layer.OpacityValue()); err != nil {
+				return err
+			}
+		case TiledObjectLayer:
+			objectLayer, err := newMapObjectLayer(layer)
+			if err != nil {
+				return fmt.Errorf("Could not load object layer %s: %s", name, err.Error())
+			}
+			objectLayer.Name = name
+			objectLayer.Visible = visible && layer.IsVisible()
+			res.ObjectLayers[name] = objectLayer
+		case TiledTileLayer:
+			mapLayer, err := newTiledMapLayer(layer, ranges)
+			if err != nil {
+				return fmt.Errorf("Could not load layer %s: %s", name, err.Error())
+			}
+			mapLayer.name = name
+			mapLayer.visible = visible && layer.IsVisible()
+			mapLayer.opacity = opacity * layer.OpacityValue()
+			res.Layers[name] = mapLayer
+			res.WorldMap.AddMapLayer(mapLayer)
+		}
+	}
+	return nil
+}
+
+func newTiledMapLayer(layer TiledLayer, ranges []tiledTilesetRange) (*TiledMapLayer, error) {
+	gids, err := layer.Data.Gids(layer.Width * layer.Height)
+	if err != nil {
+		return nil, err
+	}
+	l := &TiledMapLayer{BaseMapLayer: &BaseMapLayer{}, tilesets: ranges}
+	l.tileData = make([][]MapTile, layer.Height)
+	for row := range layer.Height {
+		l.tileData[row] = make([]MapTile, layer.Width)
+		for col := range layer.Width {
+			gid := MapTile(gids[row*layer.Width+col])
+			if gid == 0 {
+				l.tileData[row][col] = EmptyTile
+				continue
+			}
+			// Validate early, so we dont fail during rendering
+			if _, err := l.tile(gid); err != nil {
+				return nil, err
+			}
+			l.tileData[row][col] = gid
+		}
+	}
+	return l, nil
+}
+
+// Returns name & tileset. Tileset is nil, if it could not be resolved.
+// Unresolved tilesets will only cause an error if referenced by a tile layer
+func (l *TiledMapLoader) resolveTileset(dir string, ref TiledTileset) (string, *Tileset, error) {
+	name := ref.Name
+	if ref.Source != "" {
+		name = strings.TrimSuffix(path.Base(ref.Source), path.Ext(ref.Source))
+	}
+	if tileset, ok := l.tilesets[name]; ok {
+		return name, tileset, nil
+	}
+
+	// Load external tileset
+	tsDef := &ref
+	if ref.Source != "" {
+		tsxPath := path.Join(dir, ref.Source)
+		data, err := l.readFile(tsxPath)
+		if err != nil {
+			return name, nil, nil
+		}
+		if tsDef, err = ParseTiledTileset(data); err != nil {
+			return "", nil, err
+		}
+		dir = path.Dir(tsxPath)
+	}
+	if tsDef.Image.Source == "" {
+		return "", nil, fmt.Errorf("Tileset %s: Image collection tilesets are not supported", name)
+	}
+	if tsDef.Spacing != 0 || tsDef.Margin != 0 {
+		return "", nil, fmt.Errorf("Tileset %s: Spacing & margin are not supported", name)
+	}
+	imageData, err := l.readFile(path.Join(dir, tsDef.Image.Source))
+	if err != nil {
+		return "", nil, fmt.Errorf("Tileset %s: %s", name, err.Error())
+	}
+	im, err := loadImageFromBinaryPng(imageData)
+	if err != nil {
+		return "", nil, fmt.Errorf("Tileset %s: %s", name, err.Error())
+	}
+	tileset, err := NewTileset(ebiten.NewImageFromImage(im), tsDef.TileWidth, tsDef.TileHeight, 1.0)
+	if err != nil {
+		return "", nil, fmt.Errorf("Tileset %s: %s", name, err.Error())
+	}
+	return name, tileset, nil
+}
@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/bin/assets"
+)
+
+const testTmx = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="3" height="2" tilewidth="16" tileheight="16" infinite="0">
+ <tileset firstgid="1" source="../somewhere/first.tsx"/>
+ <tileset firstgid="5" source="second.tsx"/>
+ <group id="1" name="Logic" visible="0">
+  <layer id="2" name="walls" width="3" height="2">
+   <data encoding="csv">
+0,1,4,
+5,0,0
+</data>
+  </layer>
+ </group>
+ <group id="3" name="Dark" opacity="0.5">
+  <layer id="4" name="ground" width="3" height="2" opacity="0.5">
+   <data encoding="base64" compression="zlib">%s</data>
+  </layer>
+ </group>
+</map>`
+
+func testTiledGids(t *testing.T, gids []uint32) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if err := binary.Write(w, binary.LittleEndian, gids); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestTiledMapLoader(t *testing.T) {
+	// Second tile is flipped horizontally
+	encoded := testTiledGids(t, []uint32{6, 0x80000000 | 2, 0, 0, 0, 1})
+	loader, _ := NewTiledMapLoader(NewTiledFileMap(map[string][]byte{
+		"maps/test.tmx": []byte(fmt.Sprintf(testTmx, encoded)),
+	}))
+	first, _ := NewTileset(ebiten.NewImage(64, 16), 16, 16, 1)
+	second, _ := NewTileset(ebiten.NewImage(32, 16), 16, 16, 1)
+	loader.SetTileset("first", first)
+	loader.SetTileset("second", second)
+
+	res, err := loader.Load("maps/test.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Layers) != 2 || len(res.WorldMap.layers) != 2 {
+		t.Fatalf("Expected 2 layers, got %d", len(res.Layers))
+	}
+	walls, ok := res.Layers["Logic/walls"]
+	if !ok {
+		t.Fatal("Expected layer Logic/walls")
+	}
+	if walls.Visible() {
+		t.Error("Expected walls to inherit group visibility")
+	}
+	expected := [][]MapTile{{EmptyTile, 1, 4}, {5, EmptyTile, EmptyTile}}
+	for row := range expected {
+		for col := range expected[row] {
+			if walls.TileData()[row][col] != expected[row][col] {
+				t.Errorf("Expected %d at %d,%d, got %d", expected[row][col], row, col, walls.TileData()[row][col])
+			}
+		}
+	}
+
+	ground := res.Layers["Dark/ground"]
+	if !ground.Visible() || ground.Opacity() != 0.25 {
+		t.Errorf("Expected visible ground with opacity 0.25, got %v %f", ground.Visible(), ground.Opacity())
+	}
+	if ground.TileData()[0][1] != 2 {
+		t.Errorf("Expected flip flags to be dropped, got %d", ground.TileData()[0][1])
+	}
+	// Gid 6 is second tile of second tileset
+	if _, err := ground.tile(6); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTiledMapLoaderUnresolvedTileset(t *testing.T) {
+	loader, _ := NewTiledMapLoader(NewTiledFileMap(map[string][]byte{
+		"test.tmx": []byte(fmt.Sprintf(testTmx, testTiledGids(t, []uint32{0, 0, 0, 0, 0, 0}))),
+	}))
+	first, _ := NewTileset(ebiten.NewImage(64, 16), 16, 16, 1)
+	loader.SetTileset("first", first)
+	// Walls layer references gid 5 of missing second tileset
+	if _, err := loader.Load("test.tmx"); err == nil {
+		t.Error("Expected error for unresolved tileset")
+	}
+}
+
+func TestTiledMapLoaderMatchesCsvExport(t *testing.T) {
+	loader, _ := NewTiledMapLoader(NewTiledFileMap(map[string][]byte{
+		"labyrinth_map.tmx": assets.LabyrinthMapTMX,
+		"plains.tsx":        assets.PlainsTSX,
+		"plains.png":        assets.Plains,
+	}))
+	res, err := loader.Load("labyrinth_map.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvData, err := ReadCsvFromBinary(assets.LabyrinthMapCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := res.Layers["Tile Layer 1"]
+	for row := range csvData {
+		for col := range csvData[row] {
+			tile := layer.TileData()[row][col]
+			// CSV export is 0-based, gids are 1-based
+			if tile != EmptyTile {
+				tile -= 1
+			}
+			if tile != csvData[row][col] {
+				t.Fatalf("Mismatch at %d,%d: Expected %d, got %d", row, col, csvData[row][col], tile)
+			}
+		}
+	}
+}
+
+const testObjectsTmx = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="10" height="10" tilewidth="16" tileheight="16" infinite="0">
+ <group id="1" name="Logic">
+  <objectgroup id="2" name="Regions">
+   <object id="1" x="0" y="0" width="32" height="16">
+    <properties>
+     <property name="weight" type="float" value="2"/>
+    </properties>
+   </object>
+   <object id="2" name="ushape" type="collider" x="100" y="100">
+    <polygon points="0,0 30,0 30,30 20,30 20,10 10,10 10,30 0,30"/>
+   </object>
+  </objectgroup>
+  <objectgroup id="3" name="Markers">
+   <object id="3" name="castle" x="50.5" y="60">
+    <point/>
+   </object>
+   <object id="4" x="0" y="0">
+    <polyline points="0,0 10,10 20,0"/>
+   </object>
+  </objectgroup>
+ </group>
+</map>`
+
+func TestTiledMapObjects(t *testing.T) {
+	loader, _ := NewTiledMapLoader(NewTiledFileMap(map[string][]byte{"test.tmx": []byte(testObjectsTmx)}))
+	res, err := loader.Load("test.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, ok := res.ObjectLayers["Logic/Regions"]
+	if !ok || len(regions.Objects) != 2 {
+		t.Fatal("Expected object layer Logic/Regions with 2 objects")
+	}
+	rect, ushape := regions.Objects[0], regions.Objects[1]
+	if rect.Kind != MapObjectRect || rect.Area() != 512 || rect.Properties.Float("weight", 1) != 2 {
+		t.Errorf("Unexpected rect %v", rect)
+	}
+	if ushape.Kind != MapObjectPolygon || ushape.Class != "collider" || ushape.Area() != 700 {
+		t.Errorf("Unexpected polygon %v", ushape)
+	}
+	// Gap of the U shape is not part of the polygon
+	if ushape.Contains(cp.Vector{X: 115, Y: 125}) || !ushape.Contains(cp.Vector{X: 105, Y: 125}) {
+		t.Error("Expected exact polygon containment")
+	}
+
+	// Markers
+	castle, err := res.Marker("castle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if castle.Kind != MapObjectPoint || castle.Center() != (cp.Vector{X: 50.5, Y: 60}) {
+		t.Errorf("Unexpected castle marker %v", castle)
+	}
+	if _, err := res.Marker("unknown"); err == nil {
+		t.Error("Expected error for unknown marker")
+	}
+
+	// Colliders
+	space := cp.NewSpace()
+	if err := ushape.RegisterToSpace(space); err != nil {
+		t.Fatal(err)
+	}
+	if info := space.PointQueryNearest(cp.Vector{X: 115, Y: 125}, 0, cp.SHAPE_FILTER_ALL); info.Shape != nil {
+		t.Error("Expected no collider within gap of concave polygon")
+	}
+	if info := space.PointQueryNearest(cp.Vector{X: 105, Y: 125}, 0, cp.SHAPE_FILTER_ALL); info.Shape == nil {
+		t.Error("Expected collider within polygon")
+	}
+	if err := res.ObjectLayers["Logic/Markers"].RegisterToSpace(space); err == nil {
+		t.Error("Expected error when registering point as collider")
+	}
+	// Only objects of class collider are registered
+	space = cp.NewSpace()
+	if err := res.RegisterColliders(space); err != nil {
+		t.Fatal(err)
+	}
+	if info := space.PointQueryNearest(cp.Vector{X: 105, Y: 125}, 0, cp.SHAPE_FILTER_ALL); info.Shape == nil {
+		t.Error("Expected collider registered from map")
+	}
+	if info := space.PointQueryNearest(cp.Vector{X: 16, Y: 8}, 0, cp.SHAPE_FILTER_ALL); info.Shape != nil {
+		t.Error("Expected no collider for regular region")
+	}
+}
+
+func TestSpawnArea(t *testing.T) {
+	loader, _ := NewTiledMapLoader(NewTiledFileMap(map[string][]byte{"test.tmx": []byte(testObjectsTmx)}))
+	res, err := loader.Load("test.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	area, err := NewSpawnArea(res.ObjectLayers["Logic/Regions"].Objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := NewRandomGenerator(1)
+	for range 100 {
+		pos, err := area.RandomPosition(rng)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !area.Contains(pos) {
+			t.Fatalf("Position %v outside of spawn area", pos)
+		}
+	}
+	if _, err := NewSpawnArea(res.ObjectLayers["Logic/Markers"].Objects); err == nil {
+		t.Error("Expected error for spawn area without closed shapes")
+	}
+}
@@ -3,6 +3,7 @@ package engine
 import (
 	"fmt"
 	"log"
+	"math/rand/v2"
 
 	"github.com/jakecoffman/cp"
 )
@@ -37,11 +38,11 @@ func (w *MultiLayerWorldMap) Draw(camera Camera) {
 	}
 }
 
-func (w *MultiLayerWorldMap) AddSkyboxLayer(width, height int64, tileset *Tileset) error {
+func (w *MultiLayerWorldMap) AddSkyboxLayer(width, height int64, tileset *Tileset, rng *rand.Rand) error {
 	if len(w.layers) > 0 {
 		return fmt.Errorf("Map already has existing layers. Skybox needs to be added as first layer")
 	}
-	l, err := NewSkyboxLayer(width, height, tileset)
+	l, err := NewSkyboxLayer(width, height, tileset, rng)
 	if err != nil {
 		return err
 	}
@@ -59,6 +60,9 @@ func (w *MultiLayerWorldMap) AddCsvLayer(mapCsv []byte, tileset *Tileset) error
 	return nil
 }
 
+// Append already initialized layer
+func (w *MultiLayerWorldMap) AddMapLayer(l MapLayer) { w.layers = append(w.layers, l) }
+
 // Returns vector centered on grid
 func SnapToGrid(v cp.Vector, gridX int, gridY int) cp.Vector {
 	return cp.Vector{X: float64(int(v.X/float64(gridX))*gridX) + float64(gridX)/2, Y: float64(int(v.Y/float64(gridY))*gridY) + float64(gridY/2)}
@@ -0,0 +1,21 @@
+package engine
+
+// Rasterize the current obstacles of the world into a nav grid.
+// Call after all static collision shapes have been added. Towers added or removed later update the grid
+func (w *GameWorld) BuildNavigation(cellSize float64) (*Navigation, error) {
+	grid, err := NewNavGrid(w.space, w.Width, w.Height, cellSize)
+	if err != nil {
+		return nil, err
+	}
+	if w.navigation, err = NewNavigation(grid); err != nil {
+		return nil, err
+	}
+	rebuild := func(e GameEntity) {
+		if e.Shape() != nil && !navObstacleFilter.Reject(e.Shape().Filter) {
+			grid.Rebuild(e.Shape().BB())
+		}
+	}
+	Subscribe(w.events, func(e EntitySpawned) { rebuild(e.Entity) })
+	Subscribe(w.events, func(e EntityDestroyed) { rebuild(e.Entity) })
+	return w.navigation, nil
+}
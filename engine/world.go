@@ -1,9 +1,11 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"log"
+	"math/rand/v2"
 	"slices"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -22,7 +24,6 @@ const (
 	DEBUG_DRAW_STATIC_BODY       = false
 	DEBUG_ENTITY_STATS           = true
 	DEBUG_RENDER_COLLISION_BOXES = false
-	SFX_VOLUME                   = 0.4
 )
 
 type GameWorld struct {
@@ -46,12 +47,38 @@ type GameWorld struct {
 	// Integral of time steps that continues after game over. Used for animation sim
 	animationTime float64
 	AssetManager  AssetManager
+	audio         *AudioManager
+	events        *EventBus
 	space         *cp.Space
 
 	// Game logic
-	gameOver    bool
-	GameSpeed   float64
+	gameOver  bool
+	GameSpeed float64
+	// Scattering, magnetism & despawning of dropped loot
+	LootDrops LootDropOpts
+	// Number of simulated ticks. Used to sync replays
+	tick        int
 	damageModel damage.DamageModel
+	// Entities that died during the current step
+	pendingDeaths []pendingDeath
+	// Resource kinds available to inventories, prices & loot
+	resources *loot.ResourceRegistry
+	// Item definitions. Creates uniquely identified item instances
+	items *loot.ItemDB
+	// Named loot tables, e.g. drops of npc types & trees
+	lootTables *loot.LootTableDB
+	// Recycled projectiles & projectile cap
+	projectiles *ProjectilePool
+	// Nav grid & flow fields of npcs. Nil until built
+	navigation *Navigation
+	// All randomness within the world is drawn from this generator.
+	// Two worlds with the same seed & inputs will produce identical state
+	seed uint64
+	rng  *rand.Rand
+	// Source of rng. Kept to persist generator state
+	pcg *rand.PCG
+	// Only set in dev mode
+	hotReloader *HotReloader
 }
 
 func (w *GameWorld) drawVisibleObjects() {
@@ -127,6 +154,10 @@ func (w *GameWorld) EntityVisible(e GameEntity) bool {
 }
 
 func (w *GameWorld) Update() {
+	// Apply changed assets before simulating the next frame
+	if w.hotReloader != nil {
+		w.hotReloader.Update()
+	}
 	dt := w.GameSpeed / 60.0
 	w.animationTime += dt
 	// Stop updating if game over
@@ -134,7 +165,14 @@ func (w *GameWorld) Update() {
 		return
 	}
 	*w.gameTime += dt
+	w.tick++
+	w.updateStatusEffects()
+	w.updateLootDrops(dt)
+	if w.navigation != nil {
+		w.navigation.Update()
+	}
 	w.space.Step(dt)
+	w.resolveDeaths()
 	// Delete objects scheduled for deletion
 	if len(w.objectIdsToDelete) > 0 {
 		for _, id := range w.objectIdsToDelete {
@@ -144,6 +182,72 @@ func (w *GameWorld) Update() {
 	}
 }
 
+// Tick & expire status effects. Sorted by id to keep damage ticks deterministic
+func (w *GameWorld) updateStatusEffects() {
+	if w.player != nil {
+		w.player.effects.Update(w)
+	}
+	ids := []GameEntityId{}
+	for id, obj := range w.objects {
+		if _, ok := obj.(StatusEffectCarrier); ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		w.objects[id].(StatusEffectCarrier).StatusEffects().Update(w)
+	}
+}
+
+func (w *GameWorld) Audio() *AudioManager { return w.audio }
+func (w *GameWorld) Events() *EventBus    { return w.events }
+
+// Resource kinds are passed on creation, so loot tables & inventories can use all of them
+func (w *GameWorld) Resources() *loot.ResourceRegistry { return w.resources }
+
+// Register item definitions before restoring snapshots containing instances of them
+func (w *GameWorld) Items() *loot.ItemDB { return w.items }
+
+// Loaded from loot.json together with its item definitions
+func (w *GameWorld) LootTables() *loot.LootTableDB { return w.lootTables }
+
+// Sounds are heard from the center of the camera, or the player if there is no camera yet
+func (w *GameWorld) ListenerPosition() cp.Vector {
+	if w.camera != nil {
+		topLeft, bottomRight := w.camera.Viewport()
+		return topLeft.Lerp(bottomRight, 0.5)
+	}
+	if w.player != nil {
+		return w.player.Position()
+	}
+	return cp.Vector{X: float64(w.Width) / 2, Y: float64(w.Height) / 2}
+}
+
+// Sounds hidden in fog of war are not played
+func (w *GameWorld) Audible(pos cp.Vector) bool {
+	return w.FogOfWar == nil || w.FogOfWar.VectorVisible(pos)
+}
+
+// Dev mode: Swap assets & map data on file change
+func (w *GameWorld) Reload(path string) error {
+	errs := []error{}
+	for _, res := range []any{w.AssetManager, w.WorldMap} {
+		if reloadable, ok := res.(HotReloadable); ok {
+			if err := reloadable.Reload(path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Register additional resources to reload in dev mode. Asset manager & world map are always watched
+func (w *GameWorld) WatchAssets(res HotReloadable) {
+	if w.hotReloader != nil {
+		w.hotReloader.Watch(res)
+	}
+}
+
 // Adds a game entity to the world by
 // - registering to physics space
 // - registering in object map to add / find / remove entities
@@ -154,6 +258,7 @@ func (w *GameWorld) AddEntity(object GameEntity) error {
 	object.SetId(w.nextObjectId)
 	object.SetEntityRemover(w)
 	w.nextObjectId++
+	Publish(w.events, EntitySpawned{object})
 	return nil
 }
 
@@ -175,14 +280,18 @@ func (w *GameWorld) AddCollisionLayer(mapData []byte) error {
 	if err != nil {
 		return err
 	}
+	w.AddCollisionTiles(tileData)
+	return nil
+}
 
+// Register walls for all non-empty tiles of the provided tile data
+func (w *GameWorld) AddCollisionTiles(tileData [][]MapTile) {
 	// Register wall segments to physical space
 	walls := CalcHorizontalWallSegments(tileData)
 	walls = append(walls, CalcVerticalWallSegments(tileData)...)
 	for _, wall := range walls {
 		RegisterWallSegmentToSpace(w.space, wall)
 	}
-	return nil
 }
 
 // Adds a layer with collision segments AND tilesets
@@ -197,38 +306,24 @@ func (w *GameWorld) AddLayer(mapData []byte, tileset *Tileset) error {
 	return w.WorldMap.AddCsvLayer(mapData, tileset)
 }
 
-// Dropping items is done by evaluating the input loot table &
-// spawning item sprites with guaranteed drops for every result of the original loot table
-func (w *GameWorld) DropLoot(lootTable loot.LootTable, pos cp.Vector) error {
-	for _, lootableItem := range lootTable.Result() {
-		itemEntity, err := NewItemEntity(pos)
-		if err != nil {
-			return err
-		}
-		// FIX: Hard-coding wood asset here. This needs to be provided thorugh the loot table though
-		asset, err := w.AssetManager.CharacterAsset("wood")
-		if err != nil {
-			return err
-		}
-		if err := itemEntity.SetAsset(asset); err != nil {
-			return err
-		}
-		copyOfLootTable := loot.NewGuaranteedLootTable(lootableItem)
-		itemEntity.SetLootTable(copyOfLootTable)
-		if err := w.AddEntity(itemEntity); err != nil {
-			return err
-		}
+func (w *GameWorld) EndGame() {
+	if w.gameOver {
+		return
 	}
-	return nil
+	w.gameOver = true
+	Publish(w.events, GameOver{})
 }
-
-func (w *GameWorld) EndGame()                        { w.gameOver = true }
 func (w *GameWorld) Space() *cp.Space                { return w.space }
 func (w *GameWorld) IngameTime() float64             { return *w.gameTime }
 func (w *GameWorld) AnimationTime() float64          { return w.animationTime }
 func (w *GameWorld) IsOver() bool                    { return w.gameOver }
 func (w *GameWorld) DamageModel() damage.DamageModel { return w.damageModel }
 func (w *GameWorld) Player() *Player                 { return w.player }
+func (w *GameWorld) Rng() *rand.Rand                 { return w.rng }
+func (w *GameWorld) Projectiles() *ProjectilePool    { return w.projectiles }
+func (w *GameWorld) Navigation() *Navigation         { return w.navigation }
+func (w *GameWorld) Seed() uint64                    { return w.seed }
+func (w *GameWorld) Tick() int                       { return w.tick }
 
 func (w *GameWorld) drawCombatLog() {
 	damageLog := w.damageModel.DamageLog()
@@ -247,7 +342,14 @@ func (w *GameWorld) drawCombatLog() {
 		absPos := entry.Pos.Add(cp.Vector{X: 0, Y: -timeDiff / maxTimeDiff * 20})
 		relPos := w.camera.WorldToScreenPos(absPos)
 
-		ebitenutil.DebugPrintAt(w.camera.Screen(), fmt.Sprintf("%.0f", entry.Damage), int(relPos.X), int(relPos.Y))
+		label := fmt.Sprintf("%.0f", entry.Damage)
+		if entry.Crit {
+			label += "!"
+		}
+		if entry.Type != damage.Physical {
+			label += " " + string(entry.Type)
+		}
+		ebitenutil.DebugPrintAt(w.camera.Screen(), label, int(relPos.X), int(relPos.Y))
 	}
 }
 
@@ -261,6 +363,10 @@ func (w *GameWorld) removeObject(id GameEntityId) {
 	w.space.RemoveShape(object.Shape())
 	w.space.RemoveBody(object.Shape().Body())
 	delete(w.objects, id)
+	Publish(w.events, EntityDestroyed{object})
+	if projectile, ok := object.(*Projectile); ok && projectile.pool != nil {
+		projectile.pool.release(projectile)
+	}
 }
 
 // Draw static bounding boxes for debugging purposes
@@ -307,27 +413,60 @@ func (w *GameWorld) drawEntityDebugInfo(screen *ebiten.Image) {
 	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("# Fps: %0.1f", ebiten.ActualFPS()), 10, yPos)
 }
 
-func NewWorld(width int64, height int64) (*GameWorld, error) {
+// Resources need to contain all kinds referenced by the loot definitions
+func NewWorld(width int64, height int64, seed uint64, resources *loot.ResourceRegistry) (*GameWorld, error) {
+	if resources == nil {
+		return nil, fmt.Errorf("Cannot init world without resource registry")
+	}
+	pcg := rand.NewPCG(seed, seed)
+	rng := rand.New(pcg)
 	// Intialize damage model
-	damageModel, err := damage.NewBasicDamageModel()
+	damageModel, err := damage.NewBasicDamageModel(rng)
 	if err != nil {
 		return nil, err
 	}
-
-	// Initialize physics
-	gameTime := float64(0)
-	space, err := NewPhysicsSpace(damageModel, &gameTime)
+	items, err := loot.NewItemDB()
+	if err != nil {
+		return nil, err
+	}
+	items.SetAffixValidator(ValidateAffix)
+	lootData, err := assetReader(loot.LootDefinitionsPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read loot definitions: %s", err.Error())
+	}
+	lootTables, err := loot.ParseLootDefinitions(lootData, resources, items)
 	if err != nil {
 		return nil, err
 	}
+
+	// Initialize physics
+	gameTime := float64(0)
+	audio := NewAudioManager()
+	events := NewEventBus()
 	w := GameWorld{
+		seed:        seed,
+		rng:         rng,
+		pcg:         pcg,
 		gameTime:    &gameTime,
 		Width:       width,
 		Height:      height,
-		space:       space,
+		audio:       audio,
+		events:      events,
 		damageModel: damageModel,
+		resources:   resources,
+		items:       items,
+		lootTables:  lootTables,
 		objects:     map[GameEntityId]GameEntity{},
 		GameSpeed:   1.0,
+		LootDrops:   DefaultLootDropOpts(),
+	}
+	space, err := NewPhysicsSpace(&w, &gameTime, rng, audio, events)
+	if err != nil {
+		return nil, err
+	}
+	w.space = space
+	if w.projectiles, err = NewProjectilePool(&w); err != nil {
+		return nil, err
 	}
 
 	// Initialize assets
@@ -336,6 +475,12 @@ func NewWorld(width int64, height int64) (*GameWorld, error) {
 		return nil, err
 	}
 	w.AssetManager = am
+	audio.SetSoundProvider(am)
+	audio.SetListener(&w)
+	if HotReloadEnabled() {
+		w.hotReloader = NewHotReloader(hotReloadDir)
+		w.hotReloader.Watch(&w)
+	}
 
 	// Initialize walls on outer edge
 	walls := []WallSegment{
@@ -353,16 +498,20 @@ func NewWorld(width int64, height int64) (*GameWorld, error) {
 	return &w, nil
 }
 
-func NewGeneratedWorld(generator WorldGenerator) (*GameWorld, error) {
+func NewGeneratedWorld(generator WorldGenerator, seed uint64) (*GameWorld, error) {
 	// Init empty world
 	width, height := generator.WorldDimensions()
-	gameWorld, err := NewWorld(width, height)
+	resources, err := generator.Resources()
+	if err != nil {
+		return nil, err
+	}
+	gameWorld, err := NewWorld(width, height, seed, resources)
 	if err != nil {
 		return nil, err
 	}
 
 	// Execute level generator
-	res, err := generator.Generate(gameWorld.AssetManager)
+	res, err := generator.Generate(gameWorld.AssetManager, gameWorld.lootTables, gameWorld.rng)
 	if err != nil {
 		return nil, fmt.Errorf("Error during level generation: %s", err.Error())
 	}
@@ -3,7 +3,10 @@ package main
 import (
 	"flag"
 	"log"
+	"math/rand/v2"
 
+	"github.com/lucb31/game-engine-go/engine"
+	"github.com/lucb31/game-engine-go/engine/hud"
 	"github.com/lucb31/game-engine-go/survival"
 	"github.com/lucb31/game-engine-go/td"
 
@@ -21,17 +24,66 @@ func main() {
 
 	// CLI
 	var gameSelected string
+	var seed uint64
+	var headless, dev bool
+	var ticks int
+	var recordPath, replayPath, loadPath, assetDir string
 	flag.StringVar(&gameSelected, "g", "survival", "Option to select game. Currently available 'td' & 'survival'")
+	flag.Uint64Var(&seed, "seed", 0, "Seed of the game world. Random if not provided")
+	flag.BoolVar(&headless, "headless", false, "Simulate the game without opening a window")
+	flag.IntVar(&ticks, "ticks", 60*60*10, "Amount of ticks to simulate in headless mode")
+	flag.StringVar(&recordPath, "record", "", "Record player inputs to the given replay file. Survival only")
+	flag.StringVar(&replayPath, "replay", "", "Play back the given replay file. Survival only")
+	flag.StringVar(&loadPath, "load", "", "Continue the given saved game. Survival only")
+	flag.StringVar(&assetDir, "assets", "", "Load assets & manifest from this directory instead of the bundled ones")
+	flag.BoolVar(&dev, "dev", false, "Read assets & maps from the assets directory (or -assets) and reload them on change")
 	flag.Parse()
+	if dev {
+		if assetDir == "" {
+			assetDir = "assets"
+		}
+		engine.EnableHotReload(assetDir)
+	} else if assetDir != "" {
+		engine.SetAssetReader(engine.NewDirAssetReader(assetDir))
+	}
+	var replay *engine.Replay
+	if replayPath != "" {
+		var err error
+		if replay, err = engine.LoadReplay(replayPath); err != nil {
+			panic(err)
+		}
+		gameSelected = replay.Game
+		seed = replay.Seed
+	}
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	log.Println("Using seed", seed)
+
+	if headless {
+		runHeadless(gameSelected, seed, ticks, replay)
+		return
+	}
 
 	// Init game
 	var g ebiten.Game
 	var err error
 	switch gameSelected {
 	case "td":
-		g, err = td.NewTDGame(screenWidth, screenHeight)
+		g, err = td.NewTDGame(screenWidth, screenHeight, seed)
 	case "survival":
-		g, err = survival.NewSurvivalGame(screenWidth, screenHeight)
+		var sg *survival.SurvivalGame
+		sg, err = survival.NewSurvivalGame(screenWidth, screenHeight, seed)
+		if err == nil && replay != nil {
+			err = sg.Replay(replay)
+		}
+		if err == nil && loadPath != "" {
+			err = loadSurvivalGame(sg, loadPath)
+		}
+		if err == nil && recordPath != "" {
+			err = sg.Record(recordPath)
+		}
+		g = sg
 	default:
 		panic("No game found")
 	}
@@ -43,3 +95,43 @@ func main() {
 		log.Println(err)
 	}
 }
+
+func loadSurvivalGame(g *survival.SurvivalGame, path string) error {
+	save, err := survival.LoadSaveGame(path)
+	if err != nil {
+		return err
+	}
+	return g.LoadGame(save)
+}
+
+type headlessGame interface {
+	engine.HeadlessGame
+	Score() hud.ScoreValue
+	CreepProgress() hud.ProgressInfo
+}
+
+func runHeadless(gameSelected string, seed uint64, ticks int, replay *engine.Replay) {
+	var g headlessGame
+	var err error
+	switch gameSelected {
+	case "td":
+		g, err = td.NewHeadlessTDGame(screenWidth, screenHeight, seed)
+	case "survival":
+		var sg *survival.SurvivalGame
+		sg, err = survival.NewHeadlessSurvivalGame(screenWidth, screenHeight, seed)
+		if err == nil && replay != nil {
+			err = sg.Replay(replay)
+		}
+		g = sg
+	default:
+		panic("No game found")
+	}
+	if err != nil {
+		panic(err)
+	}
+	simulated, err := engine.RunHeadless(g, ticks)
+	if err != nil {
+		log.Println(err)
+	}
+	log.Printf("Simulated %d ticks. Game over: %v, %s, Score: %.0f\n", simulated, g.GameOver(), g.CreepProgress().Label, g.Score())
+}
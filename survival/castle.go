@@ -11,7 +11,6 @@ import (
 	"github.com/lucb31/game-engine-go/engine/loot"
 )
 
-type gameOverCallback = func()
 type CastleEntity struct {
 	id     engine.GameEntityId
 	world  engine.GameEntityManager
@@ -30,12 +29,10 @@ type CastleEntity struct {
 
 	// Physics
 	shape *cp.Shape
-
-	gameOverCallback gameOverCallback
 }
 
-func NewCastle(world engine.GameEntityManager, cb gameOverCallback) (*CastleEntity, error) {
-	c := &CastleEntity{world: world, gameOverCallback: cb, GameEntityStats: engine.DefaultGameEntityStats()}
+func NewCastle(world engine.GameEntityManager) (*CastleEntity, error) {
+	c := &CastleEntity{world: world, GameEntityStats: engine.DefaultGameEntityStats()}
 	// Physical body
 	body := cp.NewKinematicBody()
 	body.SetVelocityUpdateFunc(c.calculateVelocity)
@@ -94,7 +91,7 @@ func (e *CastleEntity) OnCastleHit(arb *cp.Arbiter, space *cp.Space, userData in
 	// 	log.Println("Error in castle on hit: Expected attacker but did not receive one")
 	// 	return false
 	// }
-	// record, err := e.world.DamageModel().ApplyDamage(npc, e, e.world.IngameTime())
+	// record, err := e.world.ApplyDamage(npc, e)
 	// if err != nil {
 	// 	log.Println("Error during castle npc collision damage calc", err.Error())
 	// 	return false
@@ -116,7 +113,7 @@ func (e *CastleEntity) Destroy() error {
 	if err := e.asset.AnimationController().Loop("dead"); err != nil {
 		return err
 	}
-	e.gameOverCallback()
+	e.world.EndGame()
 	return nil
 }
 
@@ -180,6 +177,7 @@ func (e *CastleEntity) SetId(id engine.GameEntityId) { e.id = id }
 // Do nothing. We already have a reference to the world
 func (e *CastleEntity) SetEntityRemover(engine.EntityRemover) {}
 func (e *CastleEntity) Shape() *cp.Shape                      { return e.shape }
+func (e *CastleEntity) Body() *cp.Body                        { return e.shape.Body() }
 func (e *CastleEntity) LootTable() loot.LootTable             { return loot.NewEmptyLootTable() }
 func (e *CastleEntity) SetAsset(asset *engine.CharacterAsset) { e.asset = asset }
 func (e *CastleEntity) IsVulnerable() bool                    { return true }
@@ -196,6 +194,33 @@ func (e *CastleEntity) Inventory() loot.Inventory {
 	return nil
 }
 
+type CastleSnapshot struct {
+	Stats        engine.StatsSnapshot
+	Gun          *engine.GunSnapshot `json:",omitempty"`
+	PlayerInside bool
+}
+
+func (e *CastleEntity) Snapshot() CastleSnapshot {
+	s := CastleSnapshot{Stats: e.StatsSnapshot(), PlayerInside: e.playerInside != nil}
+	if e.gun != nil {
+		gun := e.gun.Snapshot()
+		s.Gun = &gun
+	}
+	return s
+}
+
+// Player needs to be restored beforehand to be able to enter again
+func (e *CastleEntity) Restore(s CastleSnapshot, player *engine.Player) error {
+	e.RestoreStats(s.Stats)
+	if s.Gun != nil && e.gun != nil {
+		e.gun.Restore(*s.Gun)
+	}
+	if s.PlayerInside && !player.Inside() {
+		return player.Enter(e)
+	}
+	return nil
+}
+
 func (e *CastleEntity) HealthBar() hud.ProgressInfo {
 	return hud.ProgressInfo{0, int(e.MaxHealth()), int(e.Health()), "Castle health"}
 }
@@ -3,15 +3,28 @@ package survival
 import (
 	"fmt"
 	"log"
+	"math"
 	"math/rand/v2"
+	"slices"
 
 	"github.com/jakecoffman/cp"
 	"github.com/lucb31/game-engine-go/engine"
+	"github.com/lucb31/game-engine-go/engine/loot"
 )
 
 type NpcType struct {
 	assetName string
 	opts      engine.NpcOpts
+	// Id of the loot table declaring drops
+	lootTable string
+	// Relative chance to be picked for a wave. 0 = only released by other creeps
+	spawnWeight int
+	// Ranged creeps only
+	gun        *engine.BasicGunOpts
+	projectile string
+	// Asset of the creeps released on death
+	splitInto  string
+	splitCount int
 }
 
 // ////////
@@ -20,121 +33,347 @@ type NpcType struct {
 
 // TODO: Config file or DB
 var availableNpcs = []NpcType{
-	{"npc-torch", engine.NpcOpts{BasePower: 30, BaseHealth: 60, BaseMovementSpeed: 75.0, GoldValue: 2}},
-	{"npc-orc", engine.NpcOpts{BasePower: 15, BaseHealth: 90, BaseMovementSpeed: 50.0, GoldValue: 3}},
-	{"npc-slime", engine.NpcOpts{BasePower: 50, BaseHealth: 30, BaseMovementSpeed: 25.0, GoldValue: 1}},
+	{
+		assetName: "npc-torch",
+		opts: engine.NpcOpts{BasePower: 30, BaseHealth: 60, BaseMovementSpeed: 75.0, Behaviour: "melee", Steering: steering(func(o *engine.SteeringOpts) {
+			// Fast & nimble: Overtake instead of queueing
+			o.Queue = 0.4
+			o.Responsiveness = 12
+		})},
+		lootTable:   "npc-torch",
+		spawnWeight: 3,
+	},
+	{
+		assetName: "npc-orc",
+		opts: engine.NpcOpts{BasePower: 15, BaseHealth: 90, BaseMovementSpeed: 50.0, Behaviour: "melee", Steering: steering(func(o *engine.SteeringOpts) {
+			// Bulky: Keeps more distance
+			o.Separation = 2
+			o.SeparationRadius = 10
+		})},
+		lootTable:   "npc-orc",
+		spawnWeight: 3,
+	},
+	{
+		assetName: "npc-slime",
+		// Splits into small slimes on death
+		opts: engine.NpcOpts{BasePower: 50, BaseHealth: 30, BaseMovementSpeed: 25.0, Behaviour: "melee", Steering: steering(func(o *engine.SteeringOpts) {
+			// Slimes clump together
+			o.Separation = 0.75
+			o.SeparationRadius = 2
+		})},
+		lootTable:   "npc-slime",
+		spawnWeight: 3,
+		splitInto:   "npc-slime-small",
+		splitCount:  2,
+	},
+	{
+		assetName: "npc-slime-small",
+		opts: engine.NpcOpts{BasePower: 20, BaseHealth: 10, BaseMovementSpeed: 35.0, Behaviour: "melee", Steering: steering(func(o *engine.SteeringOpts) {
+			o.Separation = 0.75
+			o.SeparationRadius = 2
+		})},
+		lootTable: "npc-slime-small",
+	},
+	{
+		assetName: "npc-archer",
+		// Keeps its distance & fires arrows
+		opts:        engine.NpcOpts{BasePower: 20, BaseHealth: 50, BaseMovementSpeed: 60.0, Behaviour: "archer", AggroRadius: 250},
+		lootTable:   "npc-archer",
+		spawnWeight: 2,
+		gun:         &engine.BasicGunOpts{FireRange: 220, FireRatePerSecond: 0.8},
+		projectile:  "arrow",
+	},
+	{
+		assetName: "npc-bomber",
+		// Explodes on contact, damaging everything around
+		opts: engine.NpcOpts{BasePower: 80, BaseHealth: 40, BaseMovementSpeed: 90.0, Behaviour: "bomber", AggroRadius: 200, AbilityRadius: 60, Steering: steering(func(o *engine.SteeringOpts) {
+			o.Queue = 0.4
+			o.Responsiveness = 12
+		})},
+		lootTable:   "npc-bomber",
+		spawnWeight: 1,
+	},
+	{
+		assetName: "npc-healer",
+		// Heals nearby creeps by its power every second
+		opts:        engine.NpcOpts{BasePower: 10, BaseHealth: 70, BaseMovementSpeed: 45.0, Behaviour: "healer", AbilityRadius: 100, FleeHealth: 0.4},
+		lootTable:   "npc-healer",
+		spawnWeight: 1,
+	},
+	{
+		assetName: "npc-shield",
+		// Blocks projectiles from the front
+		opts: engine.NpcOpts{BasePower: 20, BaseHealth: 120, BaseMovementSpeed: 40.0, Behaviour: "melee", ShieldAngle: 45, Steering: steering(func(o *engine.SteeringOpts) {
+			o.Separation = 2
+			o.SeparationRadius = 10
+		})},
+		lootTable:   "npc-shield",
+		spawnWeight: 2,
+	},
+}
+
+type BossType struct {
+	NpcType
+	name   string
+	phases []engine.BossPhase
+}
+
+// Spawned at the start of every boss wave. Drops its loot table on kill
+var boss = BossType{
+	NpcType: NpcType{
+		assetName:  "boss-orc",
+		opts:       engine.NpcOpts{BasePower: 40, BaseHealth: 1500, BaseArmor: 10, BaseMovementSpeed: 35.0, AggroRadius: 250, Size: 64},
+		lootTable:  "boss-orc",
+		gun:        &engine.BasicGunOpts{FireRange: 200, FireRatePerSecond: 2},
+		projectile: "bone",
+	},
+	name: "Orc warlord",
+	phases: []engine.BossPhase{
+		{Behaviour: "melee"},
+		// Throws bones & calls for help
+		{Health: 0.6, Behaviour: "ranged", Minions: 3, SummonInterval: 10},
+		// Enraged
+		{Health: 0.3, Behaviour: "melee", Minions: 2, SummonInterval: 6, Modifiers: []engine.StatModifier{
+			{Stat: engine.StatMovementSpeed, Op: engine.ModifierMultiply, Value: 1.8},
+			{Stat: engine.StatAtkSpeed, Op: engine.ModifierMultiply, Value: 1.5},
+		}},
+	},
+}
+
+// Released creeps spawn this far from the position of their parent
+const npcSplitSpread = 16.0
+
+// Default steering with type specific tweaks
+func steering(tweak func(*engine.SteeringOpts)) *engine.SteeringOpts {
+	opts := engine.DefaultSteeringOpts()
+	tweak(&opts)
+	return &opts
+}
+
+func npcTypeByAsset(name string) (NpcType, error) {
+	idx := slices.IndexFunc(availableNpcs, func(t NpcType) bool { return t.assetName == name })
+	if idx == -1 {
+		return NpcType{}, fmt.Errorf("Unknown npc type %s", name)
+	}
+	return availableNpcs[idx], nil
 }
 
 type SurvCreepProvider struct {
+	world        engine.GameEntityManager
 	assetManager engine.AssetManager
 	target       engine.DefenderEntity
 	// Required to not spawn within current viewport
 	camera engine.Camera
 	// Spawn areas
-	spawnAreaLayer engine.MapLayer
-	// Map waypoints used to unstuck creeps
-	aiWaypoints *engine.WaypointInfo
+	spawnArea *engine.SpawnArea
+	// Steers creeps around obstacles. Creeps move straight towards target if nil
+	navigation *engine.Navigation
+	lootTables *loot.LootTableDB
+	rng        *rand.Rand
 }
 
-func NewSurvCreepProvider(am engine.AssetManager, t engine.DefenderEntity, cam engine.Camera) (*SurvCreepProvider, error) {
-	return &SurvCreepProvider{assetManager: am, target: t, camera: cam}, nil
+func NewSurvCreepProvider(world engine.GameEntityManager, am engine.AssetManager, lootTables *loot.LootTableDB, t engine.DefenderEntity, cam engine.Camera, rng *rand.Rand) (*SurvCreepProvider, error) {
+	if rng == nil {
+		return nil, fmt.Errorf("Cannot init creep provider without random generator")
+	}
+	if lootTables == nil {
+		return nil, fmt.Errorf("Cannot init creep provider without loot tables")
+	}
+	return &SurvCreepProvider{world: world, assetManager: am, lootTables: lootTables, target: t, camera: cam, rng: rng}, nil
 }
 
-func (p *SurvCreepProvider) ParseNoSpawnArea(width, height int64, mapCsvData []byte) error {
-	layer, err := engine.NewBaseMapLayer(width, height, mapCsvData, nil)
+func (p *SurvCreepProvider) SetSpawnArea(area *engine.SpawnArea) { p.spawnArea = area }
+
+func (p *SurvCreepProvider) SetNavigation(nav *engine.Navigation) { p.navigation = nav }
+
+// Hand out navigation & guns of ranged creeps
+func (p *SurvCreepProvider) equip(npc *engine.NpcAggro, npcType NpcType) error {
+	npc.SetNavigation(p.navigation)
+	if npcType.gun == nil {
+		return nil
+	}
+	proj, err := p.assetManager.ProjectileAsset(npcType.projectile)
+	if err != nil {
+		return err
+	}
+	gun, err := engine.NewAimedGun(p.world, npc, proj, *npcType.gun)
 	if err != nil {
 		return err
 	}
-	p.spawnAreaLayer = layer
+	npc.SetGun(gun)
 	return nil
 }
 
-// NOTE: Space is required to calculate graph based on WP distances and collision between
-func (p *SurvCreepProvider) ParseCreepWaypoints(mapCsvData []byte, space *cp.Space) error {
-	mapTiles, err := engine.ReadCsvFromBinary(mapCsvData)
+func (p *SurvCreepProvider) NextNpc(wave engine.Wave) (engine.GameEntity, error) {
+	npcType := p.nextNpcType()
+	pos, err := p.calcCreepSpawnPosition(p.camera)
 	if err != nil {
-		return err
-	}
-	// Determine wp positions from CSV data
-	wpPositions := []cp.Vector{}
-	for row := range len(mapTiles) {
-		for col := range len(mapTiles[row]) {
-			if mapTiles[row][col] == engine.EmptyTile {
-				continue
-			}
-			x, y := engine.GridPosToCenterWorldPos(col, row)
-			wpPositions = append(wpPositions, cp.Vector{x, y})
-		}
+		return nil, err
 	}
+	return p.newNpc(npcType, pos, wave)
+}
 
-	// Build dijkstra graph for pathfinding based on wp positions
-	p.aiWaypoints, err = engine.NewWaypointInfo(space, wpPositions)
+// Splitting creeps release smaller copies around their position
+func (p *SurvCreepProvider) CreepsOnDeath(creep engine.GameEntity, wave engine.Wave) ([]engine.GameEntity, error) {
+	npc, ok := creep.(interface{ Asset() *engine.CharacterAsset })
+	if !ok {
+		return nil, nil
+	}
+	npcType, err := npcTypeByAsset(npc.Asset().Name())
+	if err != nil || npcType.splitInto == "" {
+		return nil, err
+	}
+	splitType, err := npcTypeByAsset(npcType.splitInto)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	return nil
+	res := []engine.GameEntity{}
+	pos := creep.Shape().Body().Position()
+	for idx := range npcType.splitCount {
+		angle := 2*math.Pi*float64(idx)/float64(npcType.splitCount) + p.rng.Float64()
+		split, err := p.newNpc(splitType, pos.Add(cp.ForAngle(angle).Mult(npcSplitSpread)), wave)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, split)
+	}
+	return res, nil
 }
 
-func (p *SurvCreepProvider) NextNpc(wave engine.Wave) (engine.GameEntity, error) {
-	npcType := p.nextNpcType()
+// Init npc incl. loot, equipment & wave scaling
+func (p *SurvCreepProvider) newNpc(npcType NpcType, pos cp.Vector, wave engine.Wave) (*engine.NpcAggro, error) {
 	// Load asset
 	npcAsset, err := p.assetManager.CharacterAsset(npcType.assetName)
 	if err != nil {
 		return nil, err
 	}
-	// Load opts & calculate starting position
+	// Load opts
 	opts := npcType.opts
-	opts.WaypointInfo = *p.aiWaypoints
-	opts.StartingPos, err = p.calcCreepSpawnPosition(p.camera)
+	if opts.LootTable, err = p.lootTables.Table(npcType.lootTable); err != nil {
+		return nil, err
+	}
+	opts.StartingPos = pos
+	// Init npc
+	npc, err := engine.NewNpcAggro(p.target, npcAsset, opts)
 	if err != nil {
 		return nil, err
 	}
-	// Apply scaling
-	opts.BaseHealth = wave.HealthScalingFunc(opts.BaseHealth)
+	if err := p.equip(npc, npcType); err != nil {
+		return nil, err
+	}
+	scaleHealth(npc, wave)
+	return npc, nil
+}
 
-	// Init npc
+func (p *SurvCreepProvider) NextBoss(wave engine.Wave) (*engine.NpcBoss, error) {
+	pos, err := p.calcCreepSpawnPosition(p.camera)
+	if err != nil {
+		return nil, err
+	}
+	npc, err := p.newBoss(pos)
+	if err != nil {
+		return nil, err
+	}
+	scaleHealth(npc.NpcAggro, wave)
+	return npc, nil
+}
+
+func (p *SurvCreepProvider) newBoss(pos cp.Vector) (*engine.NpcBoss, error) {
+	asset, err := p.assetManager.CharacterAsset(boss.assetName)
+	if err != nil {
+		return nil, err
+	}
+	opts := engine.BossOpts{NpcOpts: boss.opts, Name: boss.name, Phases: boss.phases}
+	if opts.LootTable, err = p.lootTables.Table(boss.lootTable); err != nil {
+		return nil, err
+	}
+	opts.StartingPos = pos
+	npc, err := engine.NewNpcBoss(p.target, asset, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.equip(npc.NpcAggro, boss.NpcType); err != nil {
+		return nil, err
+	}
+	return npc, nil
+}
+
+// Recreate boss of a saved game. State is applied by the world afterwards
+func (p *SurvCreepProvider) RestoreBoss(s engine.EntitySnapshot) (*engine.NpcBoss, error) {
+	if s.Asset != boss.assetName {
+		return nil, fmt.Errorf("Unknown boss %s", s.Asset)
+	}
+	return p.newBoss(s.Position)
+}
+
+// Wave scaling of max health
+func scaleHealth(npc *engine.NpcAggro, wave engine.Wave) {
+	bonus := wave.HealthScalingFunc(npc.MaxHealth()) - npc.MaxHealth()
+	if bonus > 0 {
+		source := engine.StatSource{Kind: engine.StatSourceWave, Name: fmt.Sprintf("Wave %d", wave.Round)}
+		npc.AddModifier(engine.StatModifier{Stat: engine.StatMaxHealth, Op: engine.ModifierAdd, Value: bonus, Source: source})
+		npc.SetHealth(npc.MaxHealth())
+	}
+}
+
+// Recreate npc of a saved game. State is applied by the world afterwards
+func (p *SurvCreepProvider) RestoreNpc(s engine.EntitySnapshot) (*engine.NpcAggro, error) {
+	npcType, err := npcTypeByAsset(s.Asset)
+	if err != nil {
+		return nil, err
+	}
+	npcAsset, err := p.assetManager.CharacterAsset(s.Asset)
+	if err != nil {
+		return nil, err
+	}
+	opts := npcType.opts
+	if opts.LootTable, err = p.lootTables.Table(npcType.lootTable); err != nil {
+		return nil, err
+	}
 	npc, err := engine.NewNpcAggro(p.target, npcAsset, opts)
 	if err != nil {
 		return nil, err
 	}
+	if err := p.equip(npc, npcType); err != nil {
+		return nil, err
+	}
 	return npc, nil
 }
 
 // Creeps spawning is restricted by
-// - map layer that determines spawnable areas
+// - spawn area shapes
 // - camera viewport
 func (p *SurvCreepProvider) calcCreepSpawnPosition(cam engine.Camera) (cp.Vector, error) {
 	for tries := 0; tries < 10; tries++ {
 		// Select random position within spawnable area
-		width, height := p.spawnAreaLayer.Dimensions()
-		row := rand.IntN(height)
-		col := rand.IntN(width)
-		randX, randY := engine.GridPosToCenterWorldPos(col, row)
-
-		// Check spawn area layer
-		tileAt, err := p.spawnAreaLayer.TileAt(cp.Vector{randX, randY})
+		pos, err := p.spawnArea.RandomPosition(p.rng)
 		if err != nil {
-			log.Printf("Error checking spawnable area. Retrying...\n")
-			continue
-		}
-		if tileAt == -1 {
-			log.Printf("Not a spawnable area (%d). Retrying...\n", tileAt)
+			log.Printf("Error checking spawnable area: %s. Retrying...\n", err.Error())
 			continue
 		}
 
 		// Check if within camera viewport
-		if cam.VectorVisible(cp.Vector{randX, randY}) {
-			log.Println("Position within viewport. Retrying...", randX, randY)
+		if cam.VectorVisible(pos) {
+			log.Println("Position within viewport. Retrying...", pos.X, pos.Y)
 			continue
 		}
-		return cp.Vector{X: randX, Y: randY}, nil
+		return pos, nil
 	}
 	return cp.Vector{}, fmt.Errorf("Could not find a spawn position. Max tries reached")
 }
 
-// Choose a random npc type to spawn next
+// Choose a random npc type to spawn next, weighted by spawn weight
 func (p *SurvCreepProvider) nextNpcType() NpcType {
-	idx := rand.IntN(len(availableNpcs))
-	return availableNpcs[idx]
+	total := 0
+	for _, npcType := range availableNpcs {
+		total += npcType.spawnWeight
+	}
+	roll := p.rng.IntN(total)
+	for _, npcType := range availableNpcs {
+		if roll < npcType.spawnWeight {
+			return npcType
+		}
+		roll -= npcType.spawnWeight
+	}
+	return availableNpcs[0]
 }
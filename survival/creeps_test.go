@@ -0,0 +1,163 @@
+package survival
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+func newTestCreepProvider(t *testing.T) (*SurvCreepProvider, engine.Wave) {
+	g, err := NewHeadlessSurvivalGame(1920, 1080, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewSurvCreepProvider(g.world, g.world.AssetManager, g.world.LootTables(), g.castle, nil, g.world.Rng())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider, engine.Wave{Round: 1, HealthScalingFunc: func(baseHealth float64) float64 { return baseHealth }}
+}
+
+func TestAvailableNpcsSpawn(t *testing.T) {
+	provider, wave := newTestCreepProvider(t)
+	for _, npcType := range availableNpcs {
+		npc, err := provider.newNpc(npcType, cp.Vector{X: 100, Y: 100}, wave)
+		if err != nil {
+			t.Fatalf("Could not spawn %s: %s", npcType.assetName, err.Error())
+		}
+		if (npcType.gun != nil) != (npc.Gun() != nil) {
+			t.Errorf("Expected %s to be armed according to config", npcType.assetName)
+		}
+	}
+}
+
+func TestSlimeSplitsOnDeath(t *testing.T) {
+	provider, wave := newTestCreepProvider(t)
+	slime, err := npcTypeByAsset("npc-slime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	npc, err := provider.newNpc(slime, cp.Vector{X: 100, Y: 100}, wave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creeps, err := provider.CreepsOnDeath(npc.NpcEntity, wave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(creeps) != slime.splitCount {
+		t.Fatalf("Expected %d creeps, got %d", slime.splitCount, len(creeps))
+	}
+	for _, creep := range creeps {
+		if name := creep.(*engine.NpcAggro).Asset().Name(); name != slime.splitInto {
+			t.Errorf("Expected %s, got %s", slime.splitInto, name)
+		}
+	}
+	// Small slimes do not split any further
+	if creeps, _ := provider.CreepsOnDeath(creeps[0], wave); len(creeps) > 0 {
+		t.Error("Expected small slime not to split")
+	}
+}
+
+func TestBossSpawnsWithGuaranteedLoot(t *testing.T) {
+	provider, _ := newTestCreepProvider(t)
+	npc, err := provider.newBoss(cp.Vector{X: 100, Y: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if npc.Gun() == nil {
+		t.Error("Expected boss to be armed for its ranged phase")
+	}
+	// Gold, wood & an equipment item
+	if items := npc.LootTable().Result(provider.rng); len(items) < 3 {
+		t.Errorf("Expected guaranteed boss loot, got %d items", len(items))
+	}
+	if _, err := provider.RestoreBoss(engine.EntitySnapshot{Kind: engine.EntityKindBoss, Asset: "npc-orc"}); err == nil {
+		t.Error("Expected error for unknown boss")
+	}
+}
+
+func TestBossRestoresWithGuaranteedLoot(t *testing.T) {
+	g, err := NewHeadlessSurvivalGame(1920, 1080, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewSurvCreepProvider(g.world, g.world.AssetManager, g.world.LootTables(), g.castle, nil, g.world.Rng())
+	if err != nil {
+		t.Fatal(err)
+	}
+	npc, err := provider.newBoss(cp.Vector{X: 100, Y: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.world.AddEntity(npc); err != nil {
+		t.Fatal(err)
+	}
+	// Drop below the last threshold & let the boss enter its final phase
+	npc.SetHealth(npc.MaxHealth() * 0.1)
+	npc.Update()
+	if npc.Phase() != len(boss.phases)-1 {
+		t.Fatalf("Expected boss in phase %d, got %d", len(boss.phases)-1, npc.Phase())
+	}
+	snapshot, err := g.world.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore into a fresh world with the same seed
+	restoredSnapshot := &engine.WorldSnapshot{}
+	if err := json.Unmarshal(data, restoredSnapshot); err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewHeadlessSurvivalGame(1920, 1080, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherProvider, err := NewSurvCreepProvider(other.world, other.world.AssetManager, other.world.LootTables(), other.castle, nil, other.world.Rng())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultFactory := engine.NewDefaultEntityFactory(other.world.AssetManager)
+	factory := func(s engine.EntitySnapshot) (engine.SnapshotEntity, error) {
+		switch s.Kind {
+		case engine.EntityKindNpc:
+			return otherProvider.RestoreNpc(s)
+		case engine.EntityKindBoss:
+			return otherProvider.RestoreBoss(s)
+		}
+		return defaultFactory(s)
+	}
+	entities, err := other.world.Restore(restoredSnapshot, factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored *engine.NpcBoss
+	for _, entity := range entities {
+		if b, ok := entity.(*engine.NpcBoss); ok {
+			restored = b
+		}
+	}
+	if restored == nil {
+		t.Fatal("Expected boss to be restored")
+	}
+	if restored.Name() != boss.name {
+		t.Errorf("Expected %s, got %s", boss.name, restored.Name())
+	}
+	if restored.Phase() != npc.Phase() {
+		t.Errorf("Expected phase %d, got %d", npc.Phase(), restored.Phase())
+	}
+	if restored.Health() != npc.Health() {
+		t.Errorf("Expected health %.1f, got %.1f", npc.Health(), restored.Health())
+	}
+	table, ok := restored.LootTable().(*loot.WeightedLootTable)
+	if !ok || table.Id != boss.lootTable {
+		t.Errorf("Expected guaranteed %s loot table after restore, got %v", boss.lootTable, restored.LootTable())
+	}
+}
@@ -3,12 +3,12 @@ package survival
 import (
 	"log"
 	"math"
-	"math/rand"
+	"math/rand/v2"
 
 	"github.com/jakecoffman/cp"
 )
 
-func entityDonutDistribution(center cp.Vector, innerRadius, outerRadius float64, count int, spacing float64) []cp.Vector {
+func entityDonutDistribution(rng *rand.Rand, center cp.Vector, innerRadius, outerRadius float64, count int, spacing float64) []cp.Vector {
 	if innerRadius > outerRadius {
 		log.Println("Inner radius < Outer radius. Probably not intended!")
 	}
@@ -19,7 +19,7 @@ func entityDonutDistribution(center cp.Vector, innerRadius, outerRadius float64,
 	for ; len(entityBBs) < count && tries < maxTries; tries++ {
 		// 		x := rand.Float64()*areaRadius*2 - areaRadius
 		// 		y := rand.Float64()*areaRadius*2 - areaRadius
-		radius := innerRadius + rand.Float64()*(outerRadius-innerRadius)
+		radius := innerRadius + rng.Float64()*(outerRadius-innerRadius)
 		x := math.Sin(float64(tries)) * radius
 		y := math.Cos(float64(tries)) * radius
 		currentCenter := center.Add(cp.Vector{x, y})
@@ -57,14 +57,14 @@ func posRingDistribution(center cp.Vector, radius, bbRadius, density float64) []
 	return entityPos
 }
 
-func entityCircleDistribution(areaCenter cp.Vector, areaRadius float64, entityCount int, spacing float64) []cp.Vector {
+func entityCircleDistribution(rng *rand.Rand, areaCenter cp.Vector, areaRadius float64, entityCount int, spacing float64) []cp.Vector {
 	maxTries := entityCount * 10
 	entityBBs := []cp.BB{}
 	entityPos := []cp.Vector{}
 	tries := 0
 	for ; len(entityBBs) < entityCount && tries < maxTries; tries++ {
-		x := rand.Float64()*areaRadius*2 - areaRadius
-		y := rand.Float64()*areaRadius*2 - areaRadius
+		x := rng.Float64()*areaRadius*2 - areaRadius
+		y := rng.Float64()*areaRadius*2 - areaRadius
 		currentCenter := areaCenter.Add(cp.Vector{x, y})
 		currentBB := cp.NewBBForCircle(currentCenter, spacing)
 		intersects := false
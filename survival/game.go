@@ -3,14 +3,15 @@ package survival
 import (
 	"fmt"
 	"log"
+	"math/rand/v2"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/jakecoffman/cp"
-	"github.com/lucb31/game-engine-go/bin/assets"
 	"github.com/lucb31/game-engine-go/engine"
 	"github.com/lucb31/game-engine-go/engine/hud"
+	"github.com/lucb31/game-engine-go/engine/loot"
 )
 
 type SurvivalGame struct {
@@ -19,28 +20,72 @@ type SurvivalGame struct {
 	castle       *CastleEntity
 
 	hud                       *hud.GameHUD
+	shop                      *ShopMenu
 	screenWidth, screenHeight int
 	audioContext              *audio.Context
+	// Seed of the current game world
+	seed uint64
+	// Headless games do not render, play audio or have a HUD
+	headless bool
+
+	// Recording: Inputs are written to recordPath on game over
+	recorder   *engine.InputRecorder
+	recordPath string
+	// Playback: Inputs & game speed are taken from replay. Speed controls playback rate instead
+	playback      *engine.Replay
+	playbackSpeed float64
+	playbackTicks float64
+
+	// Saved game to restore on next initialization instead of generating a new world
+	restoreFrom   *SaveGame
+	quickSaveGame *SaveGame
 }
 
 func (g *SurvivalGame) Update() error {
-	g.world.Update()
-	g.hud.Update()
+	if g.playback != nil {
+		if err := g.updatePlayback(); err != nil {
+			log.Println("Could not update replay: ", err.Error())
+		}
+	} else {
+		g.simulateTick()
+	}
+	if !g.headless {
+		g.hud.Update()
+		if inpututil.IsKeyJustPressed(ebiten.KeyF8) {
+			g.saveRecording()
+		}
+		if g.playback == nil && inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+			g.quickSave()
+		}
+		if g.playback == nil && inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+			g.quickLoad()
+		}
+	}
 	if g.world.IsOver() {
 		// Wait for restart
 		if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+			// Derive next seed to keep restarts reproducible. Replays restart from the beginning
+			if g.playback == nil {
+				g.seed = g.world.Rng().Uint64()
+			}
 			err := g.initialize()
 			if err != nil {
 				log.Println("Could not restart game: ", err.Error())
 			}
 		}
-		return nil
+	}
+	return nil
+}
+
+// Advance world & creeps by a single tick
+func (g *SurvivalGame) simulateTick() {
+	g.world.Update()
+	if g.world.IsOver() {
+		return
 	}
 	if err := g.creepManager.Update(); err != nil {
 		log.Println("Could not update creeps: ", err.Error())
 	}
-
-	return nil
 }
 
 func (g *SurvivalGame) Draw(screen *ebiten.Image) {
@@ -64,13 +109,23 @@ func (game *SurvivalGame) initialize() error {
 	generator.SetWorldDimensions(2912, 2912)
 	generator.SetScreenDimension(game.screenWidth, game.screenHeight)
 
-	// Generate random level
-	gameWorld, err := engine.NewGeneratedWorld(generator)
+	// Generate random level or restore saved one
+	var gameWorld *engine.GameWorld
+	if game.restoreFrom != nil {
+		gameWorld, err = generator.EmptyWorld(game.seed)
+	} else {
+		gameWorld, err = engine.NewGeneratedWorld(generator, game.seed)
+	}
 	if err != nil {
 		return fmt.Errorf("Error during level generation: %s", err.Error())
 	}
 	game.world = gameWorld
+	engine.Subscribe(gameWorld.Events(), game.onGameOver)
 	am := gameWorld.AssetManager
+	castleMap, err := loadCastleMap(am)
+	if err != nil {
+		return err
+	}
 
 	// Init fog of war
 	if game.world.FogOfWar, err = engine.NewDiscoveryLayer(game.world.Width, game.world.Height); err != nil {
@@ -88,7 +143,11 @@ func (game *SurvivalGame) initialize() error {
 		return err
 	}
 	player.SetAxe(axe)
-	player.Shape().Body().SetPosition(cp.Vector{1456, 1656})
+	playerStart, err := castleMap.Marker("player_start")
+	if err != nil {
+		return err
+	}
+	player.Shape().Body().SetPosition(playerStart.Center())
 
 	// Init main camera
 	camera, err := engine.NewFollowingCamera(game.screenWidth, game.screenHeight)
@@ -99,7 +158,11 @@ func (game *SurvivalGame) initialize() error {
 	game.world.SetCamera(camera)
 
 	// Castle
-	if err := game.initCastle(camera); err != nil {
+	castleMarker, err := castleMap.Marker("castle")
+	if err != nil {
+		return err
+	}
+	if err := game.initCastle(camera, castleMarker.Center()); err != nil {
 		return err
 	}
 	// Lift FoW around castle area
@@ -113,37 +176,73 @@ func (game *SurvivalGame) initialize() error {
 	if err != nil {
 		return err
 	}
-	provider, err := NewSurvCreepProvider(am, game.castle, camera)
+	provider, err := NewSurvCreepProvider(gameWorld, am, gameWorld.LootTables(), game.castle, camera, gameWorld.Rng())
 	if err != nil {
 		return err
 	}
-	if err = provider.ParseNoSpawnArea(game.world.Width, game.world.Height, assets.MapDarkLogicSpawnAreaCSV); err != nil {
+	spawnRegions, ok := castleMap.ObjectLayers["Logic/SpawnAreas"]
+	if !ok {
+		return fmt.Errorf("Castle map is missing spawn areas")
+	}
+	spawnArea, err := engine.NewSpawnArea(spawnRegions.Objects)
+	if err != nil {
 		return err
 	}
-	if err = provider.ParseCreepWaypoints(assets.MapDarkLogicWaypointsCSV, gameWorld.Space()); err != nil {
+	provider.SetSpawnArea(spawnArea)
+	// Static colliders drawn in tiled
+	if err := castleMap.RegisterColliders(gameWorld.Space()); err != nil {
 		return err
 	}
+	// Nav grid needs all obstacles incl. castle
+	navigation, err := gameWorld.BuildNavigation(engine.DefaultNavCellSize)
+	if err != nil {
+		return err
+	}
+	provider.SetNavigation(navigation)
 	if err = game.creepManager.SetProvider(provider); err != nil {
 		return err
 	}
 
-	// Init hud
-	game.hud, err = game.initHud()
-	if err != nil {
+	// Input recording & playback
+	if game.playback != nil {
+		if err := game.startPlayback(); err != nil {
+			return err
+		}
+	} else if game.recordPath != "" {
+		if err := game.startRecording(engine.NewKeyboardInputSource()); err != nil {
+			return err
+		}
+	}
+
+	// Init shop & hud
+	if err := game.initShop(); err != nil {
 		return err
 	}
+	if !game.headless {
+		game.hud, err = game.initHud()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Restore saved state last. Random generator state must not be advanced afterwards
+	if game.restoreFrom != nil {
+		if err := game.restoreSave(game.restoreFrom, provider); err != nil {
+			return fmt.Errorf("Could not restore saved game: %s", err.Error())
+		}
+	}
 
 	return nil
 }
 
-func (game *SurvivalGame) initCastle(camera *engine.FollowingCamera) error {
+func (game *SurvivalGame) initCastle(camera *engine.FollowingCamera, pos cp.Vector) error {
 	var err error
 	// Init castle
-	game.castle, err = NewCastle(game.world, game.EndGame)
+	game.castle, err = NewCastle(game.world)
 	if err != nil {
 		return err
 	}
-	game.castle.Shape().Body().SetPosition(cp.Vector{1456, 1456})
+	game.castle.Shape().Body().SetPosition(pos)
 	// Init asset
 	castleAsset, err := game.world.AssetManager.CharacterAsset("castle")
 	if err != nil {
@@ -173,6 +272,30 @@ func (game *SurvivalGame) initCastle(camera *engine.FollowingCamera) error {
 	return nil
 }
 
+// Second pcg input for the shop generator. The world uses the seed for both
+const shopRngStream = 0x5e0b
+
+// Shop is part of the simulation, so it also exists in headless games
+// It rolls items with its own generator derived from the world seed. Opening
+// or using the shop must not advance the gameplay rng
+func (g *SurvivalGame) initShop() error {
+	catalog, err := LoadShopCatalog(engine.AssetReader(), ShopDefinitionsPath, g.world.Resources())
+	if err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewPCG(g.world.Seed(), shopRngStream))
+	shop, err := NewShopMenu(g.world.Player().Inventory(), g.world.Player(), g.castle, catalog, rng)
+	if err != nil {
+		return err
+	}
+	// Allow castle to control shop enabled state
+	shop.SetShopEnabler(g.castle)
+	shop.SetGunProvider(g.castle)
+	shop.SetActionListener(g.recordEvent)
+	g.shop = shop
+	return nil
+}
+
 func (g *SurvivalGame) initHud() (*hud.GameHUD, error) {
 	// Init base
 	base, err := hud.NewHUD(g)
@@ -180,30 +303,28 @@ func (g *SurvivalGame) initHud() (*hud.GameHUD, error) {
 		return nil, err
 	}
 
-	// Init shop
-	inventory := g.world.Player().Inventory()
-	shop, err := NewShopMenu(inventory, g.world.Player(), g.castle)
+	base.AddSubMenu(g.shop)
+
+	// Init inventory
+	inventoryHud, err := hud.NewInventoryHud(g.world.Player().Inventory(), engine.LoadImageAsset)
 	if err != nil {
 		return nil, err
 	}
-	// Allow castle to control shop enabled state
-	shop.SetShopEnabler(g.castle)
-	shop.SetGunProvider(g.castle)
-	base.AddSubMenu(shop)
+	base.AddSubMenu(inventoryHud)
 
-	// Init inventory
-	inventoryHud, err := hud.NewInventoryHud(inventory)
+	// Init bag & equipment
+	equipmentHud, err := hud.NewEquipmentHud(&recordingEquipmentHolder{g.world.Player(), g})
 	if err != nil {
 		return nil, err
 	}
-	base.AddSubMenu(inventoryHud)
+	base.AddSubMenu(equipmentHud)
 
 	return base, nil
 }
 
 // Constructor: Initialize parts of game that are constant even after restarting
-func NewSurvivalGame(screenWidth, screenHeight int) (*SurvivalGame, error) {
-	game := &SurvivalGame{screenWidth: screenWidth, screenHeight: screenHeight}
+func NewSurvivalGame(screenWidth, screenHeight int, seed uint64) (*SurvivalGame, error) {
+	game := &SurvivalGame{screenWidth: screenWidth, screenHeight: screenHeight, seed: seed}
 
 	// Setup audio context
 	game.audioContext = audio.NewContext(48000)
@@ -216,18 +337,42 @@ func NewSurvivalGame(screenWidth, screenHeight int) (*SurvivalGame, error) {
 	return game, nil
 }
 
-func (g *SurvivalGame) SetSpeed(speed float64) { g.world.GameSpeed = speed }
-func (g *SurvivalGame) GameOver() bool         { return g.world.IsOver() }
+// Game without audio & HUD that can be stepped via engine.RunHeadless
+func NewHeadlessSurvivalGame(screenWidth, screenHeight int, seed uint64) (*SurvivalGame, error) {
+	game := &SurvivalGame{screenWidth: screenWidth, screenHeight: screenHeight, seed: seed, headless: true}
+	if err := game.initialize(); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+func (g *SurvivalGame) SetSpeed(speed float64) {
+	if g.playback != nil {
+		g.playbackSpeed = speed
+		return
+	}
+	g.world.GameSpeed = speed
+	if g.recorder != nil {
+		g.recorder.Replay().RecordSpeedChange(g.world.Tick(), speed)
+	}
+}
+func (g *SurvivalGame) GameOver() bool { return g.world.IsOver() }
 func (g *SurvivalGame) Score() hud.ScoreValue {
-	return hud.ScoreValue(g.world.Player().Inventory().GoldManager().Revenue())
+	return hud.ScoreValue(g.world.Player().Inventory().Resource(loot.ResourceGold).Revenue())
 }
 func (g *SurvivalGame) CastleProgress() hud.ProgressInfo { return g.castle.HealthBar() }
 func (g *SurvivalGame) CreepProgress() hud.ProgressInfo  { return g.creepManager.Progress() }
+func (g *SurvivalGame) BossProgress() hud.ProgressInfo   { return g.creepManager.BossProgress() }
 
-func (g *SurvivalGame) EndGame() {
-	g.world.EndGame()
-	if err := g.world.Player().Destroy(); err != nil {
-		log.Println("Could not destroy player on game over", err.Error())
+func (g *SurvivalGame) EndGame() { g.world.EndGame() }
+
+func (g *SurvivalGame) onGameOver(engine.GameOver) {
+	// Player might have died already
+	if player := g.world.Player(); player.Health() > 0 {
+		if err := player.Destroy(); err != nil {
+			log.Println("Could not destroy player on game over", err.Error())
+		}
 	}
+	g.saveRecording()
 	log.Println("Waiting for restart...")
 }
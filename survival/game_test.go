@@ -0,0 +1,294 @@
+package survival
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+	"github.com/lucb31/game-engine-go/engine"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+// Serialize positions of all physical bodies
+func worldState(g *SurvivalGame) string {
+	var b strings.Builder
+	g.world.Space().EachBody(func(body *cp.Body) {
+		fmt.Fprintf(&b, "%.6f,%.6f;", body.Position().X, body.Position().Y)
+	})
+	return b.String()
+}
+
+func TestHeadlessSimulationIsDeterministic(t *testing.T) {
+	ticks := 60 * 20
+	states := []string{}
+	for _, seed := range []uint64{42, 42, 7} {
+		g, err := NewHeadlessSurvivalGame(1920, 1080, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.SetSpeed(2.0)
+		initial := worldState(g)
+		if _, err := engine.RunHeadless(g, ticks); err != nil {
+			t.Fatal(err)
+		}
+		state := worldState(g)
+		if state == initial {
+			t.Fatal("Expected simulation to change world state")
+		}
+		states = append(states, state)
+	}
+	if states[0] != states[1] {
+		t.Error("Expected identical state for identical seeds")
+	}
+	if states[0] == states[2] {
+		t.Error("Expected different state for different seeds")
+	}
+}
+
+func TestReplayReproducesRecordedSession(t *testing.T) {
+	ticks := 60 * 10
+	// Scripted session: Walk, dash, interact & change speed midway
+	script := engine.NewReplay(replayGameName, 0, 1920, 1080)
+	for tick := range ticks {
+		frame := engine.InputFrame(engine.InputMoveEast)
+		if tick > ticks/2 {
+			frame = engine.InputFrame(engine.InputMoveNorth | engine.InputMoveWest)
+		}
+		if tick%90 == 0 {
+			frame |= engine.InputFrame(engine.InputDash)
+		}
+		if tick%200 < 5 {
+			frame |= engine.InputFrame(engine.InputInteract)
+		}
+		script.Frames = append(script.Frames, frame)
+	}
+	source, err := engine.NewReplayInputSource(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record
+	g, err := NewHeadlessSurvivalGame(1920, 1080, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.startRecording(source); err != nil {
+		t.Fatal(err)
+	}
+	for tick := range ticks {
+		if tick == ticks/3 {
+			g.SetSpeed(2.0)
+		}
+		if err := g.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recorded := worldState(g)
+
+	// Round trip through file format
+	var buf bytes.Buffer
+	if err := g.recorder.Replay().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := engine.ReadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Frames) == 0 || len(replay.SpeedChanges) != 1 {
+		t.Fatalf("Unexpected replay: %d frames, %d speed changes", len(replay.Frames), len(replay.SpeedChanges))
+	}
+
+	// Play back
+	playback, err := NewHeadlessSurvivalGame(1920, 1080, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := playback.Replay(replay); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.RunHeadless(playback, ticks); err != nil {
+		t.Fatal(err)
+	}
+	if worldState(playback) != recorded {
+		t.Error("Expected replay to reproduce recorded state")
+	}
+}
+
+// Serialize resources, shop & bag contents
+func uiState(g *SurvivalGame) string {
+	var b strings.Builder
+	player := g.world.Player()
+	fmt.Fprintf(&b, "gold %d; upgrades %v;", player.Inventory().Resource(loot.ResourceGold).Balance(), g.shop.catalog.Snapshot())
+	for _, slot := range g.shop.randomItemSlots {
+		if slot.item != nil {
+			fmt.Fprintf(&b, "%s,", slot.item.Id)
+		}
+	}
+	for idx := range player.Bag().Size() {
+		fmt.Fprintf(&b, "%v,", player.Bag().Slot(idx))
+	}
+	fmt.Fprintf(&b, "weapon %v", player.EquippedItem(loot.EquipmentWeapon))
+	return b.String()
+}
+
+// Same starting resources & items for recording and playback
+func setupUiTestGame(t *testing.T, g *SurvivalGame) {
+	player := g.world.Player()
+	if _, err := player.Inventory().Resource(loot.ResourceGold).Add(1000); err != nil {
+		t.Fatal(err)
+	}
+	sword, err := g.world.Items().NewInstance("short-sword", 1, rand.New(rand.NewPCG(1, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := player.Bag().Add(sword); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplayReproducesUiActions(t *testing.T) {
+	ticks := 60 * 3
+	script := engine.NewReplay(replayGameName, 0, 1920, 1080)
+	script.Frames = make([]engine.InputFrame, ticks)
+	source, err := engine.NewReplayInputSource(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record: Ui actions happen between ticks, like hud updates
+	g, err := NewHeadlessSurvivalGame(1920, 1080, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.startRecording(source); err != nil {
+		t.Fatal(err)
+	}
+	setupUiTestGame(t, g)
+	initial := uiState(g)
+	holder := &recordingEquipmentHolder{g.world.Player(), g}
+	actions := map[int]func(){
+		10: func() { g.shop.RerollHandler(0) },
+		20: func() { g.shop.randomizedItemSlotBuyHandler(1) },
+		30: func() { g.shop.upgradeBuyHandler(g.shop.upgradeSlots[0].item.Id) },
+		40: func() { holder.MoveInBag(0, 3) },
+		50: func() { holder.EquipFromBag(3) },
+		60: func() { holder.UnequipToBag(loot.EquipmentWeapon, 5) },
+	}
+	for tick := range ticks {
+		if err := g.Update(); err != nil {
+			t.Fatal(err)
+		}
+		if action, ok := actions[tick]; ok {
+			action()
+		}
+	}
+	recorded := uiState(g)
+	if recorded == initial {
+		t.Fatal("Expected ui actions to change state")
+	}
+	replay := g.recorder.Replay()
+	if len(replay.Events) != len(actions) {
+		t.Fatalf("Expected %d recorded events, got %d", len(actions), len(replay.Events))
+	}
+
+	// Play back
+	playback, err := NewHeadlessSurvivalGame(1920, 1080, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := playback.Replay(replay); err != nil {
+		t.Fatal(err)
+	}
+	setupUiTestGame(t, playback)
+	if _, err := engine.RunHeadless(playback, ticks); err != nil {
+		t.Fatal(err)
+	}
+	if state := uiState(playback); state != recorded {
+		t.Errorf("Expected replay to reproduce ui actions.\nRecorded: %s\nReplayed: %s", recorded, state)
+	}
+}
+
+func TestSaveGameRoundTrip(t *testing.T) {
+	g, err := NewHeadlessSurvivalGame(1920, 1080, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetSpeed(2.0)
+	// Run until first creeps have spawned
+	if _, err := engine.RunHeadless(g, 60*25); err != nil {
+		t.Fatal(err)
+	}
+	save, err := g.SaveGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved bytes.Buffer
+	if err := save.Write(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(saved.String(), string(engine.EntityKindNpc)) {
+		t.Fatal("Expected save to contain npcs")
+	}
+
+	// Load into a different game & save again
+	loaded, err := ReadSaveGame(bytes.NewReader(saved.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewHeadlessSurvivalGame(1920, 1080, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.LoadGame(loaded); err != nil {
+		t.Fatal(err)
+	}
+	resave, err := other.SaveGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resaved bytes.Buffer
+	if err := resave.Write(&resaved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.String() != resaved.String() {
+		t.Error("Expected loaded game to match saved state")
+	}
+	if g.world.Player().Position() != other.world.Player().Position() {
+		t.Error("Expected identical player position after loading")
+	}
+}
+
+func TestShopDoesNotAdvanceWorldRng(t *testing.T) {
+	draws := []uint64{}
+	for _, rerolls := range []int{0, 5} {
+		g, err := NewHeadlessSurvivalGame(1920, 1080, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.shop == nil {
+			t.Fatal("Expected headless game to have a shop")
+		}
+		for range rerolls {
+			g.shop.RerollItemSlot(0)
+		}
+		draws = append(draws, g.world.Rng().Uint64())
+	}
+	if draws[0] != draws[1] {
+		t.Error("Expected shop rerolls to leave world rng untouched")
+	}
+}
+
+func TestSurvivalResourcesAreRegistered(t *testing.T) {
+	g, err := NewHeadlessSurvivalGame(1920, 1080, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []loot.ResourceId{loot.ResourceGold, ResourceStone, ResourceCrystals} {
+		if g.world.Player().Inventory().Resource(id) == nil {
+			t.Errorf("Expected player inventory to hold %s", id)
+		}
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"fmt"
 	"image/color"
 	"log"
-	"math/rand"
+	"math/rand/v2"
+	"slices"
 	"strings"
 
 	"github.com/ebitenui/ebitenui/image"
@@ -34,6 +35,12 @@ type ShopMenu struct {
 	castle engine.GameEntityStatReadWriter
 	// Reference to where to apply gun upgrades
 	GunProvider
+	// Item & upgrade definitions
+	catalog *ShopCatalog
+	// Used to reroll random item slots
+	rng *rand.Rand
+	// Notified about every purchase & reroll, e.g. to record them. Tick is not set
+	onAction func(engine.ReplayEvent)
 
 	// UI
 	shopContainer *widget.Container
@@ -44,88 +51,59 @@ type ShopMenu struct {
 
 	// Logic
 	randomItemSlots []*ShopItemSlot
-	fixedItemSlots  []*ShopItemSlot
+	upgradeSlots    []*ShopItemSlot
 }
 
 type ShopItemSlot struct {
-	item             *loot.GameItem
+	// Nil, if there is nothing left to offer
+	item             *ShopItemDefinition
 	buyButton        *widget.Button
 	rerollButton     *widget.Button
 	priceLabel       *widget.Text
 	descriptionLabel *widget.Text
+	// Upgrades only: Purchases & lock reason
+	statusLabel *widget.Text
 }
 
 type ItemEffectContext struct {
 	player engine.GameEntityStatReadWriter
 	castle engine.GameEntityStatReadWriter
 	gun    engine.Gun
+	// Item the effect belongs to. Stat modifiers are tagged with it
+	source engine.StatSource
 }
 
 func (i *ShopItemSlot) ApplyItemEffect(ctx *ItemEffectContext) error {
-	effect, ok := itemEffects[i.item.ItemEffectId]
-	if !ok {
-		return fmt.Errorf("Unknown item efect id: %d", i.item.ItemEffectId)
+	for idx := range i.item.Effects {
+		if err := i.item.Effects[idx].Apply(ctx); err != nil {
+			return err
+		}
 	}
-	return effect(ctx)
+	return nil
 }
 
-func (i *ShopItemSlot) generatePriceLabel() string {
-	prices := []string{}
-	if i.item.GoldPrice > 0 {
-		prices = append(prices, fmt.Sprintf("%d gold", i.item.GoldPrice))
+func (i *ShopItemSlot) generatePriceLabel(catalog *ShopCatalog) string {
+	if i.item == nil {
+		return ""
 	}
-	if i.item.WoodPrice > 0 {
-		prices = append(prices, fmt.Sprintf("%d wood", i.item.WoodPrice))
+	item := catalog.GameItem(i.item.Id)
+	prices := []string{}
+	for _, kind := range catalog.resources.Kinds() {
+		if item.Price[kind.Id] > 0 {
+			prices = append(prices, fmt.Sprintf("%d %s", item.Price[kind.Id], strings.ToLower(kind.Name)))
+		}
 	}
 	return strings.Join(prices, ",")
 }
 
-type ItemEffect func(*ItemEffectContext) error
-
-var itemEffects = map[loot.ItemEffectId]ItemEffect{
-	loot.ItemEffectAddMaxHealth: func(ctx *ItemEffectContext) error {
-		// Need to increase both max health & current health
-		ctx.castle.SetMaxHealth(ctx.castle.MaxHealth() + 10.0)
-		ctx.castle.SetHealth(ctx.castle.Health() + 10.0)
-		return nil
-	},
-	loot.ItemEffectAddMovementSpeed: func(ctx *ItemEffectContext) error {
-		ctx.player.SetMovementSpeed(ctx.player.MovementSpeed() + 10.0)
-		return nil
-	},
-	loot.ItemEffectAddArmor: func(ctx *ItemEffectContext) error {
-		ctx.castle.SetArmor(ctx.castle.Armor() + 10.0)
-		return nil
-	},
-	loot.ItemEffectAddPower: func(ctx *ItemEffectContext) error {
-		ctx.castle.SetPower(ctx.castle.Power() + 10.0)
-		return nil
-	},
-	loot.ItemEffectAddAtkSpeed: func(ctx *ItemEffectContext) error {
-		ctx.castle.SetAtkSpeed(ctx.castle.AtkSpeed() + 0.1)
-		return nil
-	},
-	loot.ItemEffectAddCastleProjectile: func(ctx *ItemEffectContext) error {
-		if ctx.gun == nil {
-			return fmt.Errorf("Could not add projectile: No gun provided")
-		}
-		ctx.gun.SetProjectileCount(ctx.gun.ProjectileCount() + 1)
-		return nil
-	},
-}
-
-// Pool of all available items in the shop. X items from this pool will be randomly selected
-var availableItems = []loot.GameItem{
-	{GoldPrice: 50, Description: "Castle: +10 Max Health", ItemEffectId: loot.ItemEffectAddMaxHealth},
-	{GoldPrice: 50, Description: "Player: +10 Movement speed", ItemEffectId: loot.ItemEffectAddMovementSpeed},
-	{GoldPrice: 50, Description: "Castle: +10 Armor", ItemEffectId: loot.ItemEffectAddArmor},
-	{GoldPrice: 50, Description: "Castle: +10 Power", ItemEffectId: loot.ItemEffectAddPower},
-	{GoldPrice: 50, Description: "Castle: +0.1 Atk Speed", ItemEffectId: loot.ItemEffectAddAtkSpeed},
-}
-
-// Pool of permanent upgrades. All will be available
-var fixedUpgrades = []loot.GameItem{
-	{WoodPrice: 50, Description: "Additional projectile", ItemEffectId: loot.ItemEffectAddCastleProjectile},
+func (i *ShopItemSlot) generateStatusLabel(catalog *ShopCatalog) string {
+	if reason := catalog.LockReason(i.item.Id); reason != "" {
+		return reason
+	}
+	if i.item.StackSize > 0 {
+		return fmt.Sprintf("%d / %d", catalog.Purchases(i.item.Id), i.item.StackSize)
+	}
+	return ""
 }
 
 const (
@@ -133,35 +111,51 @@ const (
 	rerollPrice         = 10
 )
 
-func NewShopMenu(inventory loot.Inventory, player engine.GameEntityStatReadWriter, castle engine.GameEntityStatReadWriter) (*ShopMenu, error) {
-	shop := &ShopMenu{inventory: inventory, player: player, castle: castle}
+func NewShopMenu(inventory loot.Inventory, player engine.GameEntityStatReadWriter, castle engine.GameEntityStatReadWriter, catalog *ShopCatalog, rng *rand.Rand) (*ShopMenu, error) {
+	if catalog == nil {
+		return nil, fmt.Errorf("Cannot init shop without catalog")
+	}
+	shop := &ShopMenu{inventory: inventory, player: player, castle: castle, catalog: catalog, rng: rng}
 	shop.init()
 	return shop, nil
 }
 
 func (s *ShopMenu) RerollItemSlot(idx int) {
+	slot := s.randomItemSlots[idx]
 	// Select item from item pool
-	itemIdx := rand.Intn(len(availableItems))
-	newItem := &availableItems[itemIdx]
+	slot.item = nil
+	if pool := s.catalog.AvailableItems(); len(pool) > 0 {
+		slot.item = pool[s.rng.IntN(len(pool))]
+	}
 
 	// Update UI
-	s.randomItemSlots[idx].item = newItem
-	s.randomItemSlots[idx].priceLabel.Label = s.randomItemSlots[idx].generatePriceLabel()
-	s.randomItemSlots[idx].descriptionLabel.Label = newItem.Description
+	slot.priceLabel.Label = slot.generatePriceLabel(s.catalog)
+	slot.descriptionLabel.Label = "Sold out"
+	if slot.item != nil {
+		slot.descriptionLabel.Label = slot.item.Description
+	}
 }
 
 func (s *ShopMenu) BuyAndApply(shopItem *ShopItemSlot) error {
-	gameItem := shopItem.item
+	if shopItem.item == nil {
+		return fmt.Errorf("Nothing to buy")
+	}
+	if reason := s.catalog.LockReason(shopItem.item.Id); reason != "" {
+		return fmt.Errorf("Cannot buy %s: %s", shopItem.item.Id, reason)
+	}
+	gameItem := s.catalog.GameItem(shopItem.item.Id)
 
 	// Buy item via inventory (manages resources)
 	err := s.inventory.Buy(gameItem)
 	if err != nil {
 		return fmt.Errorf("Could not buy game item: %s", err.Error())
 	}
+	s.catalog.RecordPurchase(shopItem.item.Id)
 
 	// Apply item effect
 	// TODO: Consider moving this somewhere else
-	ctx := &ItemEffectContext{s.player, s.castle, s.Gun()}
+	source := engine.StatSource{Kind: engine.StatSourceItem, Name: gameItem.Description}
+	ctx := &ItemEffectContext{s.player, s.castle, s.Gun(), source}
 	err = shopItem.ApplyItemEffect(ctx)
 	if err != nil {
 		return fmt.Errorf("Error applying item effect: %s", err.Error())
@@ -171,6 +165,7 @@ func (s *ShopMenu) BuyAndApply(shopItem *ShopItemSlot) error {
 }
 
 func (s *ShopMenu) randomizedItemSlotBuyHandler(idx int) {
+	s.notify(engine.ReplayEvent{Action: replayShopBuy, Args: []int{idx}})
 	shopItem := s.randomItemSlots[idx]
 	if err := s.BuyAndApply(shopItem); err != nil {
 		log.Println(err.Error())
@@ -180,11 +175,12 @@ func (s *ShopMenu) randomizedItemSlotBuyHandler(idx int) {
 }
 
 func (s *ShopMenu) RerollHandler(idx int) {
-	if !s.inventory.GoldManager().CanAfford(rerollPrice) {
+	s.notify(engine.ReplayEvent{Action: replayShopReroll, Args: []int{idx}})
+	if !s.inventory.Resource(loot.ResourceGold).CanAfford(rerollPrice) {
 		log.Println("Cannot afford to reroll")
 		return
 	}
-	newBalance, err := s.inventory.GoldManager().Remove(rerollPrice)
+	newBalance, err := s.inventory.Resource(loot.ResourceGold).Remove(rerollPrice)
 	if err != nil {
 		log.Println("Error removing item cost", err.Error())
 		return
@@ -194,6 +190,24 @@ func (s *ShopMenu) RerollHandler(idx int) {
 	s.RerollItemSlot(idx)
 }
 
+func (s *ShopMenu) upgradeBuyHandler(id string) {
+	s.notify(engine.ReplayEvent{Action: replayShopUpgrade, Id: id})
+	idx := slices.IndexFunc(s.upgradeSlots, func(slot *ShopItemSlot) bool { return slot.item.Id == id })
+	if idx < 0 {
+		log.Println("Unknown upgrade", id)
+		return
+	}
+	if err := s.BuyAndApply(s.upgradeSlots[idx]); err != nil {
+		log.Println("Could not buy: ", err.Error())
+	}
+}
+
+func (s *ShopMenu) notify(event engine.ReplayEvent) {
+	if s.onAction != nil {
+		s.onAction(event)
+	}
+}
+
 func (s *ShopMenu) Update() {
 	// Toggle shop visibility with B
 	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
@@ -212,29 +226,24 @@ func (s *ShopMenu) Update() {
 
 	s.shopContainer.GetWidget().Visibility = widget.Visibility_Show
 
-	// Enable/disable buttons depending on affordability & shop status
-	rerollDisabled := !s.ShopEnabled() || !s.inventory.GoldManager().CanAfford(rerollPrice)
-	shopDisabled := !s.ShopEnabled()
+	// Enable/disable buttons depending on affordability, locks & shop status
+	rerollDisabled := !s.ShopEnabled() || !s.inventory.Resource(loot.ResourceGold).CanAfford(rerollPrice)
 	for _, slot := range s.randomItemSlots {
-		if slot.buyButton == nil {
-			continue
-		}
-		canAffordItem, _ := s.inventory.CanAfford(slot.item)
-		buyDisabled := shopDisabled || !canAffordItem
-		slot.buyButton.GetWidget().Disabled = buyDisabled
-		if slot.rerollButton == nil {
-			continue
-		}
+		slot.buyButton.GetWidget().Disabled = !s.canBuy(slot)
 		slot.rerollButton.GetWidget().Disabled = rerollDisabled
 	}
-	for _, slot := range s.fixedItemSlots {
-		if slot.buyButton == nil {
-			continue
-		}
-		canAffordItem, _ := s.inventory.CanAfford(slot.item)
-		buyDisabled := shopDisabled || !canAffordItem
-		slot.buyButton.GetWidget().Disabled = buyDisabled
+	for _, slot := range s.upgradeSlots {
+		slot.buyButton.GetWidget().Disabled = !s.canBuy(slot)
+		slot.statusLabel.Label = slot.generateStatusLabel(s.catalog)
+	}
+}
+
+func (s *ShopMenu) canBuy(slot *ShopItemSlot) bool {
+	if !s.ShopEnabled() || slot.item == nil || s.catalog.LockReason(slot.item.Id) != "" {
+		return false
 	}
+	canAffordItem, _ := s.inventory.CanAfford(s.catalog.GameItem(slot.item.Id))
+	return canAffordItem
 }
 
 func (s *ShopMenu) init() {
@@ -242,17 +251,11 @@ func (s *ShopMenu) init() {
 	rootContainer := widget.NewContainer(
 		// the container will use a plain color as its background
 		widget.ContainerOpts.BackgroundImage(image.NewNineSliceColor(color.NRGBA{0x13, 0x1a, 0x22, 0xbb})),
-		// the container will use an anchor layout to layout its single child widget
-		widget.ContainerOpts.Layout(widget.NewGridLayout(
-			//Define number of columns in the grid
-			widget.GridLayoutOpts.Columns(3),
-			//Define how much padding to inset the child content
-			widget.GridLayoutOpts.Padding(widget.NewInsetsSimple(30)),
-			//Define how far apart the rows and columns should be
-			widget.GridLayoutOpts.Spacing(20, 10),
-			//Define how to stretch the rows and columns. Note it is required to
-			//specify the Stretch for each row and column.
-			widget.GridLayoutOpts.Stretch([]bool{true, false}, []bool{false, true}),
+		// Random items on top, followed by one row per upgrade tier
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionVertical),
+			widget.RowLayoutOpts.Padding(widget.NewInsetsSimple(30)),
+			widget.RowLayoutOpts.Spacing(20),
 		)),
 		widget.ContainerOpts.WidgetOpts(
 			widget.WidgetOpts.LayoutData(widget.AnchorLayoutData{
@@ -279,25 +282,47 @@ func (s *ShopMenu) init() {
 
 	// Init shop elements
 	s.initRandomizedItemSlots(fontFace, buttonImage)
-	s.initPermanantUpgradeItemSlots(fontFace, buttonImage)
+	s.initUpgradeTree(fontFace, buttonImage)
+}
+
+// Horizontal row of item slots
+func newShopRow() *widget.Container {
+	return widget.NewContainer(
+		widget.ContainerOpts.Layout(widget.NewRowLayout(
+			widget.RowLayoutOpts.Direction(widget.DirectionHorizontal),
+			widget.RowLayoutOpts.Spacing(20),
+		)),
+	)
+}
+
+func newShopItemContainer() *widget.Container {
+	return widget.NewContainer(
+		widget.ContainerOpts.BackgroundImage(image.NewNineSliceColor(color.NRGBA{66, 66, 66, 255})),
+		widget.ContainerOpts.Layout(widget.NewGridLayout(
+			widget.GridLayoutOpts.Columns(1),
+			widget.GridLayoutOpts.Stretch([]bool{true}, []bool{false, false, true, false}),
+			widget.GridLayoutOpts.Spacing(0, 10),
+		)),
+		widget.ContainerOpts.WidgetOpts(widget.WidgetOpts.MinSize(150, 150)),
+	)
+}
+
+func newShopLabel(label string, fontFace font.Face) *widget.Text {
+	return widget.NewText(
+		widget.TextOpts.Text(label, fontFace, color.RGBA{255, 255, 255, 1}),
+		widget.TextOpts.MaxWidth(100),
+		widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionStart),
+	)
 }
 
 func (s *ShopMenu) initRandomizedItemSlots(fontFace font.Face, buttonImage *widget.ButtonImage) {
+	row := newShopRow()
 	s.randomItemSlots = make([]*ShopItemSlot, randomizedItemSlots)
 	for idx := range s.randomItemSlots {
 		slot := &ShopItemSlot{}
 		s.randomItemSlots[idx] = slot
 
-		itemContainer := widget.NewContainer(
-			widget.ContainerOpts.BackgroundImage(image.NewNineSliceColor(color.NRGBA{66, 66, 66, 255})),
-			widget.ContainerOpts.Layout(widget.NewGridLayout(
-				widget.GridLayoutOpts.Columns(1),
-				widget.GridLayoutOpts.Stretch([]bool{true}, []bool{false, false, true, false}),
-				widget.GridLayoutOpts.Spacing(0, 10),
-			)),
-			widget.ContainerOpts.WidgetOpts(widget.WidgetOpts.MinSize(150, 150)),
-		)
-
+		itemContainer := newShopItemContainer()
 		slot.rerollButton = widget.NewButton(
 			widget.ButtonOpts.Image(buttonImage),
 			widget.ButtonOpts.Text(fmt.Sprintf("Reroll (%dg)", rerollPrice), fontFace, &widget.ButtonTextColor{
@@ -307,18 +332,10 @@ func (s *ShopMenu) initRandomizedItemSlots(fontFace font.Face, buttonImage *widg
 		)
 		itemContainer.AddChild(slot.rerollButton)
 
-		slot.priceLabel = widget.NewText(
-			widget.TextOpts.Text("", fontFace, color.RGBA{255, 255, 255, 1}),
-			widget.TextOpts.MaxWidth(100),
-			widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionStart),
-		)
+		slot.priceLabel = newShopLabel("", fontFace)
 		itemContainer.AddChild(slot.priceLabel)
 
-		slot.descriptionLabel = widget.NewText(
-			widget.TextOpts.Text("", fontFace, color.RGBA{255, 255, 255, 1}),
-			widget.TextOpts.MaxWidth(100),
-			widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionStart),
-		)
+		slot.descriptionLabel = newShopLabel("", fontFace)
 		itemContainer.AddChild(slot.descriptionLabel)
 
 		slot.buyButton = widget.NewButton(
@@ -330,56 +347,44 @@ func (s *ShopMenu) initRandomizedItemSlots(fontFace font.Face, buttonImage *widg
 		)
 		itemContainer.AddChild(slot.buyButton)
 
-		s.shopContainer.AddChild(itemContainer)
+		row.AddChild(itemContainer)
 
 		// Reroll to initialize
 		s.RerollItemSlot(idx)
 	}
+	s.shopContainer.AddChild(row)
 }
 
-func (s *ShopMenu) initPermanantUpgradeItemSlots(fontFace font.Face, buttonImage *widget.ButtonImage) {
-	s.fixedItemSlots = make([]*ShopItemSlot, len(fixedUpgrades))
-	for idx, item := range fixedUpgrades {
-		slot := &ShopItemSlot{item: &item}
-		s.fixedItemSlots[idx] = slot
-
-		itemContainer := widget.NewContainer(
-			widget.ContainerOpts.BackgroundImage(image.NewNineSliceColor(color.NRGBA{66, 66, 66, 255})),
-			widget.ContainerOpts.Layout(widget.NewGridLayout(
-				widget.GridLayoutOpts.Columns(1),
-				widget.GridLayoutOpts.Stretch([]bool{true}, []bool{false, false, true, false}),
-				widget.GridLayoutOpts.Spacing(0, 10),
-			)),
-			widget.ContainerOpts.WidgetOpts(widget.WidgetOpts.MinSize(150, 150)),
-		)
-
-		slot.priceLabel = widget.NewText(
-			widget.TextOpts.Text(slot.generatePriceLabel(), fontFace, color.RGBA{255, 255, 255, 1}),
-			widget.TextOpts.MaxWidth(100),
-			widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionStart),
-		)
-		itemContainer.AddChild(slot.priceLabel)
-
-		slot.descriptionLabel = widget.NewText(
-			widget.TextOpts.Text(item.Description, fontFace, color.RGBA{255, 255, 255, 1}),
-			widget.TextOpts.MaxWidth(100),
-			widget.TextOpts.Position(widget.TextPositionCenter, widget.TextPositionStart),
-		)
-		itemContainer.AddChild(slot.descriptionLabel)
-
-		slot.buyButton = widget.NewButton(
-			widget.ButtonOpts.Image(buttonImage),
-			widget.ButtonOpts.Text("Buy!", fontFace, &widget.ButtonTextColor{
-				Idle: color.RGBA{255, 255, 255, 1},
-			}),
-			widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) {
-				if err := s.BuyAndApply(s.fixedItemSlots[idx]); err != nil {
-					log.Println("Could not buy: ", err.Error())
-				}
-			}),
-		)
-		itemContainer.AddChild(slot.buyButton)
-		s.shopContainer.AddChild(itemContainer)
+// One row per tier. Locked upgrades stay visible, but cannot be bought
+func (s *ShopMenu) initUpgradeTree(fontFace font.Face, buttonImage *widget.ButtonImage) {
+	s.upgradeSlots = []*ShopItemSlot{}
+	for _, tier := range s.catalog.Tiers() {
+		row := newShopRow()
+		for _, item := range tier {
+			slot := &ShopItemSlot{item: item}
+			s.upgradeSlots = append(s.upgradeSlots, slot)
+
+			itemContainer := newShopItemContainer()
+			slot.priceLabel = newShopLabel(slot.generatePriceLabel(s.catalog), fontFace)
+			itemContainer.AddChild(slot.priceLabel)
+
+			slot.descriptionLabel = newShopLabel(item.Description, fontFace)
+			itemContainer.AddChild(slot.descriptionLabel)
+
+			slot.statusLabel = newShopLabel(slot.generateStatusLabel(s.catalog), fontFace)
+			itemContainer.AddChild(slot.statusLabel)
+
+			slot.buyButton = widget.NewButton(
+				widget.ButtonOpts.Image(buttonImage),
+				widget.ButtonOpts.Text("Buy!", fontFace, &widget.ButtonTextColor{
+					Idle: color.RGBA{255, 255, 255, 1},
+				}),
+				widget.ButtonOpts.ClickedHandler(func(args *widget.ButtonClickedEventArgs) { s.upgradeBuyHandler(item.Id) }),
+			)
+			itemContainer.AddChild(slot.buyButton)
+			row.AddChild(itemContainer)
+		}
+		s.shopContainer.AddChild(row)
 	}
 }
 
@@ -388,6 +393,9 @@ func (s *ShopMenu) SetShopEnabler(enabler ShopEnabler) { s.ShopEnabler = enabler
 func (s *ShopMenu) SetGunProvider(gun GunProvider) {
 	s.GunProvider = gun
 }
+func (s *ShopMenu) SetActionListener(listener func(engine.ReplayEvent)) {
+	s.onAction = listener
+}
 
 func loadButtonImage() (*widget.ButtonImage, error) {
 	idle := image.NewNineSliceColor(color.NRGBA{R: 0, G: 170, B: 0, A: 255})
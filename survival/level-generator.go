@@ -1,16 +1,21 @@
 package survival
 
 import (
+	"fmt"
+	"math/rand/v2"
 	"slices"
 
 	"github.com/jakecoffman/cp"
 	"github.com/lucb31/game-engine-go/bin/assets"
 	"github.com/lucb31/game-engine-go/engine"
+	"github.com/lucb31/game-engine-go/engine/loot"
 )
 
 type SurvivalLevelGenerator struct {
 	*engine.BaseLevelGenerator
-	am engine.AssetManager
+	am         engine.AssetManager
+	lootTables *loot.LootTableDB
+	rng        *rand.Rand
 }
 
 var centerMapPosition = cp.Vector{1456, 1456}
@@ -24,8 +29,10 @@ func NewSurvLevelGenerator() (*SurvivalLevelGenerator, error) {
 	return g, nil
 }
 
-func (g *SurvivalLevelGenerator) Generate(am engine.AssetManager) (*engine.GeneratorResult, error) {
+func (g *SurvivalLevelGenerator) Generate(am engine.AssetManager, lootTables *loot.LootTableDB, rng *rand.Rand) (*engine.GeneratorResult, error) {
 	g.am = am
+	g.lootTables = lootTables
+	g.rng = rng
 	res := &engine.GeneratorResult{}
 	// Generate map
 	worldMap, err := g.GenerateWorldMap()
@@ -44,6 +51,53 @@ func (g *SurvivalLevelGenerator) Generate(am engine.AssetManager) (*engine.Gener
 	return res, nil
 }
 
+// Init world without generating. Map tiles & objects are restored from a saved game instead
+func (g *SurvivalLevelGenerator) EmptyWorld(seed uint64) (*engine.GameWorld, error) {
+	width, height := g.WorldDimensions()
+	resources, err := g.Resources()
+	if err != nil {
+		return nil, err
+	}
+	world, err := engine.NewWorld(width, height, seed, resources)
+	if err != nil {
+		return nil, err
+	}
+	g.am = world.AssetManager
+	g.lootTables = world.LootTables()
+	g.rng = world.Rng()
+	if world.WorldMap, err = g.initWorldMap(); err != nil {
+		return nil, err
+	}
+	return world, nil
+}
+
+// Load tiled castle map. Referenced tilesets are not part of the assets, so
+// they are resolved via asset manager
+func loadCastleMap(am engine.AssetManager) (*engine.TiledMapResult, error) {
+	loader, err := engine.NewTiledMapLoader(engine.NewTiledFileMap(map[string][]byte{
+		"map_dark.tmx": assets.MapDarkTMX,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	tilesetOverrides := map[string]string{
+		"FREE_FantasyOverflow_tileset_16x16": "props",
+		"Dark Dimension":                     "darkdimension",
+	}
+	for tiledName, assetName := range tilesetOverrides {
+		tileset, err := am.Tileset(assetName)
+		if err != nil {
+			return nil, err
+		}
+		loader.SetTileset(tiledName, tileset)
+	}
+	res, err := loader.Load("map_dark.tmx")
+	if err != nil {
+		return nil, fmt.Errorf("Could not load castle map: %s", err.Error())
+	}
+	return res, nil
+}
+
 var availableTrees = []string{"tree_a", "tree_b", "tree_small"}
 var treeProbabilities = []int{1, 1, 10}
 
@@ -52,7 +106,7 @@ func (g *SurvivalLevelGenerator) GenerateTreesAroundCenter(center cp.Vector) ([]
 	treeCount := 800
 	treeRadius := 24.0
 	density := 0.5
-	treePositions := entityDonutDistribution(center, 500, 1200, treeCount, treeRadius)
+	treePositions := entityDonutDistribution(g.rng, center, 500, 1200, treeCount, treeRadius)
 	treePositions = posRingDistribution(center, 500, treeRadius, density)
 	for i := 1; i < 10; i++ {
 		treePositions = append(treePositions, posRingDistribution(center, 500+75*float64(i), treeRadius, density)...)
@@ -60,7 +114,7 @@ func (g *SurvivalLevelGenerator) GenerateTreesAroundCenter(center cp.Vector) ([]
 	res := []engine.GameEntity{}
 
 	// Sample tree assets
-	treeSamples := engine.SampleWithRelativeProbabilities(treeProbabilities, len(treePositions))
+	treeSamples := engine.SampleWithRelativeProbabilities(g.rng, treeProbabilities, len(treePositions))
 
 	// Combine tree positions & tree asset samples
 	for idx, pos := range treePositions {
@@ -72,14 +126,21 @@ func (g *SurvivalLevelGenerator) GenerateTreesAroundCenter(center cp.Vector) ([]
 			return []engine.GameEntity{}, err
 		}
 		var tree *engine.TreeEntity
+		lootTableId := "tree"
 		if treeType == "tree_small" {
 			tree, err = engine.NewBush(asset)
+			lootTableId = "bush"
 		} else {
 			tree, err = engine.NewTree(asset)
 		}
 		if err != nil {
 			return []engine.GameEntity{}, err
 		}
+		table, err := g.lootTables.Table(lootTableId)
+		if err != nil {
+			return []engine.GameEntity{}, err
+		}
+		tree.SetLootTable(table)
 		tree.SetPosition(pos)
 		res = append(res, tree)
 	}
@@ -103,6 +164,20 @@ func (g *SurvivalLevelGenerator) GenerateForest() ([]engine.GameEntity, error) {
 }
 
 func (g *SurvivalLevelGenerator) GenerateWorldMap() (engine.WorldMap, error) {
+	worldMap, err := g.initWorldMap()
+	if err != nil {
+		return nil, err
+	}
+	// Generate map
+	if err := worldMap.Generate(); err != nil {
+		return nil, err
+	}
+	return worldMap, nil
+}
+
+// Setup map layers & segment pool without generating. Tiles are either generated
+// or restored from a saved game afterwards
+func (g *SurvivalLevelGenerator) initWorldMap() (*engine.HexWorldMap, error) {
 	worldWidth, worldHeight := g.WorldDimensions()
 
 	// Base layer
@@ -110,13 +185,13 @@ func (g *SurvivalLevelGenerator) GenerateWorldMap() (engine.WorldMap, error) {
 	if err != nil {
 		return nil, err
 	}
-	worldMap, err := engine.NewProcHexWorldMap(worldWidth, worldHeight, centerMapPosition)
+	worldMap, err := engine.NewProcHexWorldMap(worldWidth, worldHeight, centerMapPosition, g.rng)
 	if err != nil {
 		return nil, err
 	}
 	// Disable skybox. We dont need both fog of war and a skybox
 	// screenWidth, screenHeight := g.ScreenDimensions()
-	// if err := worldMap.AddSkyboxLayer(int64(screenWidth), int64(screenHeight), baseTiles); err != nil {
+	// if err := worldMap.AddSkyboxLayer(int64(screenWidth), int64(screenHeight), baseTiles, g.rng); err != nil {
 	// 	return nil, err
 	// }
 
@@ -134,16 +209,10 @@ func (g *SurvivalLevelGenerator) GenerateWorldMap() (engine.WorldMap, error) {
 	}
 
 	// Add to segment pool
-	if err := worldMap.AddHexSegment(assets.Hex128112CSV); err != nil {
-		return nil, err
-	}
-	if err := worldMap.AddHexSegment(assets.Hex128112PoolBaseCSV); err != nil {
-		return nil, err
-	}
-
-	// Generate map
-	if err := worldMap.Generate(); err != nil {
-		return nil, err
+	for _, segment := range []string{"hex_128_112.csv", "hex_128_112_pool_Base.csv"} {
+		if err := worldMap.AddHexSegmentFile(segment, engine.AssetReader()); err != nil {
+			return nil, err
+		}
 	}
 
 	// Temporarily disable castle props & collision layers
@@ -0,0 +1,198 @@
+package survival
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/lucb31/game-engine-go/engine"
+	"github.com/lucb31/game-engine-go/engine/hud"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+const (
+	replayGameName = "survival"
+	// Amount of ticks to skip when scrubbing through a replay
+	replaySeekTicks = 60 * 10
+)
+
+// Recorded ui actions
+const (
+	// Args: random item slot
+	replayShopBuy    = "shop-buy"
+	replayShopReroll = "shop-reroll"
+	// Id: upgrade item id
+	replayShopUpgrade = "shop-upgrade"
+	// Args: bag slot
+	replayEquip = "equip"
+	// Id: equipment slot, Args: bag slot
+	replayUnequip = "unequip"
+	// Args: source & target bag slot
+	replayBagMove = "bag-move"
+)
+
+// Records bag & equipment changes made via the hud
+type recordingEquipmentHolder struct {
+	hud.EquipmentHolder
+	game *SurvivalGame
+}
+
+func (h *recordingEquipmentHolder) EquipFromBag(bagIdx int) error {
+	h.game.recordEvent(engine.ReplayEvent{Action: replayEquip, Args: []int{bagIdx}})
+	return h.EquipmentHolder.EquipFromBag(bagIdx)
+}
+
+func (h *recordingEquipmentHolder) UnequipToBag(slot loot.EquipmentSlot, bagIdx int) error {
+	h.game.recordEvent(engine.ReplayEvent{Action: replayUnequip, Id: string(slot), Args: []int{bagIdx}})
+	return h.EquipmentHolder.UnequipToBag(slot, bagIdx)
+}
+
+func (h *recordingEquipmentHolder) MoveInBag(from, to int) error {
+	h.game.recordEvent(engine.ReplayEvent{Action: replayBagMove, Args: []int{from, to}})
+	return h.EquipmentHolder.MoveInBag(from, to)
+}
+
+// Record player inputs & ui actions of this and all following sessions. Replay is written to path on game over
+func (g *SurvivalGame) Record(path string) error {
+	if g.playback != nil {
+		return fmt.Errorf("Cannot record while playing back a replay")
+	}
+	g.recordPath = path
+	return g.startRecording(engine.NewKeyboardInputSource())
+}
+
+func (g *SurvivalGame) startRecording(source engine.InputSource) error {
+	replay := engine.NewReplay(replayGameName, g.seed, g.screenWidth, g.screenHeight)
+	recorder, err := engine.NewInputRecorder(source, replay)
+	if err != nil {
+		return err
+	}
+	g.recorder = recorder
+	g.world.Player().SetInputSource(recorder)
+	return nil
+}
+
+// Ui actions happen after simulating the current tick
+func (g *SurvivalGame) recordEvent(event engine.ReplayEvent) {
+	if g.recorder == nil {
+		return
+	}
+	event.Tick = g.world.Tick()
+	g.recorder.Replay().RecordEvent(event)
+}
+
+func (g *SurvivalGame) saveRecording() {
+	if g.recorder == nil || g.recordPath == "" {
+		return
+	}
+	if err := g.recorder.Replay().Save(g.recordPath); err != nil {
+		log.Println("Could not save replay: ", err.Error())
+		return
+	}
+	log.Println("Saved replay to", g.recordPath)
+}
+
+// Restart the game from the replay's seed & feed recorded inputs to the player
+func (g *SurvivalGame) Replay(replay *engine.Replay) error {
+	if replay.Game != replayGameName {
+		return fmt.Errorf("Cannot play %s replay in survival game", replay.Game)
+	}
+	g.recorder = nil
+	g.recordPath = ""
+	g.playback = replay
+	g.playbackSpeed = 1.0
+	g.seed = replay.Seed
+	return g.initialize()
+}
+
+func (g *SurvivalGame) startPlayback() error {
+	source, err := engine.NewReplayInputSource(g.playback)
+	if err != nil {
+		return err
+	}
+	g.world.Player().SetInputSource(source)
+	g.playbackTicks = 0
+	return nil
+}
+
+// Simulate as many ticks as the playback speed allows & handle scrubbing
+func (g *SurvivalGame) updatePlayback() error {
+	if !g.headless {
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+			g.simulateTicks(replaySeekTicks)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+			if err := g.seekPlayback(g.world.Tick() - replaySeekTicks); err != nil {
+				return err
+			}
+		}
+	}
+	g.playbackTicks += g.playbackSpeed
+	ticks := math.Floor(g.playbackTicks)
+	g.playbackTicks -= ticks
+	g.simulateTicks(int(ticks))
+	return nil
+}
+
+// Simulation cannot be reversed. Seeking backwards restarts & simulates up to the target tick
+func (g *SurvivalGame) seekPlayback(tick int) error {
+	if err := g.initialize(); err != nil {
+		return err
+	}
+	g.simulateTicks(max(tick, 0))
+	return nil
+}
+
+func (g *SurvivalGame) simulateTicks(ticks int) {
+	for range ticks {
+		if g.world.IsOver() {
+			return
+		}
+		g.world.GameSpeed = g.playback.SpeedAt(g.world.Tick())
+		for _, event := range g.playback.EventsAt(g.world.Tick()) {
+			if err := g.applyReplayEvent(event); err != nil {
+				log.Println("Could not apply replay event: ", err.Error())
+			}
+		}
+		g.simulateTick()
+	}
+}
+
+// Invokes the same shop & equipment handlers as the hud did while recording
+func (g *SurvivalGame) applyReplayEvent(event engine.ReplayEvent) error {
+	args := 0
+	switch event.Action {
+	case replayShopBuy, replayShopReroll, replayEquip, replayUnequip:
+		args = 1
+	case replayBagMove:
+		args = 2
+	}
+	if len(event.Args) != args {
+		return fmt.Errorf("Expected %d args for %s, got %d", args, event.Action, len(event.Args))
+	}
+	player := g.world.Player()
+	switch event.Action {
+	case replayShopBuy, replayShopReroll:
+		if event.Args[0] < 0 || event.Args[0] >= len(g.shop.randomItemSlots) {
+			return fmt.Errorf("Invalid shop slot %d", event.Args[0])
+		}
+		if event.Action == replayShopBuy {
+			g.shop.randomizedItemSlotBuyHandler(event.Args[0])
+		} else {
+			g.shop.RerollHandler(event.Args[0])
+		}
+	case replayShopUpgrade:
+		g.shop.upgradeBuyHandler(event.Id)
+	case replayEquip:
+		return player.EquipFromBag(event.Args[0])
+	case replayUnequip:
+		return player.UnequipToBag(loot.EquipmentSlot(event.Id), event.Args[0])
+	case replayBagMove:
+		return player.MoveInBag(event.Args[0], event.Args[1])
+	default:
+		return fmt.Errorf("Unknown replay action %s", event.Action)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package survival
+
+import "github.com/lucb31/game-engine-go/engine/loot"
+
+// Resources of the survival game on top of gold & wood
+const (
+	ResourceStone    loot.ResourceId = "stone"
+	ResourceCrystals loot.ResourceId = "crystals"
+)
+
+// Registered before the world parses its loot definitions
+func (g *SurvivalLevelGenerator) Resources() (*loot.ResourceRegistry, error) {
+	resources := loot.DefaultResourceRegistry()
+	for _, kind := range []loot.ResourceKind{
+		{Id: ResourceStone, Name: "Stone", AutoPickup: true},
+		// Rare, so they need to be picked up deliberately
+		{Id: ResourceCrystals, Name: "Crystals", Cap: 999},
+	} {
+		if err := resources.Register(kind); err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
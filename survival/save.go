@@ -0,0 +1,160 @@
+package survival
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lucb31/game-engine-go/engine"
+)
+
+const (
+	SaveGameVersion = 2
+	quickSavePath   = "quicksave.json"
+)
+
+// Everything required to continue a running survival game
+// NOTE: Projectiles in flight & random shop item slots are not persisted
+type SaveGame struct {
+	Version int
+	World   *engine.WorldSnapshot
+	Castle  CastleSnapshot
+	Creeps  engine.CreepManagerSnapshot
+	// Purchases per shop item. Effects are part of the castle & player state
+	Upgrades map[string]int64 `json:",omitempty"`
+}
+
+func (s *SaveGame) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+func (s *SaveGame) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.Write(f)
+}
+
+func ReadSaveGame(reader io.Reader) (*SaveGame, error) {
+	s := &SaveGame{}
+	if err := json.NewDecoder(reader).Decode(s); err != nil {
+		return nil, fmt.Errorf("Could not parse save game: %s", err.Error())
+	}
+	if s.Version != SaveGameVersion {
+		return nil, fmt.Errorf("Unsupported save game version %d. Expected %d", s.Version, SaveGameVersion)
+	}
+	if s.World == nil {
+		return nil, fmt.Errorf("Save game does not contain a world")
+	}
+	return s, nil
+}
+
+func LoadSaveGame(path string) (*SaveGame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSaveGame(f)
+}
+
+func (g *SurvivalGame) SaveGame() (*SaveGame, error) {
+	if g.world.IsOver() {
+		return nil, fmt.Errorf("Cannot save finished game")
+	}
+	world, err := g.world.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	save := &SaveGame{
+		Version: SaveGameVersion,
+		World:   world,
+		Castle:  g.castle.Snapshot(),
+		Creeps:  g.creepManager.Snapshot(),
+	}
+	if g.shop != nil {
+		save.Upgrades = g.shop.catalog.Snapshot()
+	}
+	return save, nil
+}
+
+// Rebuild the game from a save. Recording is stopped, since the replay would no longer be valid
+func (g *SurvivalGame) LoadGame(save *SaveGame) error {
+	if g.playback != nil {
+		return fmt.Errorf("Cannot load a saved game while playing back a replay")
+	}
+	if g.recorder != nil {
+		log.Println("Stopping recording. Replay cannot contain loaded games")
+		g.saveRecording()
+		g.recorder = nil
+		g.recordPath = ""
+	}
+	g.seed = save.World.Seed
+	g.restoreFrom = save
+	defer func() { g.restoreFrom = nil }()
+	return g.initialize()
+}
+
+// Apply save to an initialized game. World has not been generated, so all entities are restored
+func (g *SurvivalGame) restoreSave(save *SaveGame, provider *SurvCreepProvider) error {
+	defaultFactory := engine.NewDefaultEntityFactory(g.world.AssetManager)
+	factory := func(s engine.EntitySnapshot) (engine.SnapshotEntity, error) {
+		switch s.Kind {
+		case engine.EntityKindNpc:
+			return provider.RestoreNpc(s)
+		case engine.EntityKindBoss:
+			return provider.RestoreBoss(s)
+		}
+		return defaultFactory(s)
+	}
+	entities, err := g.world.Restore(save.World, factory)
+	if err != nil {
+		return err
+	}
+	npcs := []engine.GameEntity{}
+	for _, entity := range entities {
+		switch entity.(type) {
+		case *engine.NpcAggro, *engine.NpcBoss:
+			npcs = append(npcs, entity)
+		}
+	}
+	if err := g.creepManager.Restore(save.Creeps, npcs); err != nil {
+		return err
+	}
+	if g.shop != nil {
+		g.shop.catalog.Restore(save.Upgrades)
+	}
+	return g.castle.Restore(save.Castle, g.world.Player())
+}
+
+func (g *SurvivalGame) quickSave() {
+	save, err := g.SaveGame()
+	if err != nil {
+		log.Println("Could not quick save: ", err.Error())
+		return
+	}
+	g.quickSaveGame = save
+	// Keep in memory, if there is no file system (e.g. in the browser)
+	if err := save.Save(quickSavePath); err != nil {
+		log.Println("Could not write quick save: ", err.Error())
+	}
+	log.Println("Quick saved")
+}
+
+func (g *SurvivalGame) quickLoad() {
+	save := g.quickSaveGame
+	if save == nil {
+		var err error
+		if save, err = LoadSaveGame(quickSavePath); err != nil {
+			log.Println("Could not read quick save: ", err.Error())
+			return
+		}
+	}
+	if err := g.LoadGame(save); err != nil {
+		log.Println("Could not quick load: ", err.Error())
+	}
+}
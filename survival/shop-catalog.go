@@ -0,0 +1,270 @@
+package survival
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lucb31/game-engine-go/engine"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+const ShopDefinitionsPath = "shop.json"
+
+// Items & upgrades available in the shop
+type ShopDefinitions struct {
+	// Pool of items. Random items from this pool are offered
+	Items []ShopItemDefinition `json:"items"`
+	// Upgrade tree. All unlocked upgrades are offered
+	Upgrades []ShopItemDefinition `json:"upgrades"`
+}
+
+type ShopItemDefinition struct {
+	Id          string `json:"id"`
+	Description string `json:"description"`
+	// Price per resource, e.g. {"gold": 50}
+	Cost    map[loot.ResourceId]int64 `json:"cost"`
+	Effects []ShopEffectDefinition    `json:"effects"`
+	// Max number of purchases. 0 = unlimited
+	StackSize int64 `json:"stackSize"`
+	// Upgrades that need to be bought first
+	Requires []string `json:"requires"`
+	// Mutually exclusive branches. Buying one locks the others
+	Excludes []string `json:"excludes"`
+}
+
+// Either a stat modifier or a gun effect
+type ShopEffectDefinition struct {
+	// player, castle or gun
+	Target string `json:"target"`
+	// Stat modifier
+	Stat  engine.Stat           `json:"stat"`
+	Op    engine.StatModifierOp `json:"op"`
+	Value float64               `json:"value"`
+	// Gun behaviour
+	ProjectileCount int                         `json:"projectileCount"`
+	OnHit           *engine.StatusEffect        `json:"onHit"`
+	Behaviour       *engine.ProjectileBehaviour `json:"behaviour"`
+}
+
+func (e *ShopEffectDefinition) Apply(ctx *ItemEffectContext) error {
+	switch e.Target {
+	case "player", "castle":
+		stats := ctx.player
+		if e.Target == "castle" {
+			stats = ctx.castle
+		}
+		stats.AddModifier(engine.StatModifier{Stat: e.Stat, Op: e.Op, Value: e.Value, Source: ctx.source})
+		// More max health should not leave the entity damaged
+		if e.Stat == engine.StatMaxHealth && e.Op == engine.ModifierAdd {
+			stats.SetHealth(stats.Health() + e.Value)
+		}
+	case "gun":
+		if ctx.gun == nil {
+			return fmt.Errorf("Could not apply gun effect: No gun provided")
+		}
+		if e.ProjectileCount != 0 {
+			ctx.gun.SetProjectileCount(ctx.gun.ProjectileCount() + e.ProjectileCount)
+		}
+		if e.OnHit != nil {
+			ctx.gun.AddOnHitEffect(*e.OnHit)
+		}
+		if e.Behaviour != nil {
+			ctx.gun.AddProjectileBehaviour(*e.Behaviour)
+		}
+	}
+	return nil
+}
+
+func (e *ShopEffectDefinition) validate() error {
+	switch e.Target {
+	case "player", "castle":
+		if !e.Stat.Valid() {
+			return fmt.Errorf("Unknown stat %q", e.Stat)
+		}
+		if !e.Op.Valid() {
+			return fmt.Errorf("Unknown stat modifier op %q", e.Op)
+		}
+	case "gun":
+		if e.ProjectileCount == 0 && e.OnHit == nil && e.Behaviour == nil {
+			return fmt.Errorf("Gun effect needs projectileCount, onHit or behaviour")
+		}
+		if e.Behaviour != nil {
+			return e.Behaviour.Validate()
+		}
+	default:
+		return fmt.Errorf("Unknown effect target %s", e.Target)
+	}
+	return nil
+}
+
+// Shop definitions & purchases made so far
+type ShopCatalog struct {
+	items    []*ShopItemDefinition
+	upgrades []*ShopItemDefinition
+	byId     map[string]*ShopItemDefinition
+	// Costs may only use registered resources
+	resources *loot.ResourceRegistry
+	// Inventory representation of each definition
+	gameItems map[string]*loot.GameItem
+	purchases map[string]int64
+}
+
+func LoadShopCatalog(readFile engine.AssetFileReader, path string, resources *loot.ResourceRegistry) (*ShopCatalog, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read shop definitions: %s", err.Error())
+	}
+	defs := ShopDefinitions{}
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("Could not parse shop definitions: %s", err.Error())
+	}
+	return NewShopCatalog(defs, resources)
+}
+
+func NewShopCatalog(defs ShopDefinitions, resources *loot.ResourceRegistry) (*ShopCatalog, error) {
+	if resources == nil {
+		return nil, fmt.Errorf("Cannot init shop catalog without resource registry")
+	}
+	c := &ShopCatalog{
+		resources: resources,
+		byId:      map[string]*ShopItemDefinition{},
+		gameItems: map[string]*loot.GameItem{},
+		purchases: map[string]int64{},
+	}
+	for _, def := range defs.Items {
+		if err := c.add(def); err != nil {
+			return nil, err
+		}
+		c.items = append(c.items, c.byId[def.Id])
+	}
+	for _, def := range defs.Upgrades {
+		if err := c.add(def); err != nil {
+			return nil, err
+		}
+		c.upgrades = append(c.upgrades, c.byId[def.Id])
+	}
+	// References can only be checked once all definitions are known
+	for _, def := range c.byId {
+		for _, id := range append(append([]string{}, def.Requires...), def.Excludes...) {
+			if _, ok := c.byId[id]; !ok {
+				return nil, fmt.Errorf("Shop item %s references unknown item %s", def.Id, id)
+			}
+		}
+		if _, err := c.tier(def, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *ShopCatalog) add(def ShopItemDefinition) error {
+	if def.Id == "" {
+		return fmt.Errorf("Shop item without id: %s", def.Description)
+	}
+	if _, ok := c.byId[def.Id]; ok {
+		return fmt.Errorf("Duplicate shop item %s", def.Id)
+	}
+	for resource := range def.Cost {
+		if _, ok := c.resources.Kind(resource); !ok {
+			return fmt.Errorf("Unknown resource %s in cost of %s", resource, def.Id)
+		}
+	}
+	item := &loot.GameItem{Id: def.Id, Description: def.Description, StackSize: def.StackSize, Price: def.Cost}
+	for idx := range def.Effects {
+		if err := def.Effects[idx].validate(); err != nil {
+			return fmt.Errorf("Invalid effect of %s: %s", def.Id, err.Error())
+		}
+	}
+	c.byId[def.Id] = &def
+	c.gameItems[def.Id] = item
+	return nil
+}
+
+// Depth within the upgrade tree. Upgrades without prerequisites are tier 0
+func (c *ShopCatalog) tier(def *ShopItemDefinition, visiting map[string]bool) (int, error) {
+	if visiting[def.Id] {
+		return 0, fmt.Errorf("Circular prerequisites of shop item %s", def.Id)
+	}
+	visiting[def.Id] = true
+	defer delete(visiting, def.Id)
+	res := 0
+	for _, id := range def.Requires {
+		t, err := c.tier(c.byId[id], visiting)
+		if err != nil {
+			return 0, err
+		}
+		res = max(res, t+1)
+	}
+	return res, nil
+}
+
+// Upgrades grouped by tier
+func (c *ShopCatalog) Tiers() [][]*ShopItemDefinition {
+	res := [][]*ShopItemDefinition{}
+	for _, def := range c.upgrades {
+		t, _ := c.tier(def, map[string]bool{})
+		for len(res) <= t {
+			res = append(res, []*ShopItemDefinition{})
+		}
+		res[t] = append(res[t], def)
+	}
+	return res
+}
+
+// Random pool items that are not sold out
+func (c *ShopCatalog) AvailableItems() []*ShopItemDefinition {
+	res := []*ShopItemDefinition{}
+	for _, def := range c.items {
+		if c.LockReason(def.Id) == "" {
+			res = append(res, def)
+		}
+	}
+	return res
+}
+
+// Empty if the item can be bought
+func (c *ShopCatalog) LockReason(id string) string {
+	def, ok := c.byId[id]
+	if !ok {
+		return "Unknown item"
+	}
+	if def.StackSize > 0 && c.purchases[id] >= def.StackSize {
+		return "Sold out"
+	}
+	missing := []string{}
+	for _, req := range def.Requires {
+		if c.purchases[req] == 0 {
+			missing = append(missing, c.byId[req].Description)
+		}
+	}
+	if len(missing) > 0 {
+		return "Requires " + strings.Join(missing, ", ")
+	}
+	for _, other := range c.upgrades {
+		if c.purchases[other.Id] == 0 {
+			continue
+		}
+		// Exclusion works both ways
+		if slices.Contains(def.Excludes, other.Id) || slices.Contains(other.Excludes, id) {
+			return "Excluded by " + other.Description
+		}
+	}
+	return ""
+}
+
+func (c *ShopCatalog) GameItem(id string) *loot.GameItem        { return c.gameItems[id] }
+func (c *ShopCatalog) Definition(id string) *ShopItemDefinition { return c.byId[id] }
+func (c *ShopCatalog) Purchases(id string) int64                { return c.purchases[id] }
+func (c *ShopCatalog) RecordPurchase(id string)                 { c.purchases[id]++ }
+
+// Number of purchases per item id
+func (c *ShopCatalog) Snapshot() map[string]int64 { return maps.Clone(c.purchases) }
+func (c *ShopCatalog) Restore(purchases map[string]int64) {
+	c.purchases = maps.Clone(purchases)
+	if c.purchases == nil {
+		c.purchases = map[string]int64{}
+	}
+}
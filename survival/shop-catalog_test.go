@@ -0,0 +1,55 @@
+package survival
+
+import (
+	"testing"
+
+	"github.com/lucb31/game-engine-go/engine"
+	"github.com/lucb31/game-engine-go/engine/loot"
+)
+
+func TestShopCatalogUpgradeTree(t *testing.T) {
+	catalog, err := LoadShopCatalog(engine.AssetReader(), ShopDefinitionsPath, loot.DefaultResourceRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if catalog.LockReason("burning-projectiles") == "" {
+		t.Fatal("Expected burning projectiles to require additional projectile")
+	}
+	catalog.RecordPurchase("extra-projectile")
+	if reason := catalog.LockReason("burning-projectiles"); reason != "" {
+		t.Fatalf("Expected burning projectiles unlocked, got %s", reason)
+	}
+	catalog.RecordPurchase("burning-projectiles")
+	if catalog.LockReason("frost-projectiles") == "" {
+		t.Fatal("Expected frost projectiles excluded by burning projectiles")
+	}
+	if catalog.LockReason("burning-projectiles") != "Sold out" {
+		t.Fatalf("Expected burning projectiles sold out, got %s", catalog.LockReason("burning-projectiles"))
+	}
+	if tiers := catalog.Tiers(); len(tiers) != 3 {
+		t.Fatalf("Expected 3 upgrade tiers, got %d", len(tiers))
+	}
+}
+
+func TestShopCatalogRejectsCircularPrerequisites(t *testing.T) {
+	effects := []ShopEffectDefinition{{Target: "gun", ProjectileCount: 1}}
+	_, err := NewShopCatalog(ShopDefinitions{Upgrades: []ShopItemDefinition{
+		{Id: "a", Requires: []string{"b"}, Effects: effects},
+		{Id: "b", Requires: []string{"a"}, Effects: effects},
+	}}, loot.DefaultResourceRegistry())
+	if err == nil {
+		t.Fatal("Expected error for circular prerequisites")
+	}
+}
+
+func TestShopCatalogRejectsUnknownStats(t *testing.T) {
+	effects := [][]ShopEffectDefinition{
+		{{Target: "player", Stat: "powr", Op: engine.ModifierAdd, Value: 1}},
+		{{Target: "castle", Stat: engine.StatArmor, Op: "mult", Value: 1.1}},
+	}
+	for _, e := range effects {
+		if _, err := NewShopCatalog(ShopDefinitions{Items: []ShopItemDefinition{{Id: "typo", Effects: e}}}, loot.DefaultResourceRegistry()); err == nil {
+			t.Errorf("Expected error for %s %s effect", e[0].Stat, e[0].Op)
+		}
+	}
+}
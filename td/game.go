@@ -6,7 +6,6 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/jakecoffman/cp"
-	"github.com/lucb31/game-engine-go/bin/assets"
 	"github.com/lucb31/game-engine-go/engine"
 	"github.com/lucb31/game-engine-go/engine/hud"
 	"github.com/lucb31/game-engine-go/engine/loot"
@@ -20,12 +19,18 @@ type TDGame struct {
 	goldManager               loot.ResourceManager
 	hud                       *hud.GameHUD
 	castle                    *CastleEntity
+	// Seed of the current game world
+	seed uint64
+	// Headless games do not render & do not have a HUD
+	headless bool
 }
 
 func (g *TDGame) Update() error {
 	if g.world.IsOver() {
 		// Wait for restart
 		if ebiten.IsKeyPressed(ebiten.KeySpace) {
+			// Derive next seed to keep restarts reproducible
+			g.seed = g.world.Rng().Uint64()
 			err := g.initialize()
 			if err != nil {
 				log.Println("Could not restart game: ", err.Error())
@@ -37,6 +42,9 @@ func (g *TDGame) Update() error {
 	if err := g.creepManager.Update(); err != nil {
 		log.Println("Could not update creeps: ", err.Error())
 	}
+	if g.headless {
+		return nil
+	}
 	g.towerManager.Update()
 	g.hud.Update()
 
@@ -59,23 +67,22 @@ func (game *TDGame) initialize() error {
 	// Init game world
 	width := int64(game.screenWidth)
 	height := int64(game.screenHeight)
-	w, err := engine.NewWorld(width, height)
+	w, err := engine.NewWorld(width, height, game.seed, loot.DefaultResourceRegistry())
 	if err != nil {
 		return err
 	}
 	am := w.AssetManager
 	// Initialize map
-	tileset, err := am.Tileset("plains")
+	loader, err := engine.NewTiledMapLoader(engine.TiledFileReader(engine.AssetReader()))
 	if err != nil {
 		return err
 	}
-	w.WorldMap, err = engine.NewMultiLayerWorldMap(width, height)
+	tiledMap, err := loader.Load("labyrinth_map.tmx")
 	if err != nil {
-		return err
-	}
-	if err = w.WorldMap.AddCsvLayer(assets.LabyrinthMapCSV, tileset); err != nil {
-		return err
+		return fmt.Errorf("Could not load map: %s", err.Error())
 	}
+	w.WorldMap = tiledMap.WorldMap
+	w.WatchAssets(tiledMap)
 	game.world = w
 
 	// Add collision handler for castle
@@ -128,9 +135,11 @@ func (game *TDGame) initialize() error {
 	}
 
 	// Setup HUD. Needs to be reset to initialize speed slider correctly
-	game.hud, err = hud.NewHUD(game)
-	if err != nil {
-		return err
+	if !game.headless {
+		game.hud, err = hud.NewHUD(game)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Setup camera
@@ -144,8 +153,8 @@ func (game *TDGame) initialize() error {
 }
 
 // Constructor: Initialize parts of game that are constant even after restarting
-func NewTDGame(screenWidth, screenHeight int) (*TDGame, error) {
-	game := &TDGame{screenWidth: screenWidth, screenHeight: screenHeight}
+func NewTDGame(screenWidth, screenHeight int, seed uint64) (*TDGame, error) {
+	game := &TDGame{screenWidth: screenWidth, screenHeight: screenHeight, seed: seed}
 
 	// Initialize
 	if err := game.initialize(); err != nil {
@@ -154,8 +163,18 @@ func NewTDGame(screenWidth, screenHeight int) (*TDGame, error) {
 	return game, nil
 }
 
+// Game without HUD that can be stepped via engine.RunHeadless
+func NewHeadlessTDGame(screenWidth, screenHeight int, seed uint64) (*TDGame, error) {
+	game := &TDGame{screenWidth: screenWidth, screenHeight: screenHeight, seed: seed, headless: true}
+	if err := game.initialize(); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
 func (g *TDGame) GameOver() bool                   { return g.world.IsOver() }
 func (g *TDGame) CreepProgress() hud.ProgressInfo  { return g.creepManager.Progress() }
+func (g *TDGame) BossProgress() hud.ProgressInfo   { return g.creepManager.BossProgress() }
 func (g *TDGame) CastleProgress() hud.ProgressInfo { return g.castle.GetHealthBar() }
 func (g *TDGame) SetSpeed(speed float64)           { g.world.GameSpeed = speed }
 func (g *TDGame) Score() hud.ScoreValue {
@@ -167,7 +186,9 @@ func (g *TDGame) EndGame() {
 
 	// Keeping score
 	log.Printf("You've lost at wave %d \n", g.creepManager.Round())
-	g.hud.SaveScore(g.Score())
+	if g.hud != nil {
+		g.hud.SaveScore(g.Score())
+	}
 
 	log.Println("Waiting for restart...")
 }
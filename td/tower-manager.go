@@ -3,7 +3,6 @@ package td
 import (
 	"fmt"
 	"log"
-	"math/rand"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -128,7 +127,7 @@ func (t *TowerManager) AddTower(cursorPos cp.Vector) error {
 
 	// Tower Factory
 	// FIX:Currently type of tower is randomly selected
-	selectedTower := TowerType(rand.Intn(2))
+	selectedTower := TowerType(t.world.Rng().IntN(2))
 	var tower *TowerEntity
 	switch selectedTower {
 	case SingleTarget: